@@ -1,215 +1,84 @@
 package adapter
 
 import (
-	"chat/adapter/azure"
-	"chat/adapter/baichuan"
-	"chat/adapter/bing"
-	"chat/adapter/chatgpt"
-	"chat/adapter/claude"
-	"chat/adapter/dashscope"
-	"chat/adapter/hunyuan"
-	"chat/adapter/midjourney"
-	"chat/adapter/oneapi"
-	"chat/adapter/palm2"
-	"chat/adapter/skylark"
-	"chat/adapter/slack"
-	"chat/adapter/sparkdesk"
-	"chat/adapter/zhinao"
-	"chat/adapter/zhipuai"
+	_ "chat/adapter/anthropic"
+	_ "chat/adapter/azure"
+	_ "chat/adapter/baichuan"
+	_ "chat/adapter/bing"
+	_ "chat/adapter/chatgpt"
+	_ "chat/adapter/claude"
+	adaptercommon "chat/adapter/common"
+	_ "chat/adapter/dashscope"
+	_ "chat/adapter/hunyuan"
+	_ "chat/adapter/midjourney"
+	_ "chat/adapter/ollama"
+	_ "chat/adapter/oneapi"
+	_ "chat/adapter/palm2"
+	_ "chat/adapter/skylark"
+	_ "chat/adapter/slack"
+	_ "chat/adapter/sparkdesk"
+	_ "chat/adapter/zhinao"
+	_ "chat/adapter/zhipuai"
 	"chat/globals"
-	"chat/utils"
 	"fmt"
 )
 
-type RequestProps struct {
-	MaxRetries *int
-	Current    int
-	Group      string
-}
-
-type ChatProps struct {
-	RequestProps
-
-	Model             string
-	Plan              bool
-	Infinity          bool
-	Message           []globals.Message
-	Token             int
-	PresencePenalty   *float32
-	FrequencyPenalty  *float32
-	RepetitionPenalty *float32
-	Temperature       *float32
-	TopP              *float32
-	TopK              *int
-	Tools             *globals.FunctionTools
-	ToolChoice        *interface{}
-	Buffer            utils.Buffer
-}
-
-func createChatRequest(conf globals.ChannelConfig, props *ChatProps, hook globals.Hook) error {
-	model := conf.GetModelReflect(props.Model)
-
-	switch conf.GetType() {
-	case globals.OpenAIChannelType:
-		return chatgpt.NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&chatgpt.ChatProps{
-			Model:   model,
-			Message: props.Message,
-			Token: utils.Multi(
-				props.Token == 0,
-				utils.Multi(props.Infinity || props.Plan, nil, utils.ToPtr(2500)),
-				&props.Token,
-			),
-			PresencePenalty:  props.PresencePenalty,
-			FrequencyPenalty: props.FrequencyPenalty,
-			Temperature:      props.Temperature,
-			TopP:             props.TopP,
-			Tools:            props.Tools,
-			ToolChoice:       props.ToolChoice,
-			Buffer:           props.Buffer,
-		}, hook)
-
-	case globals.AzureOpenAIChannelType:
-		return azure.NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&azure.ChatProps{
-			Model:   model,
-			Message: props.Message,
-			Token: utils.Multi(
-				props.Token == 0,
-				utils.Multi(props.Infinity || props.Plan, nil, utils.ToPtr(2500)),
-				&props.Token,
-			),
-			PresencePenalty:  props.PresencePenalty,
-			FrequencyPenalty: props.FrequencyPenalty,
-			Temperature:      props.Temperature,
-			TopP:             props.TopP,
-			Tools:            props.Tools,
-			ToolChoice:       props.ToolChoice,
-			Buffer:           props.Buffer,
-		}, hook)
-
-	case globals.ClaudeChannelType:
-		return claude.NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&claude.ChatProps{
-			Model:       model,
-			Message:     props.Message,
-			Token:       utils.Multi(props.Token == 0, 50000, props.Token),
-			TopP:        props.TopP,
-			TopK:        props.TopK,
-			Temperature: props.Temperature,
-		}, hook)
-
-	case globals.SlackChannelType:
-		return slack.NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&slack.ChatProps{
-			Message: props.Message,
-		}, hook)
-
-	case globals.BingChannelType:
-		return bing.NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&bing.ChatProps{
-			Model:   model,
-			Message: props.Message,
-		}, hook)
+type RequestProps = adaptercommon.RequestProps
+type ChatProps = adaptercommon.ChatProps
 
-	case globals.PalmChannelType:
-		return palm2.NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&palm2.ChatProps{
-			Model:   model,
-			Message: props.Message,
-		}, hook)
+// getProvider returns the registered provider of the channel, or an error if the request cannot be honored by it
+func getProvider(conf globals.ChannelConfig, props *ChatProps) (adaptercommon.Provider, error) {
+	provider := adaptercommon.GetProvider(conf.GetType())
+	if provider == nil {
+		return nil, fmt.Errorf("unknown channel type %s for model %s", conf.GetType(), props.Model)
+	}
 
-	case globals.SparkdeskChannelType:
-		return sparkdesk.NewChatInstance(conf, model).CreateStreamChatRequest(&sparkdesk.ChatProps{
-			Model:       model,
-			Message:     props.Message,
-			Token:       utils.Multi(props.Token == 0, nil, utils.ToPtr(props.Token)),
-			Temperature: props.Temperature,
-			TopK:        props.TopK,
-			Tools:       props.Tools,
-			Buffer:      props.Buffer,
-		}, hook)
+	if err := adaptercommon.CheckParams(provider, conf.GetModelReflect(props.Model), props); err != nil {
+		return nil, err
+	}
 
-	case globals.ChatGLMChannelType:
-		return zhipuai.NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&zhipuai.ChatProps{
-			Model:       model,
-			Message:     props.Message,
-			Temperature: props.Temperature,
-			TopP:        props.TopP,
-		}, hook)
+	if !provider.Accept(conf, props) {
+		return nil, fmt.Errorf("request for model %s is not acceptable by the channel (type: %s)", props.Model, conf.GetType())
+	}
 
-	case globals.QwenChannelType:
-		return dashscope.NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&dashscope.ChatProps{
-			Model:             model,
-			Message:           props.Message,
-			Token:             utils.Multi(props.Infinity || props.Plan, 2048, props.Token),
-			Temperature:       props.Temperature,
-			TopP:              props.TopP,
-			TopK:              props.TopK,
-			RepetitionPenalty: props.RepetitionPenalty,
-		}, hook)
+	return provider, nil
+}
 
-	case globals.HunyuanChannelType:
-		return hunyuan.NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&hunyuan.ChatProps{
-			Model:       model,
-			Message:     props.Message,
-			Temperature: props.Temperature,
-			TopP:        props.TopP,
-		}, hook)
+// IsChatSupported returns whether the channel can honor all the parameters of the request (e.g. tools) and accept it
+func IsChatSupported(conf globals.ChannelConfig, props *ChatProps) bool {
+	_, err := getProvider(conf, props)
+	return err == nil
+}
 
-	case globals.BaichuanChannelType:
-		return baichuan.NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&baichuan.ChatProps{
-			Model:       model,
-			Message:     props.Message,
-			TopP:        props.TopP,
-			TopK:        props.TopK,
-			Temperature: props.Temperature,
-		}, hook)
+func createChatRequest(provider adaptercommon.Provider, conf globals.ChannelConfig, props *ChatProps, hook globals.Hook) error {
+	// copy the props to avoid the reflection model leaking to the other channels
+	instance := *props
+	instance.Model = conf.GetModelReflect(props.Model)
 
-	case globals.SkylarkChannelType:
-		return skylark.NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&skylark.ChatProps{
-			Model:            model,
-			Message:          props.Message,
-			Token:            utils.Multi(props.Token == 0, 4096, props.Token),
-			TopP:             props.TopP,
-			TopK:             props.TopK,
-			Temperature:      props.Temperature,
-			FrequencyPenalty: props.FrequencyPenalty,
-			PresencePenalty:  props.PresencePenalty,
-			RepeatPenalty:    props.RepetitionPenalty,
-			Tools:            props.Tools,
-		}, hook)
+	if !provider.SupportParam(instance.Model, adaptercommon.ParamImages) {
+		// text only models receive the text view of the multimodal messages
+		instance.Message = globals.GetTextMessages(props.Message)
+	}
 
-	case globals.ZhinaoChannelType:
-		return zhinao.NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&zhinao.ChatProps{
-			Model:             model,
-			Message:           props.Message,
-			Token:             utils.Multi(props.Infinity || props.Plan, nil, utils.ToPtr(2048)),
-			TopP:              props.TopP,
-			TopK:              props.TopK,
-			Temperature:       props.Temperature,
-			RepetitionPenalty: props.RepetitionPenalty,
-		}, hook)
+	return provider.CreateStreamChatRequest(conf, &instance, hook)
+}
 
-	case globals.MidjourneyChannelType:
-		return midjourney.NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&midjourney.ChatProps{
-			Model:    model,
-			Messages: props.Message,
-		}, hook)
+// IsModelListable returns whether the models of the channel type can be listed from the upstream
+func IsModelListable(t string) bool {
+	_, ok := adaptercommon.GetProvider(t).(adaptercommon.ModelLister)
+	return ok
+}
 
-	case globals.OneAPIChannelType:
-		return oneapi.NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&oneapi.ChatProps{
-			Model:   model,
-			Message: props.Message,
-			Token: utils.Multi(
-				props.Token == 0,
-				utils.Multi(props.Plan || props.Infinity, nil, utils.ToPtr(2500)),
-				&props.Token,
-			),
-			PresencePenalty:  props.PresencePenalty,
-			FrequencyPenalty: props.FrequencyPenalty,
-			Temperature:      props.Temperature,
-			TopP:             props.TopP,
-			Tools:            props.Tools,
-			ToolChoice:       props.ToolChoice,
-			Buffer:           props.Buffer,
-		}, hook)
+// ListModels returns the models which are served by the upstream of the channel
+func ListModels(conf globals.ChannelConfig) ([]string, error) {
+	lister, ok := adaptercommon.GetProvider(conf.GetType()).(adaptercommon.ModelLister)
+	if !ok {
+		return nil, fmt.Errorf("channel type %s does not support listing models", conf.GetType())
+	}
 
-	default:
-		return fmt.Errorf("unknown channel type %s for model %s", conf.GetType(), props.Model)
+	models, err := lister.ListModels(conf)
+	if err != nil {
+		return nil, conf.ProcessError(err)
 	}
+	return models, nil
 }
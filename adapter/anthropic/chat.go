@@ -0,0 +1,243 @@
+package anthropic
+
+import (
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+	"strings"
+)
+
+const anthropicVersion = "2023-06-01"
+
+type ChatProps struct {
+	Model       string
+	Message     []globals.Message
+	Token       int
+	Temperature *float32
+	TopP        *float32
+	TopK        *int
+	Tools       *globals.FunctionTools
+	ToolChoice  *interface{}
+	Stop        []string
+	User        *string
+	Buffer      *utils.Buffer
+}
+
+func (c *ChatInstance) GetChatEndpoint() string {
+	return fmt.Sprintf("%s/v1/messages", c.GetEndpoint())
+}
+
+func (c *ChatInstance) GetChatHeaders() map[string]string {
+	return map[string]string{
+		"content-type":      "application/json",
+		"accept":            "application/json",
+		"x-api-key":         c.GetApiKey(),
+		"anthropic-version": anthropicVersion,
+	}
+}
+
+func (c *ChatInstance) GetChatBody(props *ChatProps, stream bool) *ChatBody {
+	system, messages := c.GetMessages(props.Message)
+
+	body := &ChatBody{
+		Model:         props.Model,
+		Messages:      messages,
+		System:        system,
+		MaxTokens:     props.Token,
+		Stream:        stream,
+		StopSequences: props.Stop,
+		Temperature:   props.Temperature,
+		TopP:          props.TopP,
+		TopK:          props.TopK,
+	}
+
+	if props.User != nil && len(*props.User) > 0 {
+		body.Metadata = &Metadata{UserId: *props.User}
+	}
+
+	if !isToolChoiceNone(props.ToolChoice) {
+		// anthropic does not support `none` tool choice, so the tools are not sent instead
+		body.Tools = getTools(props.Tools)
+		if len(body.Tools) > 0 {
+			body.ToolChoice = getToolChoice(props.ToolChoice)
+		}
+	}
+
+	return body
+}
+
+// getToolCalls collects the tool_use blocks of the response as openai tool calls
+func getToolCalls(contents []Content) *globals.ToolCalls {
+	calls := make(globals.ToolCalls, 0)
+	for _, content := range contents {
+		if content.Type != ToolUseType {
+			continue
+		}
+
+		calls = append(calls, globals.ToolCall{
+			Type: "function",
+			Id:   globals.ToolCallId(utils.GetPtrVal(content.Id, "")),
+			Function: globals.ToolCallFunction{
+				Name:      utils.GetPtrVal(content.Name, ""),
+				Arguments: utils.Marshal(content.Input),
+			},
+		})
+	}
+
+	if len(calls) == 0 {
+		return nil
+	}
+	return &calls
+}
+
+// CreateChatRequest is the request for anthropic messages api
+func (c *ChatInstance) CreateChatRequest(props *ChatProps) (string, error) {
+	res, err := utils.Post(c.GetChatEndpoint(), c.GetChatHeaders(), c.GetChatBody(props, false))
+	if err != nil || res == nil {
+		return "", fmt.Errorf("anthropic error: %s", utils.GetError(err))
+	}
+
+	data := utils.MapToStruct[ChatResponse](res)
+	if data == nil {
+		return "", fmt.Errorf("anthropic error: cannot parse response")
+	} else if data.Error != nil {
+		return "", fmt.Errorf("anthropic error: %s (type: %s)", data.Error.Message, data.Error.Type)
+	}
+
+	props.Buffer.SetInputTokens(data.Usage.InputTokens)
+	props.Buffer.SetOutputTokens(data.Usage.OutputTokens)
+	props.Buffer.SetToolCalls(getToolCalls(data.Content))
+
+	result := ""
+	for _, content := range data.Content {
+		if content.Type == TextType {
+			result += utils.GetPtrVal(content.Text, "")
+		}
+	}
+	return result, nil
+}
+
+// streamProcessor holds the state of the stream response, since tool calls are sent in several events
+type streamProcessor struct {
+	props     *ChatProps
+	toolCalls globals.ToolCalls
+	blocks    map[int]int // content block index -> tool call index
+	empty     bool
+}
+
+func newStreamProcessor(props *ChatProps) *streamProcessor {
+	return &streamProcessor{
+		props:     props,
+		toolCalls: make(globals.ToolCalls, 0),
+		blocks:    map[int]int{},
+		empty:     true,
+	}
+}
+
+func (p *streamProcessor) process(form *StreamResponse, hook globals.Hook) error {
+	// response example:
+	//
+	// event: content_block_delta
+	// data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}
+
+	switch form.Type {
+	case "message_start":
+		if form.Message != nil {
+			p.props.Buffer.SetInputTokens(form.Message.Usage.InputTokens)
+		}
+	case "content_block_start":
+		if form.ContentBlock != nil && form.ContentBlock.Type == ToolUseType {
+			p.blocks[form.Index] = len(p.toolCalls)
+			p.toolCalls = append(p.toolCalls, globals.ToolCall{
+				Type: "function",
+				Id:   globals.ToolCallId(utils.GetPtrVal(form.ContentBlock.Id, "")),
+				Function: globals.ToolCallFunction{
+					Name: utils.GetPtrVal(form.ContentBlock.Name, ""),
+				},
+			})
+		}
+	case "content_block_delta":
+		if form.Delta == nil {
+			return nil
+		}
+
+		switch form.Delta.Type {
+		case "text_delta":
+			if len(form.Delta.Text) == 0 {
+				return nil
+			}
+
+			p.empty = false
+			return hook(form.Delta.Text)
+		case "input_json_delta":
+			if idx, ok := p.blocks[form.Index]; ok {
+				p.toolCalls[idx].Function.Arguments += form.Delta.PartialJson
+			}
+		}
+	case "message_delta":
+		if form.Usage != nil {
+			p.props.Buffer.SetOutputTokens(form.Usage.OutputTokens)
+		}
+	case "error":
+		if form.Error != nil {
+			return fmt.Errorf("anthropic error: %s (type: %s)", form.Error.Message, form.Error.Type)
+		}
+		return fmt.Errorf("anthropic error: unknown error")
+	}
+
+	return nil
+}
+
+func (p *streamProcessor) done() error {
+	if len(p.toolCalls) > 0 {
+		for idx := range p.toolCalls {
+			if len(p.toolCalls[idx].Function.Arguments) == 0 {
+				p.toolCalls[idx].Function.Arguments = "{}"
+			}
+		}
+
+		p.props.Buffer.SetToolCalls(&p.toolCalls)
+		return nil
+	}
+
+	if p.empty {
+		return fmt.Errorf("empty response")
+	}
+	return nil
+}
+
+// CreateStreamChatRequest is the stream request for anthropic messages api
+func (c *ChatInstance) CreateStreamChatRequest(props *ChatProps, hook globals.Hook) error {
+	buf := ""
+	processor := newStreamProcessor(props)
+
+	err := utils.EventSource(
+		"POST",
+		c.GetChatEndpoint(),
+		c.GetChatHeaders(),
+		c.GetChatBody(props, true),
+		func(data string) error {
+			if strings.HasPrefix(data, "event:") {
+				return nil
+			} else if strings.HasPrefix(data, "data:") {
+				buf = ""
+				data = strings.TrimSpace(strings.TrimPrefix(data, "data:"))
+			}
+
+			// the line may be cut off by the chunk, wait for the rest of it
+			buf += data
+			form := utils.UnmarshalForm[StreamResponse](buf)
+			if form == nil {
+				return nil
+			}
+
+			buf = ""
+			return processor.process(form, hook)
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+	return processor.done()
+}
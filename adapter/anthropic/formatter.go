@@ -0,0 +1,187 @@
+package anthropic
+
+import (
+	"chat/globals"
+	"chat/utils"
+	"strings"
+)
+
+var anthropicMaxImages = 20
+
+func getTextContent(text string) Content {
+	return Content{
+		Type: TextType,
+		Text: &text,
+	}
+}
+
+// getUserContents converts the typed parts of the user message to the anthropic text and base64 image blocks
+func getUserContents(contents globals.MessageContents) []Content {
+	images := 0
+	return utils.EachNotNil[globals.MessageContent, Content](contents, func(content globals.MessageContent) *Content {
+		if !content.IsImage() {
+			if text := content.GetText(); len(strings.TrimSpace(text)) > 0 {
+				return utils.ToPtr(getTextContent(text))
+			}
+			return nil
+		}
+
+		if images >= anthropicMaxImages {
+			return nil
+		}
+
+		mimeType, data, err := utils.GetImageData(content)
+		if err != nil {
+			return nil
+		}
+
+		images++
+		return &Content{
+			Type: ImageType,
+			Source: &ImageSource{
+				Type:      "base64",
+				MediaType: mimeType,
+				Data:      data,
+			},
+		}
+	})
+}
+
+func getToolUseContents(calls *globals.ToolCalls) []Content {
+	if calls == nil {
+		return nil
+	}
+
+	return utils.Each[globals.ToolCall, Content](*calls, func(call globals.ToolCall) Content {
+		// anthropic requires the tool input to be an object
+		var input interface{} = map[string]interface{}{}
+		if form := utils.UnmarshalForm[map[string]interface{}](call.Function.Arguments); form != nil {
+			input = *form
+		}
+
+		return Content{
+			Type:  ToolUseType,
+			Id:    utils.ToPtr(string(call.Id)),
+			Name:  utils.ToPtr(call.Function.Name),
+			Input: input,
+		}
+	})
+}
+
+func getContents(message globals.Message) []Content {
+	contents := make([]Content, 0)
+
+	switch message.Role {
+	case globals.Tool:
+		return append(contents, Content{
+			Type:      ToolResultType,
+			ToolUseId: message.ToolCallId,
+			Content:   utils.ToPtr(message.Content),
+		})
+	case globals.Assistant:
+		if len(strings.TrimSpace(message.Content)) > 0 {
+			contents = append(contents, getTextContent(message.Content))
+		}
+		return append(contents, getToolUseContents(message.ToolCalls)...)
+	default:
+		return append(contents, getUserContents(message.GetContents())...)
+	}
+}
+
+func getRole(role string) string {
+	switch role {
+	case globals.Assistant:
+		return globals.Assistant
+	default:
+		// tool results are sent as user messages
+		return globals.User
+	}
+}
+
+// GetMessages returns the system prompt and the messages of the anthropic messages api
+func (c *ChatInstance) GetMessages(message []globals.Message) (string, []Message) {
+	system := make([]string, 0)
+	result := make([]Message, 0)
+
+	for _, item := range message {
+		if item.Role == globals.System {
+			if len(strings.TrimSpace(item.Content)) > 0 {
+				system = append(system, item.Content)
+			}
+			continue
+		}
+
+		role := getRole(item.Role)
+		contents := getContents(item)
+		if len(contents) == 0 {
+			// anthropic: message must include non empty content
+			continue
+		}
+
+		if len(result) == 0 && role == globals.Assistant {
+			// anthropic: first message must be user
+			continue
+		}
+
+		if len(result) > 0 && role == result[len(result)-1].Role {
+			// anthropic: messages must alternate between user and assistant
+			result[len(result)-1].Content = append(result[len(result)-1].Content, contents...)
+			continue
+		}
+
+		result = append(result, Message{
+			Role:    role,
+			Content: contents,
+		})
+	}
+
+	return strings.Join(system, "\n\n"), result
+}
+
+func getTools(tools *globals.FunctionTools) []Tool {
+	if tools == nil || len(*tools) == 0 {
+		return nil
+	}
+
+	return utils.Each[globals.ToolObject, Tool](*tools, func(tool globals.ToolObject) Tool {
+		return Tool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: tool.Function.Parameters,
+		}
+	})
+}
+
+// getToolChoice converts the openai tool choice (string or object) to the anthropic format
+func getToolChoice(choice *interface{}) *ToolChoice {
+	if choice == nil {
+		return nil
+	}
+
+	switch value := (*choice).(type) {
+	case string:
+		switch value {
+		case "auto":
+			return &ToolChoice{Type: "auto"}
+		case "required", "any":
+			return &ToolChoice{Type: "any"}
+		}
+	case map[string]interface{}:
+		if function, ok := value["function"].(map[string]interface{}); ok {
+			if name, ok := function["name"].(string); ok && len(name) > 0 {
+				return &ToolChoice{Type: "tool", Name: &name}
+			}
+		}
+	}
+
+	return nil
+}
+
+func isToolChoiceNone(choice *interface{}) bool {
+	if choice == nil {
+		return false
+	}
+
+	value, ok := (*choice).(string)
+	return ok && value == "none"
+}
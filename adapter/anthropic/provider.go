@@ -0,0 +1,42 @@
+package anthropic
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/globals"
+)
+
+// max_tokens is required by anthropic messages api
+var tokenLimit = adaptercommon.TokenLimit{Default: 4096}
+
+func init() {
+	adaptercommon.Register(adaptercommon.NewProvider(
+		globals.AnthropicChannelType,
+		[]string{
+			adaptercommon.ParamTemperature,
+			adaptercommon.ParamTopP,
+			adaptercommon.ParamTopK,
+			adaptercommon.ParamTools,
+			adaptercommon.ParamToolChoice,
+			adaptercommon.ParamImages,
+			adaptercommon.ParamStop,
+		},
+		tokenLimit,
+		createChatRequest,
+	))
+}
+
+func createChatRequest(conf globals.ChannelConfig, props *adaptercommon.ChatProps, hook globals.Hook) error {
+	return NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&ChatProps{
+		Model:       props.Model,
+		Message:     props.Message,
+		Token:       tokenLimit.GetTokenValue(props),
+		Temperature: props.Temperature,
+		TopP:        props.TopP,
+		TopK:        props.TopK,
+		Tools:       props.Tools,
+		ToolChoice:  props.ToolChoice,
+		Stop:        props.Stop,
+		User:        props.User,
+		Buffer:      props.Buffer,
+	}, hook)
+}
@@ -0,0 +1,32 @@
+package anthropic
+
+import (
+	"chat/globals"
+)
+
+type ChatInstance struct {
+	Endpoint string
+	ApiKey   string
+}
+
+func NewChatInstance(endpoint, apiKey string) *ChatInstance {
+	return &ChatInstance{
+		Endpoint: endpoint,
+		ApiKey:   apiKey,
+	}
+}
+
+func NewChatInstanceFromConfig(conf globals.ChannelConfig) *ChatInstance {
+	return NewChatInstance(
+		conf.GetEndpoint(),
+		conf.GetRandomSecret(),
+	)
+}
+
+func (c *ChatInstance) GetEndpoint() string {
+	return c.Endpoint
+}
+
+func (c *ChatInstance) GetApiKey() string {
+	return c.ApiKey
+}
@@ -0,0 +1,105 @@
+package anthropic
+
+import "chat/globals"
+
+const (
+	TextType       = "text"
+	ImageType      = "image"
+	ToolUseType    = "tool_use"
+	ToolResultType = "tool_result"
+)
+
+type ImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+// Content is the content block of anthropic messages
+type Content struct {
+	Type      string       `json:"type"`
+	Text      *string      `json:"text,omitempty"`
+	Source    *ImageSource `json:"source,omitempty"`      // only `image` type
+	Id        *string      `json:"id,omitempty"`          // only `tool_use` type
+	Name      *string      `json:"name,omitempty"`        // only `tool_use` type
+	Input     interface{}  `json:"input,omitempty"`       // only `tool_use` type
+	ToolUseId *string      `json:"tool_use_id,omitempty"` // only `tool_result` type
+	Content   *string      `json:"content,omitempty"`     // only `tool_result` type
+}
+
+type Message struct {
+	Role    string    `json:"role"`
+	Content []Content `json:"content"`
+}
+
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema globals.ToolParameters `json:"input_schema"`
+}
+
+type ToolChoice struct {
+	Type string  `json:"type"` // auto, any or tool
+	Name *string `json:"name,omitempty"`
+}
+
+// Metadata is the metadata of the request, user_id is the end user for the abuse detection of anthropic
+type Metadata struct {
+	UserId string `json:"user_id"`
+}
+
+// ChatBody is the request body for anthropic messages api
+type ChatBody struct {
+	Model         string      `json:"model"`
+	Messages      []Message   `json:"messages"`
+	System        string      `json:"system,omitempty"`
+	MaxTokens     int         `json:"max_tokens"`
+	Stream        bool        `json:"stream"`
+	StopSequences []string    `json:"stop_sequences,omitempty"`
+	Temperature   *float32    `json:"temperature,omitempty"`
+	TopP          *float32    `json:"top_p,omitempty"`
+	TopK          *int        `json:"top_k,omitempty"`
+	Tools         []Tool      `json:"tools,omitempty"`
+	ToolChoice    *ToolChoice `json:"tool_choice,omitempty"`
+	Metadata      *Metadata   `json:"metadata,omitempty"`
+}
+
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type Error struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// ChatResponse is the native http response body for anthropic messages api
+type ChatResponse struct {
+	Id         string    `json:"id"`
+	Type       string    `json:"type"`
+	Role       string    `json:"role"`
+	Model      string    `json:"model"`
+	Content    []Content `json:"content"`
+	StopReason *string   `json:"stop_reason"`
+	Usage      Usage     `json:"usage"`
+	Error      *Error    `json:"error,omitempty"`
+}
+
+type Delta struct {
+	Type        string  `json:"type"`
+	Text        string  `json:"text"`         // only `text_delta` type
+	PartialJson string  `json:"partial_json"` // only `input_json_delta` type
+	StopReason  *string `json:"stop_reason"`  // only `message_delta` event
+}
+
+// StreamResponse is the stream event body for anthropic messages api
+type StreamResponse struct {
+	Type         string        `json:"type"`
+	Index        int           `json:"index"`
+	Message      *ChatResponse `json:"message,omitempty"`       // only `message_start` event
+	ContentBlock *Content      `json:"content_block,omitempty"` // only `content_block_start` event
+	Delta        *Delta        `json:"delta,omitempty"`
+	Usage        *Usage        `json:"usage,omitempty"` // only `message_delta` event
+	Error        *Error        `json:"error,omitempty"` // only `error` event
+}
@@ -0,0 +1,91 @@
+package adapter
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/globals"
+	"fmt"
+	"strings"
+)
+
+type AudioProps = adaptercommon.AudioProps
+type AudioResponse = adaptercommon.AudioResponse
+type SpeechProps = adaptercommon.SpeechProps
+type SpeechResponse = adaptercommon.SpeechResponse
+
+// IsAudioSupported returns whether the channel type supports the audio api
+func IsAudioSupported(t string) bool {
+	_, ok := adaptercommon.GetProvider(t).(adaptercommon.AudioCreator)
+	return ok
+}
+
+func getAudioCreator(conf globals.ChannelConfig, model string) (adaptercommon.AudioCreator, error) {
+	creator, ok := adaptercommon.GetProvider(conf.GetType()).(adaptercommon.AudioCreator)
+	if !ok {
+		return nil, conf.ProcessError(fmt.Errorf("channel type %s does not support audio api for model %s", conf.GetType(), model))
+	}
+	return creator, nil
+}
+
+func NewTranscriptionRequest(conf globals.ChannelConfig, props *AudioProps) (*AudioResponse, error) {
+	creator, err := getAudioCreator(conf, props.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	return createRetryTranscriptionRequest(creator, conf, props)
+}
+
+func createTranscriptionRequest(creator adaptercommon.AudioCreator, conf globals.ChannelConfig, props *AudioProps) (*AudioResponse, error) {
+	// copy the props to avoid the reflection model leaking to the other channels
+	instance := *props
+	instance.Model = conf.GetModelReflect(props.Model)
+
+	return creator.CreateTranscription(conf, &instance)
+}
+
+func createRetryTranscriptionRequest(creator adaptercommon.AudioCreator, conf globals.ChannelConfig, props *AudioProps) (*AudioResponse, error) {
+	resp, err := createTranscriptionRequest(creator, conf, props)
+
+	retries := conf.GetRetry()
+	props.Current++
+
+	if IsAvailableError(err) && props.Current < retries {
+		content := strings.Replace(err.Error(), "\n", "", -1)
+		globals.Warn(fmt.Sprintf("retrying %s request for %s (attempt %d/%d, error: %s)", props.Type, props.Model, props.Current+1, retries, content))
+		return createRetryTranscriptionRequest(creator, conf, props)
+	}
+
+	return resp, conf.ProcessError(err)
+}
+
+func NewSpeechRequest(conf globals.ChannelConfig, props *SpeechProps) (*SpeechResponse, error) {
+	creator, err := getAudioCreator(conf, props.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	return createRetrySpeechRequest(creator, conf, props)
+}
+
+func createSpeechRequest(creator adaptercommon.AudioCreator, conf globals.ChannelConfig, props *SpeechProps) (*SpeechResponse, error) {
+	// copy the props to avoid the reflection model leaking to the other channels
+	instance := *props
+	instance.Model = conf.GetModelReflect(props.Model)
+
+	return creator.CreateSpeech(conf, &instance)
+}
+
+func createRetrySpeechRequest(creator adaptercommon.AudioCreator, conf globals.ChannelConfig, props *SpeechProps) (*SpeechResponse, error) {
+	resp, err := createSpeechRequest(creator, conf, props)
+
+	retries := conf.GetRetry()
+	props.Current++
+
+	if IsAvailableError(err) && props.Current < retries {
+		content := strings.Replace(err.Error(), "\n", "", -1)
+		globals.Warn(fmt.Sprintf("retrying speech request for %s (attempt %d/%d, error: %s)", props.Model, props.Current+1, retries, content))
+		return createRetrySpeechRequest(creator, conf, props)
+	}
+
+	return resp, conf.ProcessError(err)
+}
@@ -0,0 +1,91 @@
+package azure
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+	"strings"
+)
+
+// SpeechRequest is the request body for azure text-to-speech, the model is specified by the deployment
+type SpeechRequest struct {
+	Input          string   `json:"input"`
+	Voice          string   `json:"voice"`
+	ResponseFormat *string  `json:"response_format,omitempty"`
+	Speed          *float32 `json:"speed,omitempty"`
+}
+
+// AudioErrorResponse is the error response body for azure audio api
+type AudioErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// TranscriptionResponse is the native http response body for azure transcriptions and translations (verbose json)
+type TranscriptionResponse struct {
+	adaptercommon.AudioResponse
+	AudioErrorResponse
+}
+
+func (c *ChatInstance) GetAudioEndpoint(model string, path string) string {
+	model = c.GetDeployment(model, globals.AzureAudioDeployment)
+	return fmt.Sprintf("%s/openai/deployments/%s/audio/%s?api-version=%s", c.GetResource(), model, path, c.GetEndpoint())
+}
+
+// GetTranscriptionFields returns the fields of the multipart form, the model is specified by the deployment
+func (c *ChatInstance) GetTranscriptionFields(props *adaptercommon.AudioProps) map[string]string {
+	fields := props.GetFields()
+	delete(fields, "model")
+	return fields
+}
+
+func (c *ChatInstance) GetSpeechBody(props *adaptercommon.SpeechProps) SpeechRequest {
+	return SpeechRequest{
+		Input:          props.Input,
+		Voice:          props.Voice,
+		ResponseFormat: props.ResponseFormat,
+		Speed:          props.Speed,
+	}
+}
+
+// CreateTranscriptionRequest is the native http request for azure transcriptions and translations
+func (c *ChatInstance) CreateTranscriptionRequest(props *adaptercommon.AudioProps) (*adaptercommon.AudioResponse, error) {
+	res, err := utils.PostMultipart(c.GetAudioEndpoint(props.Model, props.GetPath()), c.GetHeader(), c.GetTranscriptionFields(props), props.GetFiles())
+	if err != nil || res == nil {
+		return nil, fmt.Errorf("azure error: %s", utils.GetError(err))
+	}
+
+	data := utils.MapToStruct[TranscriptionResponse](res)
+	if data == nil {
+		return nil, fmt.Errorf("azure error: cannot parse response")
+	} else if data.Error.Message != "" {
+		return nil, fmt.Errorf("azure error: %s", data.Error.Message)
+	}
+
+	return &data.AudioResponse, nil
+}
+
+// CreateSpeechRequest is the native http request for azure text-to-speech, the audio is returned as binary
+func (c *ChatInstance) CreateSpeechRequest(props *adaptercommon.SpeechProps) (*adaptercommon.SpeechResponse, error) {
+	data, contentType, err := utils.PostRaw(c.GetAudioEndpoint(props.Model, "speech"), c.GetHeader(), c.GetSpeechBody(props))
+	if err != nil {
+		return nil, fmt.Errorf("azure error: %s", err.Error())
+	}
+
+	if strings.HasPrefix(contentType, "application/json") {
+		form := utils.UnmarshalForm[AudioErrorResponse](string(data))
+		if form == nil || form.Error.Message == "" {
+			return nil, fmt.Errorf("azure error: cannot parse response")
+		}
+		return nil, fmt.Errorf("azure error: %s", form.Error.Message)
+	} else if len(data) == 0 {
+		return nil, fmt.Errorf("azure error: empty speech response")
+	}
+
+	return &adaptercommon.SpeechResponse{
+		Data:        data,
+		ContentType: contentType,
+	}, nil
+}
@@ -17,11 +17,16 @@ type ChatProps struct {
 	TopP             *float32
 	Tools            *globals.FunctionTools
 	ToolChoice       *interface{}
-	Buffer           utils.Buffer
+	Stop             []string
+	Seed             *int
+	LogitBias        *map[string]float32
+	ResponseFormat   *interface{}
+	User             *string
+	Buffer           *utils.Buffer
 }
 
 func (c *ChatInstance) GetChatEndpoint(props *ChatProps) string {
-	model := strings.ReplaceAll(props.Model, ".", "")
+	model := c.GetDeployment(props.Model, globals.AzureChatDeployment)
 	if props.Model == globals.GPT3TurboInstruct {
 		return fmt.Sprintf("%s/openai/deployments/%s/completions?api-version=%s", c.GetResource(), model, c.GetEndpoint())
 	}
@@ -64,6 +69,11 @@ func (c *ChatInstance) GetChatBody(props *ChatProps, stream bool) interface{} {
 		TopP:             props.TopP,
 		Tools:            props.Tools,
 		ToolChoice:       props.ToolChoice,
+		Stop:             props.Stop,
+		Seed:             props.Seed,
+		LogitBias:        props.LogitBias,
+		ResponseFormat:   props.ResponseFormat,
+		User:             props.User,
 	}
 }
 
@@ -139,7 +149,7 @@ func (c *ChatInstance) CreateStreamChatRequest(props *ChatProps, callback global
 
 	if err != nil {
 		return err
-	} else if len(chunk) == 0 {
+	} else if len(chunk) == 0 && !props.Buffer.IsFunctionCalling() {
 		return fmt.Errorf("empty response")
 	}
 
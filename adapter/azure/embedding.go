@@ -0,0 +1,60 @@
+package azure
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+)
+
+// EmbeddingRequest is the request body for azure embeddings, the model is specified by the deployment
+type EmbeddingRequest struct {
+	Input          interface{} `json:"input"`
+	EncodingFormat *string     `json:"encoding_format,omitempty"`
+	Dimensions     *int        `json:"dimensions,omitempty"`
+	User           *string     `json:"user,omitempty"`
+}
+
+// EmbeddingResponse is the native http response body for azure embeddings
+type EmbeddingResponse struct {
+	adaptercommon.EmbeddingResponse
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (c *ChatInstance) GetEmbeddingEndpoint(model string) string {
+	model = c.GetDeployment(model, globals.AzureEmbeddingDeployment)
+	return fmt.Sprintf("%s/openai/deployments/%s/embeddings?api-version=%s", c.GetResource(), model, c.GetEndpoint())
+}
+
+func (c *ChatInstance) GetEmbeddingBody(props *adaptercommon.EmbeddingProps) EmbeddingRequest {
+	return EmbeddingRequest{
+		Input:          props.Input.GetValue(),
+		EncodingFormat: props.EncodingFormat,
+		Dimensions:     props.Dimensions,
+		User:           props.User,
+	}
+}
+
+// CreateEmbeddingRequest is the native http request for azure embeddings
+func (c *ChatInstance) CreateEmbeddingRequest(props *adaptercommon.EmbeddingProps) (*adaptercommon.EmbeddingResponse, error) {
+	res, err := utils.Post(c.GetEmbeddingEndpoint(props.Model), c.GetHeader(), c.GetEmbeddingBody(props))
+	if err != nil || res == nil {
+		return nil, fmt.Errorf("azure error: %s", utils.GetError(err))
+	}
+
+	data := utils.MapToStruct[EmbeddingResponse](res)
+	if data == nil {
+		return nil, fmt.Errorf("azure error: cannot parse response")
+	} else if data.Error.Message != "" {
+		return nil, fmt.Errorf("azure error: %s", data.Error.Message)
+	} else if len(data.Data) == 0 {
+		return nil, fmt.Errorf("azure error: empty embedding response")
+	}
+
+	if len(data.Model) == 0 {
+		data.Model = props.Model
+	}
+	return &data.EmbeddingResponse, nil
+}
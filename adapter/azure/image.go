@@ -1,12 +1,32 @@
 package azure
 
 import (
+	adaptercommon "chat/adapter/common"
 	"chat/globals"
 	"chat/utils"
 	"fmt"
 	"strings"
 )
 
+// ImagesRequest is the request body for azure images generation api, the model is specified by the deployment
+type ImagesRequest struct {
+	Prompt         string  `json:"prompt"`
+	N              *int    `json:"n,omitempty"`
+	Size           *string `json:"size,omitempty"`
+	Quality        *string `json:"quality,omitempty"`
+	Style          *string `json:"style,omitempty"`
+	ResponseFormat *string `json:"response_format,omitempty"`
+	User           *string `json:"user,omitempty"`
+}
+
+// ImagesResponse is the native http response body for azure images api
+type ImagesResponse struct {
+	adaptercommon.ImageResponse
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
 type ImageProps struct {
 	Model  string
 	Prompt string
@@ -14,7 +34,7 @@ type ImageProps struct {
 }
 
 func (c *ChatInstance) GetImageEndpoint(model string) string {
-	model = strings.ReplaceAll(model, ".", "")
+	model = c.GetDeployment(model, globals.AzureImageDeployment)
 	return fmt.Sprintf("%s/openai/deployments/%s/images/generations?api-version=%s", c.GetResource(), model, c.GetEndpoint())
 }
 
@@ -60,3 +80,53 @@ func (c *ChatInstance) CreateImage(props *ChatProps) (string, error) {
 
 	return utils.GetImageMarkdown(url), nil
 }
+
+func (c *ChatInstance) GetImagesEndpoint(props *adaptercommon.ImageProps) string {
+	model := c.GetDeployment(props.Model, globals.AzureImageDeployment)
+	return fmt.Sprintf("%s/openai/deployments/%s/images/%s?api-version=%s", c.GetResource(), model, props.GetPath(), c.GetEndpoint())
+}
+
+// GetImagesFields returns the fields of the multipart form, the model is specified by the deployment
+func (c *ChatInstance) GetImagesFields(props *adaptercommon.ImageProps) map[string]string {
+	fields := props.GetFields()
+	delete(fields, "model")
+	return fields
+}
+
+func (c *ChatInstance) GetImagesBody(props *adaptercommon.ImageProps) ImagesRequest {
+	return ImagesRequest{
+		Prompt:         props.Prompt,
+		N:              props.N,
+		Size:           props.Size,
+		Quality:        props.Quality,
+		Style:          props.Style,
+		ResponseFormat: props.ResponseFormat,
+		User:           props.User,
+	}
+}
+
+// CreateImagesRequest is the native http request for azure images api, the edit and variation requests are sent as the multipart form
+func (c *ChatInstance) CreateImagesRequest(props *adaptercommon.ImageProps) (*adaptercommon.ImageResponse, error) {
+	var res interface{}
+	var err error
+	if props.IsMultipart() {
+		res, err = utils.PostMultipart(c.GetImagesEndpoint(props), c.GetHeader(), c.GetImagesFields(props), props.GetFiles())
+	} else {
+		res, err = utils.Post(c.GetImagesEndpoint(props), c.GetHeader(), c.GetImagesBody(props))
+	}
+
+	if err != nil || res == nil {
+		return nil, fmt.Errorf("azure error: %s", utils.GetError(err))
+	}
+
+	data := utils.MapToStruct[ImagesResponse](res)
+	if data == nil {
+		return nil, fmt.Errorf("azure error: cannot parse response")
+	} else if data.Error.Message != "" {
+		return nil, fmt.Errorf("azure error: %s", data.Error.Message)
+	} else if len(data.Data) == 0 {
+		return nil, fmt.Errorf("azure error: empty image response")
+	}
+
+	return &data.ImageResponse, nil
+}
@@ -1,6 +1,7 @@
 package azure
 
 import (
+	adaptercommon "chat/adapter/common"
 	"chat/globals"
 	"chat/utils"
 	"errors"
@@ -37,43 +38,9 @@ func formatMessages(props *ChatProps) interface{} {
 		return props.Message
 	} else if globals.IsGPT41106VisionPreview(props.Model) {
 		return utils.Each[globals.Message, Message](props.Message, func(message globals.Message) Message {
-			if message.Role == globals.User {
-				urls := utils.ExtractImageUrls(message.Content)
-				images := utils.EachNotNil[string, MessageContent](urls, func(url string) *MessageContent {
-					obj, err := utils.NewImage(url)
-					if err != nil {
-						return nil
-					}
-
-					props.Buffer.AddImage(obj)
-
-					return &MessageContent{
-						Type: "image_url",
-						ImageUrl: &ImageUrl{
-							Url: url,
-						},
-					}
-				})
-
-				return Message{
-					Role: message.Role,
-					Content: utils.Prepend(images, MessageContent{
-						Type: "text",
-						Text: &message.Content,
-					}),
-					ToolCalls:  message.ToolCalls,
-					ToolCallId: message.ToolCallId,
-				}
-			}
-
 			return Message{
-				Role: message.Role,
-				Content: MessageContents{
-					MessageContent{
-						Type: "text",
-						Text: &message.Content,
-					},
-				},
+				Role:       message.Role,
+				Content:    adaptercommon.GetMessageContents(message, props.Buffer),
 				ToolCalls:  message.ToolCalls,
 				ToolCallId: message.ToolCallId,
 			}
@@ -93,6 +60,11 @@ func processChatResponse(data string) *ChatStreamResponse {
 		if form = utils.UnmarshalForm[ChatStreamResponse](data[:len(data)-1]); form != nil {
 			return form
 		}
+
+		// the usage chunk ends with the nested object, so the closing brace is not appended by the formatter
+		if form = utils.UnmarshalForm[ChatStreamResponse](data + "}"); form != nil && form.Data.Usage != nil {
+			return form
+		}
 	}
 
 	return nil
@@ -173,7 +145,7 @@ func getRobustnessResult(chunk string) string {
 	}
 }
 
-func (c *ChatInstance) ProcessLine(obj utils.Buffer, instruct bool, buf, data string) (string, error) {
+func (c *ChatInstance) ProcessLine(obj *utils.Buffer, instruct bool, buf, data string) (string, error) {
 	item := processFormat(buf + data)
 	if isDone(item) {
 		return "", nil
@@ -204,6 +176,11 @@ func (c *ChatInstance) ProcessLine(obj utils.Buffer, instruct bool, buf, data st
 		}
 
 	} else {
+		if usage := form.Data.Usage; usage != nil {
+			obj.SetInputTokens(usage.PromptTokens)
+			obj.SetOutputTokens(usage.CompletionTokens)
+		}
+
 		obj.SetToolCalls(getToolCalls(form))
 		return getChoices(form), nil
 	}
@@ -2,12 +2,14 @@ package azure
 
 import (
 	"chat/globals"
+	"strings"
 )
 
 type ChatInstance struct {
-	Endpoint string
-	ApiKey   string
-	Resource string
+	Endpoint    string // api version
+	ApiKey      string
+	Resource    string
+	Deployments globals.AzureChannelConfig // nil if the channel has no azure settings
 }
 
 type InstanceProps struct {
@@ -27,6 +29,17 @@ func (c *ChatInstance) GetResource() string {
 	return c.Resource
 }
 
+// GetDeployment returns the deployment of the model, the deployment is the model name without dots if it is not configured
+func (c *ChatInstance) GetDeployment(model string, kind string) string {
+	if c.Deployments != nil {
+		if deployment := c.Deployments.GetAzureDeployment(model, kind); len(deployment) > 0 {
+			return deployment
+		}
+	}
+
+	return strings.ReplaceAll(model, ".", "")
+}
+
 func (c *ChatInstance) GetHeader() map[string]string {
 	return map[string]string{
 		"Content-Type": "application/json",
@@ -44,9 +57,15 @@ func NewChatInstance(endpoint, apiKey string, resource string) *ChatInstance {
 
 func NewChatInstanceFromConfig(conf globals.ChannelConfig) *ChatInstance {
 	param := conf.SplitRandomSecret(2)
-	return NewChatInstance(
+	instance := NewChatInstance(
 		conf.GetEndpoint(),
 		param[0],
 		param[1],
 	)
+
+	if azure, ok := conf.(globals.AzureChannelConfig); ok {
+		instance.Endpoint = azure.GetAzureApiVersion()
+		instance.Deployments = azure
+	}
+	return instance
 }
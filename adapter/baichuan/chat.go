@@ -13,6 +13,7 @@ type ChatProps struct {
 	TopP        *float32
 	TopK        *int
 	Temperature *float32
+	Buffer      *utils.Buffer
 }
 
 func (c *ChatInstance) GetChatEndpoint() string {
@@ -82,7 +83,7 @@ func (c *ChatInstance) CreateStreamChatRequest(props *ChatProps, callback global
 		c.GetHeader(),
 		c.GetChatBody(props, true),
 		func(data string) error {
-			data, err := c.ProcessLine(buf, data)
+			data, err := c.ProcessLine(props.Buffer, buf, data)
 			chunk += data
 
 			if err != nil {
@@ -69,21 +69,35 @@ func getChoices(form *ChatStreamResponse) string {
 		if len(form.Choices) > 0 {
 			return form.Choices[0].Delta.Content
 		}
+		return ""
 	}
 
 	return form.Data.Choices[0].Delta.Content
 }
 
-func (c *ChatInstance) ProcessLine(buf, data string) (string, error) {
+func getUsage(form *ChatStreamResponse) *Usage {
+	if form.Data.Usage != nil {
+		return form.Data.Usage
+	}
+
+	return form.Usage
+}
+
+func (c *ChatInstance) ProcessLine(obj *utils.Buffer, buf, data string) (string, error) {
 	item := processFormat(buf + data)
 	if isDone(item) {
 		return "", nil
 	}
 
+	// the error chunk is also a valid chat response without choices, check it first
+	if err := processChatErrorResponse(item); err != nil && err.Data.Error.Message != "" {
+		return "", fmt.Errorf("baichuan error: %s (type: %s)", err.Data.Error.Message, err.Data.Error.Type)
+	}
+
 	if form := processChatResponse(item); form == nil {
 		// recursive call
 		if len(buf) > 0 {
-			return c.ProcessLine("", buf+item)
+			return c.ProcessLine(obj, "", buf+item)
 		}
 
 		if err := processChatErrorResponse(item); err == nil || err.Data.Error.Message == "" {
@@ -94,6 +108,11 @@ func (c *ChatInstance) ProcessLine(buf, data string) (string, error) {
 		}
 
 	} else {
+		if usage := getUsage(form); usage != nil {
+			obj.SetInputTokens(usage.PromptTokens)
+			obj.SetOutputTokens(usage.CompletionTokens)
+		}
+
 		return getChoices(form), nil
 	}
 }
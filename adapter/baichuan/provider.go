@@ -0,0 +1,30 @@
+package baichuan
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/globals"
+)
+
+func init() {
+	adaptercommon.Register(adaptercommon.NewProvider(
+		globals.BaichuanChannelType,
+		[]string{
+			adaptercommon.ParamTemperature,
+			adaptercommon.ParamTopP,
+			adaptercommon.ParamTopK,
+		},
+		adaptercommon.TokenLimit{},
+		createChatRequest,
+	))
+}
+
+func createChatRequest(conf globals.ChannelConfig, props *adaptercommon.ChatProps, hook globals.Hook) error {
+	return NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&ChatProps{
+		Model:       props.Model,
+		Message:     props.Message,
+		TopP:        props.TopP,
+		TopK:        props.TopK,
+		Temperature: props.Temperature,
+		Buffer:      props.Buffer,
+	}, hook)
+}
@@ -43,6 +43,7 @@ type ChatStreamResponse struct {
 			}
 			Index int `json:"index"`
 		} `json:"choices"`
+		Usage *Usage `json:"usage,omitempty"`
 	} `json:"data"`
 
 	ID      string `json:"id"`
@@ -55,6 +56,13 @@ type ChatStreamResponse struct {
 		}
 		Index int `json:"index"`
 	} `json:"choices"`
+	Usage *Usage `json:"usage,omitempty"` // only the last chunk
+}
+
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
 }
 
 type ChatStreamErrorResponse struct {
@@ -0,0 +1,24 @@
+package adapter
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/globals"
+	"fmt"
+)
+
+// IsBalanceSupported returns whether the channel type can query the remaining balance of the secret
+func IsBalanceSupported(t string) bool {
+	_, ok := adaptercommon.GetProvider(t).(adaptercommon.BalanceQuerier)
+	return ok
+}
+
+// GetBalance returns the remaining balance of the secret of the channel in usd
+func GetBalance(conf globals.ChannelConfig) (float64, error) {
+	querier, ok := adaptercommon.GetProvider(conf.GetType()).(adaptercommon.BalanceQuerier)
+	if !ok {
+		return 0, conf.ProcessError(fmt.Errorf("channel type %s does not support querying balance", conf.GetType()))
+	}
+
+	balance, err := querier.GetBalance(conf)
+	return balance, conf.ProcessError(err)
+}
@@ -0,0 +1,22 @@
+package bing
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/globals"
+)
+
+func init() {
+	adaptercommon.Register(adaptercommon.NewProvider(
+		globals.BingChannelType,
+		[]string{},
+		adaptercommon.TokenLimit{},
+		createChatRequest,
+	))
+}
+
+func createChatRequest(conf globals.ChannelConfig, props *adaptercommon.ChatProps, hook globals.Hook) error {
+	return NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&ChatProps{
+		Model:   props.Model,
+		Message: props.Message,
+	}, hook)
+}
@@ -1,6 +1,7 @@
 package chatgpt
 
 import (
+	adaptercommon "chat/adapter/common"
 	"chat/globals"
 	"chat/utils"
 	"fmt"
@@ -17,7 +18,12 @@ type ChatProps struct {
 	TopP             *float32
 	Tools            *globals.FunctionTools
 	ToolChoice       *interface{}
-	Buffer           utils.Buffer
+	Stop             []string
+	Seed             *int
+	LogitBias        *map[string]float32
+	ResponseFormat   *interface{}
+	User             *string
+	Buffer           *utils.Buffer
 }
 
 func (c *ChatInstance) GetChatEndpoint(props *ChatProps) string {
@@ -65,6 +71,12 @@ func (c *ChatInstance) GetChatBody(props *ChatProps, stream bool) interface{} {
 		TopP:             props.TopP,
 		Tools:            props.Tools,
 		ToolChoice:       props.ToolChoice,
+		Stop:             props.Stop,
+		Seed:             props.Seed,
+		LogitBias:        props.LogitBias,
+		ResponseFormat:   props.ResponseFormat,
+		User:             props.User,
+		StreamOptions:    utils.Multi[*StreamOptions](stream && !adaptercommon.IsStreamOptionsRejected(c.GetEndpoint()), &StreamOptions{IncludeUsage: true}, nil),
 	}
 }
 
@@ -108,39 +120,41 @@ func (c *ChatInstance) CreateStreamChatRequest(props *ChatProps, callback global
 	chunk := ""
 	instruct := props.Model == globals.GPT3TurboInstruct
 
-	err := utils.EventSource(
-		"POST",
-		c.GetChatEndpoint(props),
-		c.GetHeader(),
-		c.GetChatBody(props, true),
-		func(data string) error {
-			data, err := c.ProcessLine(props.Buffer, instruct, buf, data)
-			chunk += data
-
-			if err != nil {
-				if strings.HasPrefix(err.Error(), "chatgpt error") {
-					return err
+	err := adaptercommon.WithStreamOptions(c.GetEndpoint(), func() error {
+		return utils.EventSource(
+			"POST",
+			c.GetChatEndpoint(props),
+			c.GetHeader(),
+			c.GetChatBody(props, true),
+			func(data string) error {
+				data, err := c.ProcessLine(props.Buffer, instruct, buf, data)
+				chunk += data
+
+				if err != nil {
+					if strings.HasPrefix(err.Error(), "chatgpt error") {
+						return err
+					}
+
+					// error when break line
+					buf = buf + data
+					return nil
 				}
 
-				// error when break line
-				buf = buf + data
-				return nil
-			}
-
-			buf = ""
-			if data != "" {
-				cursor += 1
-				if err := callback(data); err != nil {
-					return err
+				buf = ""
+				if data != "" {
+					cursor += 1
+					if err := callback(data); err != nil {
+						return err
+					}
 				}
-			}
-			return nil
-		},
-	)
+				return nil
+			},
+		)
+	})
 
 	if err != nil {
 		return err
-	} else if len(chunk) == 0 {
+	} else if len(chunk) == 0 && !props.Buffer.IsFunctionCalling() {
 		return fmt.Errorf("empty response")
 	}
 
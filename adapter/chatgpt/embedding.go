@@ -0,0 +1,57 @@
+package chatgpt
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/utils"
+	"fmt"
+)
+
+// EmbeddingRequest is the request body for chatgpt embeddings
+type EmbeddingRequest struct {
+	Model          string      `json:"model"`
+	Input          interface{} `json:"input"`
+	EncodingFormat *string     `json:"encoding_format,omitempty"`
+	Dimensions     *int        `json:"dimensions,omitempty"`
+	User           *string     `json:"user,omitempty"`
+}
+
+// EmbeddingResponse is the native http response body for chatgpt embeddings
+type EmbeddingResponse struct {
+	adaptercommon.EmbeddingResponse
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (c *ChatInstance) GetEmbeddingEndpoint() string {
+	return fmt.Sprintf("%s/v1/embeddings", c.GetEndpoint())
+}
+
+func (c *ChatInstance) GetEmbeddingBody(props *adaptercommon.EmbeddingProps) EmbeddingRequest {
+	return EmbeddingRequest{
+		Model:          props.Model,
+		Input:          props.Input.GetValue(),
+		EncodingFormat: props.EncodingFormat,
+		Dimensions:     props.Dimensions,
+		User:           props.User,
+	}
+}
+
+// CreateEmbeddingRequest is the native http request for chatgpt embeddings
+func (c *ChatInstance) CreateEmbeddingRequest(props *adaptercommon.EmbeddingProps) (*adaptercommon.EmbeddingResponse, error) {
+	res, err := utils.Post(c.GetEmbeddingEndpoint(), c.GetHeader(), c.GetEmbeddingBody(props))
+	if err != nil || res == nil {
+		return nil, fmt.Errorf("chatgpt error: %s", utils.GetError(err))
+	}
+
+	data := utils.MapToStruct[EmbeddingResponse](res)
+	if data == nil {
+		return nil, fmt.Errorf("chatgpt error: cannot parse response")
+	} else if data.Error.Message != "" {
+		return nil, fmt.Errorf("chatgpt error: %s", data.Error.Message)
+	} else if len(data.Data) == 0 {
+		return nil, fmt.Errorf("chatgpt error: empty embedding response")
+	}
+
+	return &data.EmbeddingResponse, nil
+}
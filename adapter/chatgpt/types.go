@@ -1,25 +1,15 @@
 package chatgpt
 
-import "chat/globals"
-
-type ImageUrl struct {
-	Url    string  `json:"url"`
-	Detail *string `json:"detail,omitempty"`
-}
-
-type MessageContent struct {
-	Type     string    `json:"type"`
-	Text     *string   `json:"text,omitempty"`
-	ImageUrl *ImageUrl `json:"image_url,omitempty"`
-}
-
-type MessageContents []MessageContent
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/globals"
+)
 
 type Message struct {
-	Role       string             `json:"role"`
-	Content    MessageContents    `json:"content"`
-	ToolCallId *string            `json:"tool_call_id,omitempty"` // only `tool` role
-	ToolCalls  *globals.ToolCalls `json:"tool_calls,omitempty"`   // only `assistant` role
+	Role       string                        `json:"role"`
+	Content    adaptercommon.MessageContents `json:"content"`
+	ToolCallId *string                       `json:"tool_call_id,omitempty"` // only `tool` role
+	ToolCalls  *globals.ToolCalls            `json:"tool_calls,omitempty"`   // only `assistant` role
 }
 
 // ChatRequest is the request body for chatgpt
@@ -34,6 +24,23 @@ type ChatRequest struct {
 	TopP             *float32               `json:"top_p,omitempty"`
 	Tools            *globals.FunctionTools `json:"tools,omitempty"`
 	ToolChoice       *interface{}           `json:"tool_choice,omitempty"` // string or object
+	Stop             []string               `json:"stop,omitempty"`
+	Seed             *int                   `json:"seed,omitempty"`
+	LogitBias        *map[string]float32    `json:"logit_bias,omitempty"`
+	ResponseFormat   *interface{}           `json:"response_format,omitempty"`
+	User             *string                `json:"user,omitempty"`
+	StreamOptions    *StreamOptions         `json:"stream_options,omitempty"`
+}
+
+// StreamOptions asks the upstream to send the usage chunk at the end of the stream
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
 }
 
 // CompletionRequest is the request body for chatgpt completion
@@ -72,6 +79,7 @@ type ChatStreamResponse struct {
 			Index        int             `json:"index"`
 			FinishReason string          `json:"finish_reason"`
 		} `json:"choices"`
+		Usage *Usage `json:"usage,omitempty"` // only the last chunk if the usage is included
 	} `json:"data"`
 }
 
@@ -0,0 +1,32 @@
+package claude
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/globals"
+)
+
+var tokenLimit = adaptercommon.TokenLimit{Default: 50000}
+
+func init() {
+	adaptercommon.Register(adaptercommon.NewProvider(
+		globals.ClaudeChannelType,
+		[]string{
+			adaptercommon.ParamTemperature,
+			adaptercommon.ParamTopP,
+			adaptercommon.ParamTopK,
+		},
+		tokenLimit,
+		createChatRequest,
+	))
+}
+
+func createChatRequest(conf globals.ChannelConfig, props *adaptercommon.ChatProps, hook globals.Hook) error {
+	return NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&ChatProps{
+		Model:       props.Model,
+		Message:     props.Message,
+		Token:       tokenLimit.GetTokenValue(props),
+		TopP:        props.TopP,
+		TopK:        props.TopK,
+		Temperature: props.Temperature,
+	}, hook)
+}
@@ -0,0 +1,109 @@
+package adaptercommon
+
+import (
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+)
+
+const (
+	AudioTranscription = "transcription"
+	AudioTranslation   = "translation"
+)
+
+// AudioVerboseFormat is the response format requested from the upstream, since the duration of the audio is required by the billing
+const AudioVerboseFormat = "verbose_json"
+
+type AudioProps struct {
+	RequestProps
+
+	Type        string // transcription or translation
+	Model       string
+	File        *utils.MultipartFile
+	Language    *string // only transcription
+	Prompt      *string
+	Temperature *float32
+}
+
+type AudioSegment struct {
+	Id    int     `json:"id"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// AudioResponse is the verbose json response of the transcription and translation
+type AudioResponse struct {
+	Task     string         `json:"task,omitempty"`
+	Language string         `json:"language,omitempty"`
+	Duration float64        `json:"duration"`
+	Text     string         `json:"text"`
+	Segments []AudioSegment `json:"segments,omitempty"`
+}
+
+type SpeechProps struct {
+	RequestProps
+
+	Model          string
+	Input          string
+	Voice          string
+	ResponseFormat *string // mp3, opus, aac or flac
+	Speed          *float32
+}
+
+type SpeechResponse struct {
+	Data        []byte
+	ContentType string
+}
+
+// AudioHandler and SpeechHandler serve the audio api, together they are the AudioCreator capability of the provider
+type AudioHandler func(conf globals.ChannelConfig, props *AudioProps) (*AudioResponse, error)
+type SpeechHandler func(conf globals.ChannelConfig, props *SpeechProps) (*SpeechResponse, error)
+
+// AudioCreator is implemented by the providers which support the audio api (transcriptions, translations and speech)
+type AudioCreator interface {
+	CreateTranscription(conf globals.ChannelConfig, props *AudioProps) (*AudioResponse, error)
+	CreateSpeech(conf globals.ChannelConfig, props *SpeechProps) (*SpeechResponse, error)
+}
+
+func (h AudioHandler) CreateTranscription(conf globals.ChannelConfig, props *AudioProps) (*AudioResponse, error) {
+	return h(conf, props)
+}
+
+func (h SpeechHandler) CreateSpeech(conf globals.ChannelConfig, props *SpeechProps) (*SpeechResponse, error) {
+	return h(conf, props)
+}
+
+// GetPath returns the path of the openai audio api (e.g. `transcriptions`)
+func (p *AudioProps) GetPath() string {
+	if p.Type == AudioTranslation {
+		return "translations"
+	}
+	return "transcriptions"
+}
+
+// GetFields returns the text fields of the multipart form
+func (p *AudioProps) GetFields() map[string]string {
+	fields := map[string]string{
+		"model":           p.Model,
+		"response_format": AudioVerboseFormat,
+	}
+
+	if p.Language != nil && p.Type == AudioTranscription {
+		fields["language"] = *p.Language
+	}
+	if p.Prompt != nil {
+		fields["prompt"] = *p.Prompt
+	}
+	if p.Temperature != nil {
+		fields["temperature"] = fmt.Sprintf("%g", *p.Temperature)
+	}
+	return fields
+}
+
+// GetFiles returns the file fields of the multipart form
+func (p *AudioProps) GetFiles() map[string]*utils.MultipartFile {
+	return map[string]*utils.MultipartFile{
+		"file": p.File,
+	}
+}
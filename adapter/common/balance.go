@@ -0,0 +1,15 @@
+package adaptercommon
+
+import "chat/globals"
+
+// BalanceHandler queries the remaining balance of the secret, it is the BalanceQuerier capability of the provider
+type BalanceHandler func(conf globals.ChannelConfig) (float64, error)
+
+// BalanceQuerier is implemented by the providers which can query the remaining balance of the secret
+type BalanceQuerier interface {
+	GetBalance(conf globals.ChannelConfig) (float64, error)
+}
+
+func (h BalanceHandler) GetBalance(conf globals.ChannelConfig) (float64, error) {
+	return h(conf)
+}
@@ -0,0 +1,107 @@
+package adaptercommon
+
+import (
+	"chat/globals"
+	"chat/utils"
+)
+
+// CompletionProps is the request of the legacy completions api
+type CompletionProps struct {
+	RequestProps
+
+	Model            string
+	Prompt           string
+	Suffix           *string
+	Echo             bool
+	Stop             []string
+	Logprobs         *int
+	MaxTokens        *int
+	Temperature      *float32
+	TopP             *float32
+	PresencePenalty  *float32
+	FrequencyPenalty *float32
+	User             *string
+	Buffer           *utils.Buffer
+}
+
+type CompletionLogprobs struct {
+	Tokens        []string             `json:"tokens"`
+	TokenLogprobs []*float64           `json:"token_logprobs"` // the first token of the echoed prompt has no logprob
+	TopLogprobs   []map[string]float64 `json:"top_logprobs"`
+	TextOffset    []int                `json:"text_offset"`
+}
+
+type CompletionChoice struct {
+	Text         string              `json:"text"`
+	Index        int                 `json:"index"`
+	Logprobs     *CompletionLogprobs `json:"logprobs"`
+	FinishReason interface{}         `json:"finish_reason"`
+}
+
+type CompletionUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// CompletionResponse is the stream chunk of the completions api, the chunks are merged by the relay for the non-stream requests
+type CompletionResponse struct {
+	Id      string             `json:"id"`
+	Object  string             `json:"object"`
+	Created int64              `json:"created"`
+	Model   string             `json:"model"`
+	Choices []CompletionChoice `json:"choices"`
+	Usage   *CompletionUsage   `json:"usage,omitempty"`
+}
+
+// CompletionHook is called with each chunk of the stream response
+type CompletionHook func(resp *CompletionResponse) error
+
+// CompletionHandler serves the legacy completions api, it is the Completer capability of the provider
+type CompletionHandler func(conf globals.ChannelConfig, props *CompletionProps, hook CompletionHook) error
+
+// Completer is implemented by the providers which support the legacy completions api
+type Completer interface {
+	CreateCompletion(conf globals.ChannelConfig, props *CompletionProps, hook CompletionHook) error
+}
+
+func (h CompletionHandler) CreateCompletion(conf globals.ChannelConfig, props *CompletionProps, hook CompletionHook) error {
+	return h(conf, props, hook)
+}
+
+// GetNativeParams returns the parameters which can only be honored by the completions api, but not by the chat models
+func (p *CompletionProps) GetNativeParams() []string {
+	var params []string
+	if p.Suffix != nil && *p.Suffix != "" {
+		params = append(params, "suffix")
+	}
+	if p.Logprobs != nil {
+		params = append(params, "logprobs")
+	}
+	return params
+}
+
+// GetChatMessages converts the prompt to the chat messages, for the channels which only speak chat
+func (p *CompletionProps) GetChatMessages() []globals.Message {
+	return []globals.Message{
+		{
+			Role:    globals.User,
+			Content: p.Prompt,
+		},
+	}
+}
+
+// Merge appends the logprobs of the next chunk
+func (l *CompletionLogprobs) Merge(next *CompletionLogprobs) *CompletionLogprobs {
+	if next == nil {
+		return l
+	} else if l == nil {
+		return next
+	}
+
+	l.Tokens = append(l.Tokens, next.Tokens...)
+	l.TokenLogprobs = append(l.TokenLogprobs, next.TokenLogprobs...)
+	l.TopLogprobs = append(l.TopLogprobs, next.TopLogprobs...)
+	l.TextOffset = append(l.TextOffset, next.TextOffset...)
+	return l
+}
@@ -0,0 +1,155 @@
+package adaptercommon
+
+import (
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+)
+
+// EmbeddingInput is the batched input of embedding request, either texts or token arrays
+type EmbeddingInput struct {
+	Texts  []string
+	Tokens [][]int
+}
+
+type EmbeddingProps struct {
+	RequestProps
+
+	Model          string
+	Input          *EmbeddingInput
+	EncodingFormat *string
+	Dimensions     *int
+	User           *string
+}
+
+type EmbeddingData struct {
+	Object    string      `json:"object"`
+	Index     int         `json:"index"`
+	Embedding interface{} `json:"embedding"` // float array or base64 string
+}
+
+type EmbeddingUsage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+type EmbeddingResponse struct {
+	Data  []EmbeddingData `json:"data"`
+	Model string          `json:"model"`
+	Usage EmbeddingUsage  `json:"usage"`
+}
+
+// EmbeddingHandler serves the embeddings api, it is the Embedder capability of the provider
+type EmbeddingHandler func(conf globals.ChannelConfig, props *EmbeddingProps) (*EmbeddingResponse, error)
+
+// Embedder is implemented by the providers which support the embeddings api
+type Embedder interface {
+	CreateEmbedding(conf globals.ChannelConfig, props *EmbeddingProps) (*EmbeddingResponse, error)
+}
+
+func (h EmbeddingHandler) CreateEmbedding(conf globals.ChannelConfig, props *EmbeddingProps) (*EmbeddingResponse, error) {
+	return h(conf, props)
+}
+
+func toTokens(value []interface{}) ([]int, bool) {
+	tokens := make([]int, 0, len(value))
+	for _, item := range value {
+		token, ok := item.(float64)
+		if !ok || token != float64(int(token)) {
+			return nil, false
+		}
+		tokens = append(tokens, int(token))
+	}
+	return tokens, true
+}
+
+// ParseEmbeddingInput parses the openai embedding input, which is a string, string array, token array or array of token arrays
+func ParseEmbeddingInput(input interface{}) (*EmbeddingInput, error) {
+	switch value := input.(type) {
+	case string:
+		return &EmbeddingInput{Texts: []string{value}}, nil
+	case []interface{}:
+		if len(value) == 0 {
+			return nil, fmt.Errorf("input cannot be empty")
+		}
+
+		switch value[0].(type) {
+		case string:
+			texts := make([]string, 0, len(value))
+			for _, item := range value {
+				text, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("input array must contain only strings")
+				}
+				texts = append(texts, text)
+			}
+			return &EmbeddingInput{Texts: texts}, nil
+		case float64:
+			tokens, ok := toTokens(value)
+			if !ok {
+				return nil, fmt.Errorf("token array must contain only integers")
+			}
+			return &EmbeddingInput{Tokens: [][]int{tokens}}, nil
+		case []interface{}:
+			arrays := make([][]int, 0, len(value))
+			for _, item := range value {
+				array, ok := item.([]interface{})
+				if !ok {
+					return nil, fmt.Errorf("input array must contain only token arrays")
+				}
+
+				tokens, ok := toTokens(array)
+				if !ok || len(tokens) == 0 {
+					return nil, fmt.Errorf("token array must contain only integers")
+				}
+				arrays = append(arrays, tokens)
+			}
+			return &EmbeddingInput{Tokens: arrays}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("input must be a string, array of strings, array of tokens or array of token arrays")
+}
+
+// IsTokens returns whether the input is given as token arrays
+func (i *EmbeddingInput) IsTokens() bool {
+	return len(i.Tokens) > 0
+}
+
+// Len returns the batch size of the input
+func (i *EmbeddingInput) Len() int {
+	if i.IsTokens() {
+		return len(i.Tokens)
+	}
+	return len(i.Texts)
+}
+
+// GetValue returns the input in openai format
+func (i *EmbeddingInput) GetValue() interface{} {
+	if i.IsTokens() {
+		return i.Tokens
+	}
+	return i.Texts
+}
+
+// GetTexts returns the input as texts, token arrays are decoded by the tokenizer of the model
+func (i *EmbeddingInput) GetTexts(model string) []string {
+	if !i.IsTokens() {
+		return i.Texts
+	}
+
+	return utils.Each[[]int, string](i.Tokens, func(tokens []int) string {
+		return utils.DecodeTokens(tokens, model)
+	})
+}
+
+// CountTokens returns the number of the input tokens
+func (i *EmbeddingInput) CountTokens(model string) int {
+	if !i.IsTokens() {
+		return utils.NumTokensFromTexts(i.Texts, model)
+	}
+
+	return utils.Sum(utils.Each[[]int, int](i.Tokens, func(tokens []int) int {
+		return len(tokens)
+	}))
+}
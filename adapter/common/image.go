@@ -0,0 +1,105 @@
+package adaptercommon
+
+import (
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+)
+
+const (
+	ImageGeneration = "generation"
+	ImageEdit       = "edit"
+	ImageVariation  = "variation"
+)
+
+type ImageProps struct {
+	RequestProps
+
+	Type           string // generation, edit or variation
+	Model          string
+	Prompt         string
+	Image          *utils.MultipartFile // only edit and variation
+	Mask           *utils.MultipartFile // only edit
+	N              *int
+	Size           *string
+	Quality        *string
+	Style          *string
+	ResponseFormat *string
+	User           *string
+}
+
+type ImageData struct {
+	Url           string `json:"url,omitempty"`
+	B64Json       string `json:"b64_json,omitempty"`
+	RevisedPrompt string `json:"revised_prompt,omitempty"`
+}
+
+type ImageResponse struct {
+	Created int64       `json:"created"`
+	Data    []ImageData `json:"data"`
+}
+
+// ImageHandler serves the images api, it is the ImageCreator capability of the provider
+type ImageHandler func(conf globals.ChannelConfig, props *ImageProps) (*ImageResponse, error)
+
+// ImageCreator is implemented by the providers which support the images api (generations, edits and variations)
+type ImageCreator interface {
+	CreateImage(conf globals.ChannelConfig, props *ImageProps) (*ImageResponse, error)
+}
+
+func (h ImageHandler) CreateImage(conf globals.ChannelConfig, props *ImageProps) (*ImageResponse, error) {
+	return h(conf, props)
+}
+
+// GetPath returns the path of the openai images api (e.g. `generations`)
+func (p *ImageProps) GetPath() string {
+	switch p.Type {
+	case ImageEdit:
+		return "edits"
+	case ImageVariation:
+		return "variations"
+	default:
+		return "generations"
+	}
+}
+
+// IsMultipart returns whether the request is sent as the multipart form, which is used by the edit and variation requests
+func (p *ImageProps) IsMultipart() bool {
+	return p.Type == ImageEdit || p.Type == ImageVariation
+}
+
+// GetFields returns the text fields of the multipart form
+func (p *ImageProps) GetFields() map[string]string {
+	fields := map[string]string{
+		"model": p.Model,
+	}
+
+	if p.Type == ImageEdit {
+		fields["prompt"] = p.Prompt
+	}
+	if p.N != nil {
+		fields["n"] = fmt.Sprintf("%d", *p.N)
+	}
+	if p.Size != nil {
+		fields["size"] = *p.Size
+	}
+	if p.ResponseFormat != nil {
+		fields["response_format"] = *p.ResponseFormat
+	}
+	if p.User != nil {
+		fields["user"] = *p.User
+	}
+	return fields
+}
+
+// GetFiles returns the file fields of the multipart form
+func (p *ImageProps) GetFiles() map[string]*utils.MultipartFile {
+	files := map[string]*utils.MultipartFile{
+		"image": p.Image,
+	}
+
+	if p.Type == ImageEdit && p.Mask != nil {
+		files["mask"] = p.Mask
+	}
+	return files
+}
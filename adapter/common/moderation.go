@@ -0,0 +1,64 @@
+package adaptercommon
+
+import (
+	"chat/globals"
+	"sort"
+)
+
+type ModerationProps struct {
+	RequestProps
+
+	Model string
+	Input []string
+}
+
+type ModerationResult struct {
+	Flagged        bool               `json:"flagged"`
+	Categories     map[string]bool    `json:"categories"`
+	CategoryScores map[string]float64 `json:"category_scores"`
+}
+
+type ModerationResponse struct {
+	Id      string             `json:"id"`
+	Model   string             `json:"model"`
+	Results []ModerationResult `json:"results"`
+}
+
+// ModerationHandler serves the moderations api, it is the Moderator capability of the provider
+type ModerationHandler func(conf globals.ChannelConfig, props *ModerationProps) (*ModerationResponse, error)
+
+// Moderator is implemented by the providers which support the moderations api
+type Moderator interface {
+	CreateModeration(conf globals.ChannelConfig, props *ModerationProps) (*ModerationResponse, error)
+}
+
+func (h ModerationHandler) CreateModeration(conf globals.ChannelConfig, props *ModerationProps) (*ModerationResponse, error) {
+	return h(conf, props)
+}
+
+// IsFlagged returns whether any of the inputs is flagged
+func (r *ModerationResponse) IsFlagged() bool {
+	for _, result := range r.Results {
+		if result.Flagged {
+			return true
+		}
+	}
+	return false
+}
+
+// GetCategories returns the sorted flagged categories of all the inputs
+func (r *ModerationResponse) GetCategories() []string {
+	categories := make([]string, 0)
+	seen := map[string]bool{}
+	for _, result := range r.Results {
+		for category, flagged := range result.Categories {
+			if flagged && !seen[category] {
+				seen[category] = true
+				categories = append(categories, category)
+			}
+		}
+	}
+
+	sort.Strings(categories)
+	return categories
+}
@@ -0,0 +1,143 @@
+package adaptercommon
+
+import (
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+	"strings"
+)
+
+type Handler func(conf globals.ChannelConfig, props *ChatProps, hook globals.Hook) error
+
+// Support returns whether the model of the provider supports the parameter, for the providers whose capability differs by model
+type Support func(model string, param string) bool
+
+// Acceptor returns whether the channel can serve the request, for the providers whose state is bound to the channel (e.g. midjourney tasks)
+type Acceptor func(conf globals.ChannelConfig, props *ChatProps) bool
+
+// Provider is the upstream adapter of a channel type, each package under `adapter/` registers itself as a provider,
+// the providers compose the capability handlers (e.g. EmbeddingHandler) onto the BaseProvider for the other apis
+type Provider interface {
+	GetType() string
+	GetParams() []string
+	SupportParam(model string, param string) bool
+	Accept(conf globals.ChannelConfig, props *ChatProps) bool
+	GetTokenLimit() TokenLimit
+	CreateStreamChatRequest(conf globals.ChannelConfig, props *ChatProps, hook globals.Hook) error
+}
+
+type BaseProvider struct {
+	Type     string
+	Params   []string
+	Limit    TokenLimit
+	Handler  Handler
+	Support  Support
+	Acceptor Acceptor
+}
+
+var providers = map[string]Provider{}
+
+func NewProvider(t string, params []string, limit TokenLimit, handler Handler) *BaseProvider {
+	return &BaseProvider{
+		Type:    t,
+		Params:  params,
+		Limit:   limit,
+		Handler: handler,
+	}
+}
+
+func (p *BaseProvider) GetType() string {
+	return p.Type
+}
+
+func (p *BaseProvider) GetParams() []string {
+	return p.Params
+}
+
+// WithSupport sets the model level parameter support of the provider
+func (p *BaseProvider) WithSupport(support Support) *BaseProvider {
+	p.Support = support
+	return p
+}
+
+func (p *BaseProvider) SupportParam(model string, param string) bool {
+	if !utils.Contains(param, p.Params) {
+		return false
+	}
+
+	return p.Support == nil || p.Support(model, param)
+}
+
+// WithAcceptor sets the channel level request acceptance of the provider
+func (p *BaseProvider) WithAcceptor(acceptor Acceptor) *BaseProvider {
+	p.Acceptor = acceptor
+	return p
+}
+
+func (p *BaseProvider) Accept(conf globals.ChannelConfig, props *ChatProps) bool {
+	return p.Acceptor == nil || p.Acceptor(conf, props)
+}
+
+func (p *BaseProvider) GetTokenLimit() TokenLimit {
+	return p.Limit
+}
+
+func (p *BaseProvider) CreateStreamChatRequest(conf globals.ChannelConfig, props *ChatProps, hook globals.Hook) error {
+	return p.Handler(conf, props, hook)
+}
+
+// Register registers the provider of the channel type, it should be called in the `init` function of the adapter package
+func Register(provider Provider) {
+	if _, ok := providers[provider.GetType()]; ok {
+		panic(fmt.Sprintf("provider of channel type %s is already registered", provider.GetType()))
+	}
+
+	providers[provider.GetType()] = provider
+}
+
+func GetProvider(t string) Provider {
+	return providers[t]
+}
+
+func GetProviders() map[string]Provider {
+	return providers
+}
+
+// GetUnsupportedParams returns the parameters of the request which the provider cannot honor for the model
+func GetUnsupportedParams(provider Provider, model string, props *ChatProps) []string {
+	return utils.Filter(props.GetParams(), func(param string) bool {
+		return !provider.SupportParam(model, param)
+	})
+}
+
+// CheckParams returns an error if the request contains parameters the provider cannot honor for the model
+func CheckParams(provider Provider, model string, props *ChatProps) error {
+	if params := GetUnsupportedParams(provider, model, props); len(params) > 0 {
+		return fmt.Errorf("parameters %s are not supported by model %s of channel type %s", strings.Join(params, ", "), model, provider.GetType())
+	}
+
+	return nil
+}
+
+type Lister func(conf globals.ChannelConfig) ([]string, error)
+
+// ModelLister is implemented by the providers which can list the models of the channel from the upstream
+type ModelLister interface {
+	ListModels(conf globals.ChannelConfig) ([]string, error)
+}
+
+type ListableProvider struct {
+	*BaseProvider
+	Lister Lister
+}
+
+func NewListableProvider(provider *BaseProvider, lister Lister) *ListableProvider {
+	return &ListableProvider{
+		BaseProvider: provider,
+		Lister:       lister,
+	}
+}
+
+func (p *ListableProvider) ListModels(conf globals.ChannelConfig) ([]string, error) {
+	return p.Lister(conf)
+}
@@ -0,0 +1,30 @@
+package adaptercommon
+
+import (
+	"chat/globals"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// streamOptionsRejected records the endpoints which reject `stream_options` (e.g. the older openai compatible upstreams)
+var streamOptionsRejected sync.Map
+
+// IsStreamOptionsRejected returns whether the endpoint rejects `stream_options`, the stream requests to it are sent without the field
+func IsStreamOptionsRejected(endpoint string) bool {
+	_, ok := streamOptionsRejected.Load(endpoint)
+	return ok
+}
+
+// WithStreamOptions sends the stream request which asks for the usage chunk, the request is sent again without `stream_options`
+// if the upstream rejects the field, so the rejection neither fails the request nor trips the breaker of the channel
+func WithStreamOptions(endpoint string, fn func() error) error {
+	err := fn()
+	if err == nil || IsStreamOptionsRejected(endpoint) || !strings.Contains(err.Error(), "stream_options") {
+		return err
+	}
+
+	streamOptionsRejected.Store(endpoint, true)
+	globals.Info(fmt.Sprintf("[adapter] endpoint %s rejects stream_options, the output of its streams is counted by tiktoken", endpoint))
+	return fn()
+}
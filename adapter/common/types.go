@@ -0,0 +1,142 @@
+package adaptercommon
+
+import (
+	"chat/globals"
+	"chat/utils"
+)
+
+type RequestProps struct {
+	MaxRetries *int
+	Current    int
+	Group      string
+}
+
+type ChatProps struct {
+	RequestProps
+
+	Model             string
+	Plan              bool
+	Infinity          bool
+	Message           []globals.Message
+	Token             int
+	PresencePenalty   *float32
+	FrequencyPenalty  *float32
+	RepetitionPenalty *float32
+	Temperature       *float32
+	TopP              *float32
+	TopK              *int
+	Tools             *globals.FunctionTools
+	ToolChoice        *interface{}
+	Stop              []string
+	Seed              *int
+	LogitBias         *map[string]float32
+	ResponseFormat    *interface{} // object of the openai response format (e.g. json mode)
+	User              *string      // end user of the request, dropped by the providers without it
+	Buffer            *utils.Buffer
+}
+
+const (
+	ParamTemperature       = "temperature"
+	ParamTopP              = "top_p"
+	ParamTopK              = "top_k"
+	ParamPresencePenalty   = "presence_penalty"
+	ParamFrequencyPenalty  = "frequency_penalty"
+	ParamRepetitionPenalty = "repetition_penalty"
+	ParamTools             = "tools"
+	ParamToolChoice        = "tool_choice"
+	ParamImages            = "images" // vision input, the providers without it receive the text view of the messages
+	ParamStop              = "stop"
+	ParamSeed              = "seed"
+	ParamLogitBias         = "logit_bias"
+	ParamResponseFormat    = "response_format"
+)
+
+// GetParams returns the optional parameters which are set in the chat props
+func (p *ChatProps) GetParams() []string {
+	params := make([]string, 0)
+
+	if p.Temperature != nil {
+		params = append(params, ParamTemperature)
+	}
+	if p.TopP != nil {
+		params = append(params, ParamTopP)
+	}
+	if p.TopK != nil {
+		params = append(params, ParamTopK)
+	}
+	if p.PresencePenalty != nil {
+		params = append(params, ParamPresencePenalty)
+	}
+	if p.FrequencyPenalty != nil {
+		params = append(params, ParamFrequencyPenalty)
+	}
+	if p.RepetitionPenalty != nil {
+		params = append(params, ParamRepetitionPenalty)
+	}
+	if p.Tools != nil && len(*p.Tools) > 0 {
+		params = append(params, ParamTools)
+	}
+	if p.ToolChoice != nil {
+		params = append(params, ParamToolChoice)
+	}
+	if len(p.Stop) > 0 {
+		params = append(params, ParamStop)
+	}
+	if p.Seed != nil {
+		params = append(params, ParamSeed)
+	}
+	if p.LogitBias != nil && len(*p.LogitBias) > 0 {
+		params = append(params, ParamLogitBias)
+	}
+	if p.ResponseFormat != nil {
+		params = append(params, ParamResponseFormat)
+	}
+	if p.HasInlineImages() {
+		// image urls can fall back to the text view, but base64 images cannot
+		params = append(params, ParamImages)
+	}
+
+	return params
+}
+
+// HasInlineImages returns whether the messages contain base64 images
+func (p *ChatProps) HasInlineImages() bool {
+	for _, message := range p.Message {
+		if message.Contents.HasInlineImages() {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenLimit is the max tokens strategy of a provider
+type TokenLimit struct {
+	Default  int  // max tokens if the request does not specify one, 0 means no limit is sent
+	Max      int  // max tokens the provider accepts, 0 means unbounded
+	Infinity bool // do not send the default limit for subscription plan or infinity requests
+}
+
+// GetToken returns the max tokens of the request, nil means no limit
+func (l TokenLimit) GetToken(props *ChatProps) *int {
+	token := props.Token
+	if token <= 0 {
+		if l.Infinity && (props.Plan || props.Infinity) {
+			return nil
+		}
+		token = l.Default
+	}
+
+	if l.Max > 0 && token > l.Max {
+		token = l.Max
+	}
+
+	if token <= 0 {
+		return nil
+	}
+	return &token
+}
+
+// GetTokenValue returns the max tokens of the request, 0 means no limit
+func (l TokenLimit) GetTokenValue(props *ChatProps) int {
+	return utils.GetPtrVal(l.GetToken(props), 0)
+}
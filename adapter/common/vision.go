@@ -0,0 +1,65 @@
+package adaptercommon
+
+import (
+	"chat/globals"
+	"chat/utils"
+)
+
+type ImageUrl struct {
+	Url    string  `json:"url"`
+	Detail *string `json:"detail,omitempty"`
+}
+
+type MessageContent struct {
+	Type     string    `json:"type"`
+	Text     *string   `json:"text,omitempty"`
+	ImageUrl *ImageUrl `json:"image_url,omitempty"`
+}
+
+// MessageContents is the content of the message in the openai vision format, shared by the openai compatible adapters
+type MessageContents []MessageContent
+
+// GetMessageContents converts the typed parts of the message to the openai vision format, the images are counted to the buffer
+func GetMessageContents(message globals.Message, buffer *utils.Buffer) MessageContents {
+	if message.Role != globals.User {
+		return MessageContents{
+			MessageContent{
+				Type: "text",
+				Text: &message.Content,
+			},
+		}
+	}
+
+	return utils.EachNotNil[globals.MessageContent, MessageContent](message.GetContents(), func(content globals.MessageContent) *MessageContent {
+		if !content.IsImage() {
+			text := content.GetText()
+			return &MessageContent{
+				Type: "text",
+				Text: &text,
+			}
+		}
+
+		if content.Type == globals.ImageUrlContentType {
+			// skip the remote image which cannot be fetched
+			obj, err := utils.NewImageFromContent(content)
+			if err != nil {
+				return nil
+			}
+			buffer.AddImage(obj)
+		} else if obj, err := utils.NewImageFromContent(content); err == nil {
+			buffer.AddImage(obj)
+		}
+
+		image := &ImageUrl{
+			Url: content.GetImageUrl(),
+		}
+		if content.ImageUrl != nil {
+			image.Detail = content.ImageUrl.Detail
+		}
+
+		return &MessageContent{
+			Type:     "image_url",
+			ImageUrl: image,
+		}
+	})
+}
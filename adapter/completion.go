@@ -0,0 +1,166 @@
+package adapter
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+type CompletionProps = adaptercommon.CompletionProps
+type CompletionResponse = adaptercommon.CompletionResponse
+type CompletionHook = adaptercommon.CompletionHook
+
+// getCompleter returns the completer of the channel, if the reflected model is served by the native completions api
+func getCompleter(conf globals.ChannelConfig, model string) (adaptercommon.Completer, bool) {
+	completer, ok := adaptercommon.GetProvider(conf.GetType()).(adaptercommon.Completer)
+	if !ok || !globals.IsCompletionModel(conf.GetModelReflect(model)) {
+		return nil, false
+	}
+	return completer, true
+}
+
+// getCompletionChatProps converts the completion request to the chat request, for the channels which only speak chat
+func getCompletionChatProps(props *CompletionProps) *ChatProps {
+	return &ChatProps{
+		RequestProps:     props.RequestProps,
+		Model:            props.Model,
+		Message:          props.GetChatMessages(),
+		Token:            utils.GetPtrVal(props.MaxTokens, 0),
+		PresencePenalty:  props.PresencePenalty,
+		FrequencyPenalty: props.FrequencyPenalty,
+		Temperature:      props.Temperature,
+		TopP:             props.TopP,
+		Buffer:           props.Buffer,
+	}
+}
+
+// IsCompletionSupported returns whether the channel can honor the completion request natively or by the chat conversion
+func IsCompletionSupported(conf globals.ChannelConfig, props *CompletionProps) bool {
+	if _, ok := getCompleter(conf, props.Model); ok {
+		return true
+	}
+
+	return len(props.GetNativeParams()) == 0 && IsChatSupported(conf, getCompletionChatProps(props))
+}
+
+func NewCompletionRequest(conf globals.ChannelConfig, props *CompletionProps, hook CompletionHook) error {
+	if completer, ok := getCompleter(conf, props.Model); ok {
+		return createRetryCompletionRequest(completer, conf, props, hook)
+	}
+
+	if params := props.GetNativeParams(); len(params) > 0 {
+		return conf.ProcessError(fmt.Errorf("parameters %s are not supported by the chat model %s of channel type %s", strings.Join(params, ", "), props.Model, conf.GetType()))
+	}
+
+	return createChatCompletionRequest(conf, props, hook)
+}
+
+func createCompletionRequest(completer adaptercommon.Completer, conf globals.ChannelConfig, props *CompletionProps, hook CompletionHook) error {
+	// copy the props to avoid the reflection model leaking to the other channels
+	instance := *props
+	instance.Model = conf.GetModelReflect(props.Model)
+
+	return completer.CreateCompletion(conf, &instance, hook)
+}
+
+func createRetryCompletionRequest(completer adaptercommon.Completer, conf globals.ChannelConfig, props *CompletionProps, hook CompletionHook) error {
+	err := createCompletionRequest(completer, conf, props, hook)
+
+	retries := conf.GetRetry()
+	props.Current++
+
+	if IsAvailableError(err) && props.Current < retries {
+		content := strings.Replace(err.Error(), "\n", "", -1)
+		globals.Warn(fmt.Sprintf("retrying completion request for %s (attempt %d/%d, error: %s)", props.Model, props.Current+1, retries, content))
+		return createRetryCompletionRequest(completer, conf, props, hook)
+	}
+
+	return conf.ProcessError(err)
+}
+
+// completionWriter converts the chat stream to the completion chunks, the echo and the stop sequences are emulated
+type completionWriter struct {
+	props   *CompletionProps
+	hook    CompletionHook
+	data    string
+	cursor  int // bytes of the data which have been sent
+	holding int // bytes held back since they may be the beginning of a stop sequence
+	stopped bool
+}
+
+func newCompletionWriter(props *CompletionProps, hook CompletionHook) *completionWriter {
+	holding := 0
+	for _, stop := range props.Stop {
+		if len(stop) > holding+1 {
+			holding = len(stop) - 1
+		}
+	}
+
+	return &completionWriter{props: props, hook: hook, holding: holding}
+}
+
+func (w *completionWriter) send(text string, reason interface{}) error {
+	return w.hook(&CompletionResponse{
+		Choices: []adaptercommon.CompletionChoice{
+			{Text: text, FinishReason: reason},
+		},
+	})
+}
+
+// Write is the chat hook, it returns the signal to stop the chat request when a stop sequence is generated
+func (w *completionWriter) Write(data string) error {
+	if data == "" {
+		// heartbeat
+		return nil
+	}
+
+	w.data += data
+	for _, stop := range w.props.Stop {
+		if stop == "" {
+			continue
+		}
+
+		if index := strings.Index(w.data[w.cursor:], stop); index >= 0 {
+			w.data = w.data[:w.cursor+index]
+			w.stopped = true
+			return fmt.Errorf("signal")
+		}
+	}
+
+	end := len(w.data) - w.holding
+	for end > w.cursor && end < len(w.data) && !utf8.RuneStart(w.data[end]) {
+		end--
+	}
+	if end <= w.cursor {
+		return nil
+	}
+
+	text := w.data[w.cursor:end]
+	w.cursor = end
+	return w.send(text, nil)
+}
+
+// Close sends the rest of the data with the finish reason
+func (w *completionWriter) Close() error {
+	return w.send(w.data[w.cursor:], "stop")
+}
+
+func createChatCompletionRequest(conf globals.ChannelConfig, props *CompletionProps, hook CompletionHook) error {
+	writer := newCompletionWriter(props, hook)
+	if props.Echo {
+		if err := writer.send(props.Prompt, nil); err != nil {
+			return err
+		}
+	}
+
+	if err := NewChatRequest(conf, getCompletionChatProps(props), writer.Write); err != nil {
+		if err.Error() != "signal" || !writer.stopped {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
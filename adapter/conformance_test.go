@@ -0,0 +1,513 @@
+package adapter_test
+
+import (
+	"chat/adapter"
+	"chat/globals"
+	"chat/utils"
+	"github.com/spf13/viper"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+// channelConfig is the channel pointing to the fake upstream, `{endpoint}` in the endpoint and
+// the secret is replaced by the url of the fake upstream
+type channelConfig struct {
+	Type     string
+	Endpoint string
+	Secret   string
+}
+
+func (c *channelConfig) GetType() string                     { return c.Type }
+func (c *channelConfig) GetModelReflect(model string) string { return model }
+func (c *channelConfig) GetRetry() int                       { return 1 }
+func (c *channelConfig) GetRandomSecret() string             { return c.Secret }
+func (c *channelConfig) GetEndpoint() string                 { return c.Endpoint }
+func (c *channelConfig) ProcessError(err error) error        { return err }
+
+func (c *channelConfig) SplitRandomSecret(num int) []string {
+	arr := strings.Split(c.Secret, "|")
+	for len(arr) < num {
+		arr = append(arr, "")
+	}
+	return arr[:num]
+}
+
+type conformanceCase struct {
+	name     string
+	channel  string
+	model    string
+	endpoint string // `{endpoint}` if empty
+	secret   string
+	tools    bool
+	upstream upstream
+
+	text   string                     // text sent through the hook, heartbeats are ignored
+	calls  []globals.ToolCallFunction // tool calls recorded on the buffer
+	input  int                        // usage reported by the upstream
+	output int
+	err    string // substring of the error, empty if the request should succeed
+}
+
+var weatherTools = globals.FunctionTools{
+	{
+		Type: "function",
+		Function: globals.ToolFunction{
+			Name:        "get_weather",
+			Description: "Get the current weather of the city",
+			Parameters: globals.ToolParameters{
+				Type: "object",
+				Properties: globals.ToolProperties{
+					"city": {Type: "string", Description: "name of the city"},
+				},
+				Required: []string{"city"},
+			},
+		},
+	},
+}
+
+var weatherCall = []globals.ToolCallFunction{
+	{Name: "get_weather", Arguments: `{"city":"Paris"}`},
+}
+
+var conformanceCases = []conformanceCase{
+	// openai sse
+	{
+		name: "openai/text", channel: globals.OpenAIChannelType, model: globals.GPT3Turbo, secret: "sk-test",
+		upstream: sse("/v1/chat/completions", "openai/text.txt"),
+		text:     "Hello, world!",
+	},
+	{
+		name: "openai/usage", channel: globals.OpenAIChannelType, model: globals.GPT3Turbo, secret: "sk-test",
+		upstream: sse("/v1/chat/completions", "openai/usage.txt"),
+		text:     "Hello, world!", input: 9, output: 4,
+	},
+	{
+		name: "openai/stream_options_rejected", channel: globals.OpenAIChannelType, model: globals.GPT3Turbo, secret: "sk-test",
+		upstream: legacy("/v1/chat/completions", "openai/text.txt"),
+		text:     "Hello, world!",
+	},
+	{
+		name: "openai/tool_calls", channel: globals.OpenAIChannelType, model: globals.GPT3Turbo, secret: "sk-test", tools: true,
+		upstream: sse("/v1/chat/completions", "openai/tool_calls.txt"),
+		calls:    weatherCall,
+	},
+	{
+		name: "openai/stream_error", channel: globals.OpenAIChannelType, model: globals.GPT3Turbo, secret: "sk-test",
+		upstream: sse("/v1/chat/completions", "openai/stream_error.txt"),
+		err:      "chatgpt error: That model is currently overloaded with other requests. (type: server_error)",
+	},
+	{
+		name: "openai/unauthorized", channel: globals.OpenAIChannelType, model: globals.GPT3Turbo, secret: "sk-test",
+		upstream: reply("/v1/chat/completions", http.StatusUnauthorized, "openai/unauthorized.json"),
+		err:      "request failed with status: 401 Unauthorized",
+	},
+	{
+		name: "openai/empty", channel: globals.OpenAIChannelType, model: globals.GPT3Turbo, secret: "sk-test",
+		upstream: sse("/v1/chat/completions", "openai/empty.txt"),
+		err:      "empty response",
+	},
+	{
+		name: "azure/text", channel: globals.AzureOpenAIChannelType, model: globals.GPT3Turbo,
+		endpoint: "2023-12-01-preview", secret: "sk-test|{endpoint}",
+		upstream: sse("/openai/deployments/gpt-35-turbo/chat/completions", "openai/text.txt"),
+		text:     "Hello, world!",
+	},
+	{
+		name: "azure/usage", channel: globals.AzureOpenAIChannelType, model: globals.GPT3Turbo,
+		endpoint: "2024-06-01", secret: "sk-test|{endpoint}",
+		upstream: sse("/openai/deployments/gpt-35-turbo/chat/completions", "openai/usage.txt"),
+		text:     "Hello, world!", input: 9, output: 4,
+	},
+	{
+		name: "azure/tool_calls", channel: globals.AzureOpenAIChannelType, model: globals.GPT3Turbo, tools: true,
+		endpoint: "2023-12-01-preview", secret: "sk-test|{endpoint}",
+		upstream: sse("/openai/deployments/gpt-35-turbo/chat/completions", "openai/tool_calls.txt"),
+		calls:    weatherCall,
+	},
+	{
+		name: "oneapi/text", channel: globals.OneAPIChannelType, model: globals.GPT3Turbo, secret: "sk-test",
+		upstream: sse("/v1/chat/completions", "openai/text.txt"),
+		text:     "Hello, world!",
+	},
+
+	// gemini
+	{
+		name: "gemini/text", channel: globals.PalmChannelType, model: globals.GeminiPro, secret: "test-key",
+		upstream: sse("/v1beta/models/gemini-pro:streamGenerateContent", "gemini/text.txt"),
+		text:     "Hello, world!", input: 4, output: 4,
+	},
+	{
+		name: "gemini/function_call", channel: globals.PalmChannelType, model: globals.GeminiPro, secret: "test-key", tools: true,
+		upstream: sse("/v1beta/models/gemini-pro:streamGenerateContent", "gemini/function_call.txt"),
+		calls:    weatherCall, input: 32, output: 6,
+	},
+	{
+		name: "gemini/safety", channel: globals.PalmChannelType, model: globals.GeminiPro, secret: "test-key",
+		upstream: sse("/v1beta/models/gemini-pro:streamGenerateContent", "gemini/safety.txt"),
+		text:     "I",
+		err:      "gemini error: the response was blocked by safety filters",
+	},
+	{
+		name: "gemini/invalid_key", channel: globals.PalmChannelType, model: globals.GeminiPro, secret: "test-key",
+		upstream: reply("/v1beta/models/gemini-pro:streamGenerateContent", http.StatusBadRequest, "gemini/invalid_key.json"),
+		err:      "API key not valid",
+	},
+
+	// dashscope
+	{
+		name: "dashscope/text", channel: globals.QwenChannelType, model: globals.QwenTurbo, secret: "sk-test",
+		upstream: sse("/api/v1/services/aigc/text-generation/generation", "dashscope/text.txt"),
+		text:     "Hello, world!", input: 8, output: 4,
+	},
+	{
+		name: "dashscope/tool_calls", channel: globals.QwenChannelType, model: globals.QwenTurbo, secret: "sk-test", tools: true,
+		upstream: sse("/api/v1/services/aigc/text-generation/generation", "dashscope/tool_calls.txt"),
+		calls:    []globals.ToolCallFunction{{Name: "get_weather", Arguments: `{"city": "Paris"}`}},
+		input:    170, output: 15,
+	},
+	{
+		name: "dashscope/error", channel: globals.QwenChannelType, model: globals.QwenTurbo, secret: "sk-test",
+		upstream: sse("/api/v1/services/aigc/text-generation/generation", "dashscope/error.txt"),
+		err:      "dashscope error: Input data may contain inappropriate content.",
+	},
+
+	// zhipuai
+	{
+		name: "zhipuai/v3_text", channel: globals.ChatGLMChannelType, model: globals.ZhiPuChatGLMTurbo, secret: "id.secret",
+		upstream: sse("/api/paas/v3/model-api/chatglm_turbo/sse-invoke", "zhipuai/v3_text.txt"),
+		text:     "Hello, world!",
+	},
+	{
+		name: "zhipuai/v4_text", channel: globals.ChatGLMChannelType, model: globals.GLM4, secret: "id.secret",
+		upstream: sse("/api/paas/v4/chat/completions", "zhipuai/v4_text.txt"),
+		text:     "Hello, world!", input: 6, output: 4,
+	},
+	{
+		name: "zhipuai/v4_tool_calls", channel: globals.ChatGLMChannelType, model: globals.GLM4, secret: "id.secret", tools: true,
+		upstream: sse("/api/paas/v4/chat/completions", "zhipuai/v4_tool_calls.txt"),
+		calls:    weatherCall, input: 120, output: 12,
+	},
+	{
+		name: "zhipuai/v4_error", channel: globals.ChatGLMChannelType, model: globals.GLM4, secret: "id.secret",
+		upstream: reply("/api/paas/v4/chat/completions", http.StatusOK, "zhipuai/v4_error.json"),
+		err:      "zhipuai error: Your account is in arrears, please recharge and try again. (code: 1113)",
+	},
+
+	// baichuan
+	{
+		name: "baichuan/text", channel: globals.BaichuanChannelType, model: globals.Baichuan53B, secret: "sk-test",
+		upstream: sse("/v1/chat/completions", "baichuan/text.txt"),
+		text:     "Hello, world!", input: 4, output: 4,
+	},
+	{
+		name: "baichuan/stream_error", channel: globals.BaichuanChannelType, model: globals.Baichuan53B, secret: "sk-test",
+		upstream: sse("/v1/chat/completions", "baichuan/stream_error.txt"),
+		err:      "baichuan error: You exceeded your current quota, please check your plan and billing details. (type: insufficient_quota)",
+	},
+	{
+		name: "baichuan/empty", channel: globals.BaichuanChannelType, model: globals.Baichuan53B, secret: "sk-test",
+		upstream: sse("/v1/chat/completions", "baichuan/empty.txt"),
+		err:      "empty response",
+	},
+
+	// skylark
+	{
+		name: "skylark/text", channel: globals.SkylarkChannelType, model: globals.SkylarkLite, secret: "ak|sk",
+		upstream: sse("/api/v1/chat", "skylark/text.txt"),
+		text:     "Hello, world!", input: 4, output: 4,
+	},
+	{
+		name: "skylark/function_call", channel: globals.SkylarkChannelType, model: globals.SkylarkLite, secret: "ak|sk", tools: true,
+		upstream: sse("/api/v1/chat", "skylark/function_call.txt"),
+		calls:    weatherCall,
+	},
+	{
+		name: "skylark/error", channel: globals.SkylarkChannelType, model: globals.SkylarkLite, secret: "ak|sk",
+		upstream: reply("/api/v1/chat", http.StatusBadRequest, "skylark/error.json"),
+		err:      "the model skylark-lite-public is not found",
+	},
+
+	// hunyuan
+	{
+		name: "hunyuan/text", channel: globals.HunyuanChannelType, model: globals.Hunyuan, secret: "1250000000|secret-id|secret-key",
+		upstream: sse("/hyllm/v1/chat/completions", "hunyuan/text.txt"),
+		text:     "Hello, world!", input: 4, output: 4,
+	},
+	{
+		name: "hunyuan/error", channel: globals.HunyuanChannelType, model: globals.Hunyuan, secret: "1250000000|secret-id|secret-key",
+		upstream: sse("/hyllm/v1/chat/completions", "hunyuan/error.txt"),
+		err:      "tencent hunyuan error: 鉴权失败 (code: 2004)",
+	},
+
+	// sparkdesk websocket
+	{
+		name: "sparkdesk/text", channel: globals.SparkdeskChannelType, model: globals.SparkDeskV3, secret: "app-id|api-secret|api-key",
+		upstream: ws("/v3.1/chat", "sparkdesk/text.jsonl"),
+		text:     "Hello, world!", input: 4, output: 4,
+	},
+	{
+		name: "sparkdesk/function_call", channel: globals.SparkdeskChannelType, model: globals.SparkDeskV3, secret: "app-id|api-secret|api-key", tools: true,
+		upstream: ws("/v3.1/chat", "sparkdesk/function_call.jsonl"),
+		calls:    weatherCall, input: 30, output: 8,
+	},
+	{
+		name: "sparkdesk/error", channel: globals.SparkdeskChannelType, model: globals.SparkDeskV3, secret: "app-id|api-secret|api-key",
+		upstream: ws("/v3.1/chat", "sparkdesk/error.jsonl"),
+		err:      "sparkdesk error: input content audit failed (sid: cht000cb089@dx18b7f5f6e7b6a6b534)",
+	},
+
+	// bing websocket
+	{
+		name: "bing/text", channel: globals.BingChannelType, model: globals.BingCreative, secret: "hash",
+		upstream: ws("/chat", "bing/text.jsonl"),
+		text:     "Hello, world!",
+	},
+
+	// ollama ndjson
+	{
+		name: "ollama/text", channel: globals.OllamaChannelType, model: "llama2",
+		upstream: ndjson("/api/chat", "ollama/text.jsonl"),
+		text:     "Hello, world!", input: 26, output: 4,
+	},
+	{
+		name: "ollama/error", channel: globals.OllamaChannelType, model: "llama2",
+		upstream: ndjson("/api/chat", "ollama/error.jsonl"),
+		err:      "ollama error: model 'llama2' not found, try pulling it first",
+	},
+
+	// llama.cpp server sse
+	{
+		name: "llamacpp/text", channel: globals.LlamaCppChannelType, model: "llama-2-7b-chat",
+		upstream: sse("/completion", "llamacpp/text.txt"),
+		text:     "Hello, world!", input: 12, output: 4,
+	},
+
+	// midjourney proxy with the notify hook
+	{
+		name: "midjourney/imagine", channel: globals.MidjourneyChannelType, model: globals.Midjourney, secret: "mj-secret|",
+		upstream: midjourneyProxy("midjourney/submit.json", "midjourney/notify.jsonl"),
+		text:     "```progress\n50\n100\n```\n![image](https://cdn.discordapp.com/attachments/1/2/cat.png)\n\n> task: `1712205491372528` · actions: U1-U4 · V1-V4 · /reroll",
+	},
+	{
+		name: "midjourney/failure", channel: globals.MidjourneyChannelType, model: globals.Midjourney, secret: "mj-secret|",
+		upstream: midjourneyProxy("midjourney/submit.json", "midjourney/failure.jsonl"),
+		text:     "```progress\n100\n```\n",
+		err:      "error from midjourney: task failed: Banned prompt detected",
+	},
+	{
+		name: "midjourney/queue_full", channel: globals.MidjourneyChannelType, model: globals.Midjourney, secret: "mj-secret|",
+		upstream: midjourneyProxy("midjourney/queue_full.json", ""),
+		text:     "```progress\n```\n",
+		err:      "error from midjourney: task queue is full, please try again later",
+	},
+}
+
+func TestMain(m *testing.M) {
+	// keep the adapter logs out of the console and the working directory
+	viper.Set("log.ignore_console", true)
+	globals.Logger.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+func TestChatConformance(t *testing.T) {
+	for _, tc := range conformanceCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			url := tc.upstream(t)
+			conf := &channelConfig{
+				Type:     tc.channel,
+				Endpoint: strings.ReplaceAll(utils.Multi(tc.endpoint == "", "{endpoint}", tc.endpoint), "{endpoint}", url),
+				Secret:   strings.ReplaceAll(tc.secret, "{endpoint}", url),
+			}
+
+			props := &adapter.ChatProps{
+				Model:   tc.model,
+				Message: []globals.Message{{Role: globals.User, Content: "Hello"}},
+				Buffer:  &utils.Buffer{Model: tc.model},
+			}
+			if tc.tools {
+				props.Tools = &weatherTools
+			}
+
+			var text strings.Builder
+			err := adapter.NewChatRequest(conf, props, func(data string) error {
+				text.WriteString(data)
+				return nil
+			})
+
+			if tc.err == "" && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			} else if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+				t.Fatalf("error = %v, want %q", err, tc.err)
+			}
+
+			if text.String() != tc.text {
+				t.Errorf("text = %q, want %q", text.String(), tc.text)
+			}
+
+			var calls []globals.ToolCallFunction
+			if buffer := props.Buffer.GetToolCalls(); buffer != nil {
+				calls = utils.Each(*buffer, func(call globals.ToolCall) globals.ToolCallFunction {
+					return call.Function
+				})
+			}
+			if utils.Marshal(calls) != utils.Marshal(tc.calls) {
+				t.Errorf("tool calls = %s, want %s", utils.Marshal(calls), utils.Marshal(tc.calls))
+			}
+
+			if props.Buffer.InputTokens != tc.input || props.Buffer.OutputTokens != tc.output {
+				t.Errorf("usage = %d/%d, want %d/%d", props.Buffer.InputTokens, props.Buffer.OutputTokens, tc.input, tc.output)
+			}
+		})
+	}
+}
+
+type paramsCase struct {
+	name    string
+	channel string
+	model   string
+	secret  string
+	path    string
+	fixture string
+	props   adapter.ChatProps
+
+	body string // fields expected in the request body
+	err  string // substring of the error, empty if the request should be sent
+}
+
+var paramsCases = []paramsCase{
+	{
+		name: "openai", channel: globals.OpenAIChannelType, model: globals.GPT3Turbo, secret: "sk-test",
+		path: "/v1/chat/completions", fixture: "openai/text.txt",
+		props: adapter.ChatProps{
+			Stop:           []string{"\n\n"},
+			Seed:           utils.ToPtr(42),
+			LogitBias:      &map[string]float32{"50256": -100},
+			ResponseFormat: utils.ToPtr[interface{}](map[string]string{"type": "json_object"}),
+			User:           utils.ToPtr("user-1"),
+		},
+		body: `{"logit_bias":{"50256":-100},"response_format":{"type":"json_object"},"seed":42,"stop":["\n\n"],"user":"user-1"}`,
+	},
+	{
+		name: "gemini", channel: globals.PalmChannelType, model: globals.GeminiPro, secret: "test-key",
+		path: "/v1beta/models/gemini-pro:streamGenerateContent", fixture: "gemini/text.txt",
+		props: adapter.ChatProps{Stop: []string{"END"}},
+		body:  `{"generationConfig":{"stopSequences":["END"]}}`,
+	},
+	{
+		name: "gemini/seed", channel: globals.PalmChannelType, model: globals.GeminiPro, secret: "test-key",
+		path: "/v1beta/models/gemini-pro:streamGenerateContent", fixture: "gemini/text.txt",
+		props: adapter.ChatProps{Seed: utils.ToPtr(42)},
+		err:   "parameters seed are not supported",
+	},
+	{
+		name: "ollama", channel: globals.OllamaChannelType, model: "llama2",
+		path: "/api/chat", fixture: "ollama/text.jsonl",
+		props: adapter.ChatProps{Stop: []string{"END"}, Seed: utils.ToPtr(42)},
+		body:  `{"model":"llama2","options":{"seed":42,"stop":["END"]},"stream":true}`,
+	},
+}
+
+// pick returns the fields of the body which are present in the expected json, nested objects are picked recursively
+func pick(body interface{}, want interface{}) interface{} {
+	expected, ok := want.(map[string]interface{})
+	actual, is := body.(map[string]interface{})
+	if !ok || !is {
+		return body
+	}
+
+	result := map[string]interface{}{}
+	for key, value := range expected {
+		if field, exist := actual[key]; exist {
+			result[key] = pick(field, value)
+		}
+	}
+	return result
+}
+
+func TestChatParamsConformance(t *testing.T) {
+	for _, tc := range paramsCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var body []byte
+			conf := &channelConfig{
+				Type:     tc.channel,
+				Endpoint: capture(tc.path, tc.fixture, &body)(t),
+				Secret:   tc.secret,
+			}
+
+			props := tc.props
+			props.Model = tc.model
+			props.Message = []globals.Message{{Role: globals.User, Content: "Hello"}}
+			props.Buffer = &utils.Buffer{Model: tc.model}
+
+			err := adapter.NewChatRequest(conf, &props, func(data string) error { return nil })
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("error = %v, want %q", err, tc.err)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			want := utils.UnmarshalJson[interface{}](tc.body)
+			if got := utils.Marshal(pick(utils.UnmarshalJson[interface{}](string(body)), want)); got != utils.Marshal(want) {
+				t.Errorf("body = %s, want %s", got, utils.Marshal(want))
+			}
+		})
+	}
+}
+
+type modelsCase struct {
+	name     string
+	channel  string
+	upstream upstream
+
+	models []string // models listed from the upstream, duplicates are dropped
+	err    string   // substring of the error, empty if the models should be listed
+}
+
+var modelsCases = []modelsCase{
+	{
+		name: "ollama/tags", channel: globals.OllamaChannelType,
+		upstream: reply("/api/tags", http.StatusOK, "ollama/tags.json"),
+		models:   []string{"llama2:latest", "llava:7b"},
+	},
+	{
+		name: "llamacpp/models", channel: globals.LlamaCppChannelType,
+		upstream: reply("/v1/models", http.StatusOK, "llamacpp/models.json"),
+		models:   []string{"llama-2-7b-chat.Q4_K_M.gguf"},
+	},
+	{
+		name: "openai/unlisted", channel: globals.OpenAIChannelType,
+		upstream: reply("/v1/models", http.StatusOK, "llamacpp/models.json"),
+		err:      "channel type openai does not support listing models",
+	},
+}
+
+func TestListModelsConformance(t *testing.T) {
+	for _, tc := range modelsCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			conf := &channelConfig{Type: tc.channel, Endpoint: tc.upstream(t)}
+
+			models, err := adapter.ListModels(conf)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("error = %v, want %q", err, tc.err)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if utils.Marshal(models) != utils.Marshal(tc.models) {
+				t.Errorf("models = %s, want %s", utils.Marshal(models), utils.Marshal(tc.models))
+			}
+		})
+	}
+}
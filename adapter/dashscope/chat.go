@@ -15,6 +15,9 @@ type ChatProps struct {
 	TopK              *int
 	RepetitionPenalty *float32
 	Message           []globals.Message
+	Tools             *globals.FunctionTools
+	ToolChoice        *interface{}
+	Buffer            *utils.Buffer
 }
 
 func (c *ChatInstance) GetHeader() map[string]string {
@@ -25,50 +28,125 @@ func (c *ChatInstance) GetHeader() map[string]string {
 	}
 }
 
-func (c *ChatInstance) FormatMessages(message []globals.Message) []Message {
+// getToolName returns the function name of the tool call id, since dashscope identifies the tool message by name
+func getToolName(message []globals.Message, id *string) *string {
+	if id == nil {
+		return nil
+	}
+
+	for _, item := range message {
+		if item.ToolCalls == nil {
+			continue
+		}
+
+		for _, call := range *item.ToolCalls {
+			if string(call.Id) == *id {
+				return utils.ToPtr(call.Function.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *ChatInstance) FormatMessages(message []globals.Message, tools bool) []Message {
 	var messages []Message
 	for _, v := range message {
 		if v.Role == globals.Tool {
+			if !tools {
+				continue
+			}
+
+			messages = append(messages, Message{
+				Role:    v.Role,
+				Content: v.Content,
+				Name:    getToolName(message, v.ToolCallId),
+			})
 			continue
 		}
 
 		messages = append(messages, Message{
-			Role:    v.Role,
-			Content: v.Content,
+			Role:      v.Role,
+			Content:   v.Content,
+			ToolCalls: utils.Multi(tools, v.ToolCalls, nil),
 		})
 	}
 
 	return messages
 }
 
+func isToolCalling(props *ChatProps) bool {
+	return props.Tools != nil && len(*props.Tools) > 0
+}
+
 func (c *ChatInstance) GetChatBody(props *ChatProps) ChatRequest {
 	if props.Token <= 0 || props.Token > 1500 {
 		props.Token = 1500
 	}
 
+	tools := isToolCalling(props)
+	param := ChatParam{
+		MaxTokens:         props.Token,
+		Temperature:       props.Temperature,
+		TopP:              props.TopP,
+		TopK:              props.TopK,
+		RepetitionPenalty: props.RepetitionPenalty,
+		EnableSearch:      utils.ToPtr(strings.HasSuffix(props.Model, "-net")),
+		IncrementalOutput: true,
+	}
+
+	if tools {
+		param.ResultFormat = "message"
+		param.Tools = props.Tools
+		param.ToolChoice = props.ToolChoice
+	}
+
 	return ChatRequest{
 		Model: strings.TrimSuffix(props.Model, "-net"),
 		Input: ChatInput{
-			Messages: c.FormatMessages(props.Message),
-		},
-		Parameters: ChatParam{
-			MaxTokens:         props.Token,
-			Temperature:       props.Temperature,
-			TopP:              props.TopP,
-			TopK:              props.TopK,
-			RepetitionPenalty: props.RepetitionPenalty,
-			EnableSearch:      utils.ToPtr(strings.HasSuffix(props.Model, "-net")),
-			IncrementalOutput: true,
+			Messages: c.FormatMessages(props.Message, tools),
 		},
+		Parameters: param,
 	}
 }
 
+// mergeToolCalls merges the incremental tool calls of the stream response
+func mergeToolCalls(calls globals.ToolCalls, delta *globals.ToolCalls) globals.ToolCalls {
+	if delta == nil {
+		return calls
+	}
+
+	for _, call := range *delta {
+		if len(calls) == 0 || (len(call.Id) > 0 && call.Id != calls[len(calls)-1].Id) {
+			calls = append(calls, call)
+			continue
+		}
+
+		last := &calls[len(calls)-1]
+		last.Function.Name += call.Function.Name
+		last.Function.Arguments += call.Function.Arguments
+	}
+	return calls
+}
+
+// getChoice returns the incremental text of the response, the result format differs when tools are used
+func getChoice(form *ChatResponse, calls *globals.ToolCalls) string {
+	if len(form.Output.Choices) == 0 {
+		return form.Output.Text
+	}
+
+	message := form.Output.Choices[0].Message
+	*calls = mergeToolCalls(*calls, message.ToolCalls)
+	return message.Content
+}
+
 func (c *ChatInstance) GetChatEndpoint() string {
 	return fmt.Sprintf("%s/api/v1/services/aigc/text-generation/generation", c.Endpoint)
 }
 
 func (c *ChatInstance) CreateStreamChatRequest(props *ChatProps, callback globals.Hook) error {
-	return utils.EventSource(
+	calls := make(globals.ToolCalls, 0)
+
+	err := utils.EventSource(
 		"POST",
 		c.GetChatEndpoint(),
 		c.GetHeader(),
@@ -81,11 +159,15 @@ func (c *ChatInstance) CreateStreamChatRequest(props *ChatProps, callback global
 
 			slice := strings.TrimSpace(strings.TrimPrefix(data, "data:"))
 			if form := utils.UnmarshalForm[ChatResponse](slice); form != nil {
-				if form.Output.Text == "" && form.Message != "" {
+				text := getChoice(form, &calls)
+				if text == "" && len(calls) == 0 && form.Message != "" {
 					return fmt.Errorf("dashscope error: %s", form.Message)
 				}
 
-				if err := callback(form.Output.Text); err != nil {
+				props.Buffer.SetInputTokens(form.Usage.InputTokens)
+				props.Buffer.SetOutputTokens(form.Usage.OutputTokens)
+
+				if err := callback(text); err != nil {
 					return err
 				}
 				return nil
@@ -96,4 +178,13 @@ func (c *ChatInstance) CreateStreamChatRequest(props *ChatProps, callback global
 			return nil
 		},
 	)
+
+	if err != nil {
+		return err
+	}
+
+	if len(calls) > 0 {
+		props.Buffer.SetToolCalls(&calls)
+	}
+	return nil
 }
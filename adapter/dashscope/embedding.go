@@ -0,0 +1,93 @@
+package dashscope
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/utils"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// dashscope accepts at most 25 texts per embedding request
+const embeddingMaxBatch = 25
+
+func (c *ChatInstance) GetEmbeddingEndpoint() string {
+	return fmt.Sprintf("%s/api/v1/services/embeddings/text-embedding/text-embedding", c.Endpoint)
+}
+
+func (c *ChatInstance) GetEmbeddingHeader() map[string]string {
+	return map[string]string{
+		"Content-Type":  "application/json",
+		"Authorization": fmt.Sprintf("Bearer %s", c.GetApiKey()),
+	}
+}
+
+func (c *ChatInstance) GetEmbeddingBody(model string, texts []string) EmbeddingRequest {
+	body := EmbeddingRequest{Model: model}
+	body.Input.Texts = texts
+	body.Parameters.TextType = "document"
+	return body
+}
+
+// encodeEmbedding encodes the embedding as little-endian float32 base64, which is the openai `base64` encoding format
+func encodeEmbedding(embedding []float64) string {
+	buf := make([]byte, 4*len(embedding))
+	for i, value := range embedding {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(float32(value)))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+func (c *ChatInstance) createEmbeddingBatch(props *adaptercommon.EmbeddingProps, texts []string, offset int, result *adaptercommon.EmbeddingResponse) error {
+	res, err := utils.Post(c.GetEmbeddingEndpoint(), c.GetEmbeddingHeader(), c.GetEmbeddingBody(props.Model, texts))
+	if err != nil || res == nil {
+		return fmt.Errorf("dashscope error: %s", utils.GetError(err))
+	}
+
+	data := utils.MapToStruct[EmbeddingResponse](res)
+	if data == nil {
+		return fmt.Errorf("dashscope error: cannot parse response")
+	} else if data.Code != "" {
+		return fmt.Errorf("dashscope error: %s (code: %s)", data.Message, data.Code)
+	}
+
+	base := utils.GetPtrVal(props.EncodingFormat, "") == "base64"
+	for _, item := range data.Output.Embeddings {
+		result.Data = append(result.Data, adaptercommon.EmbeddingData{
+			Object:    "embedding",
+			Index:     offset + item.TextIndex,
+			Embedding: utils.Multi[interface{}](base, encodeEmbedding(item.Embedding), item.Embedding),
+		})
+	}
+
+	result.Usage.PromptTokens += data.Usage.TotalTokens
+	result.Usage.TotalTokens += data.Usage.TotalTokens
+	return nil
+}
+
+// CreateEmbeddingRequest is the request for dashscope text embedding, token inputs are decoded to texts
+// since dashscope only accepts texts
+func (c *ChatInstance) CreateEmbeddingRequest(props *adaptercommon.EmbeddingProps) (*adaptercommon.EmbeddingResponse, error) {
+	texts := props.Input.GetTexts(props.Model)
+	result := &adaptercommon.EmbeddingResponse{
+		Data:  make([]adaptercommon.EmbeddingData, 0, len(texts)),
+		Model: props.Model,
+	}
+
+	for offset := 0; offset < len(texts); offset += embeddingMaxBatch {
+		end := offset + embeddingMaxBatch
+		if end > len(texts) {
+			end = len(texts)
+		}
+
+		if err := c.createEmbeddingBatch(props, texts[offset:end], offset, result); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(result.Data) == 0 {
+		return nil, fmt.Errorf("dashscope error: empty embedding response")
+	}
+	return result, nil
+}
@@ -0,0 +1,55 @@
+package dashscope
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/globals"
+)
+
+// dashscope accepts at most 1500 output tokens
+var tokenLimit = adaptercommon.TokenLimit{Default: 1500, Max: 1500}
+
+// provider composes the embeddings api onto the chat provider
+type provider struct {
+	*adaptercommon.BaseProvider
+	adaptercommon.EmbeddingHandler
+}
+
+func init() {
+	base := adaptercommon.NewProvider(
+		globals.QwenChannelType,
+		[]string{
+			adaptercommon.ParamTemperature,
+			adaptercommon.ParamTopP,
+			adaptercommon.ParamTopK,
+			adaptercommon.ParamRepetitionPenalty,
+			adaptercommon.ParamTools,
+			adaptercommon.ParamToolChoice,
+		},
+		tokenLimit,
+		createChatRequest,
+	)
+
+	adaptercommon.Register(&provider{
+		BaseProvider:     base,
+		EmbeddingHandler: createEmbeddingRequest,
+	})
+}
+
+func createChatRequest(conf globals.ChannelConfig, props *adaptercommon.ChatProps, hook globals.Hook) error {
+	return NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&ChatProps{
+		Model:             props.Model,
+		Message:           props.Message,
+		Token:             tokenLimit.GetTokenValue(props),
+		Temperature:       props.Temperature,
+		TopP:              props.TopP,
+		TopK:              props.TopK,
+		RepetitionPenalty: props.RepetitionPenalty,
+		Tools:             props.Tools,
+		ToolChoice:        props.ToolChoice,
+		Buffer:            props.Buffer,
+	}, hook)
+}
+
+func createEmbeddingRequest(conf globals.ChannelConfig, props *adaptercommon.EmbeddingProps) (*adaptercommon.EmbeddingResponse, error) {
+	return NewChatInstanceFromConfig(conf).CreateEmbeddingRequest(props)
+}
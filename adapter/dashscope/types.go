@@ -1,5 +1,7 @@
 package dashscope
 
+import "chat/globals"
+
 // ChatRequest is the request body for dashscope
 type ChatRequest struct {
 	Model      string    `json:"model"`
@@ -8,8 +10,10 @@ type ChatRequest struct {
 }
 
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role      string             `json:"role"`
+	Content   string             `json:"content"`
+	Name      *string            `json:"name,omitempty"`       // only `tool` role
+	ToolCalls *globals.ToolCalls `json:"tool_calls,omitempty"` // only `assistant` role
 }
 
 type ChatInput struct {
@@ -24,6 +28,11 @@ type ChatParam struct {
 	TopP              *float32 `json:"top_p,omitempty"`
 	TopK              *int     `json:"top_k,omitempty"`
 	RepetitionPenalty *float32 `json:"repetition_penalty,omitempty"`
+
+	// tools are only supported with `message` result format
+	ResultFormat string                 `json:"result_format,omitempty"`
+	Tools        *globals.FunctionTools `json:"tools,omitempty"`
+	ToolChoice   *interface{}           `json:"tool_choice,omitempty"`
 }
 
 // ChatResponse is the response body for dashscope
@@ -31,6 +40,10 @@ type ChatResponse struct {
 	Output struct {
 		FinishReason string `json:"finish_reason"`
 		Text         string `json:"text"`
+		Choices      []struct {
+			FinishReason string  `json:"finish_reason"`
+			Message      Message `json:"message"`
+		} `json:"choices"` // only `message` result format
 	} `json:"output"`
 	RequestId string `json:"request_id"`
 	Usage     struct {
@@ -39,3 +52,30 @@ type ChatResponse struct {
 	} `json:"usage"`
 	Message string `json:"message"`
 }
+
+// EmbeddingRequest is the request body for dashscope text embedding
+type EmbeddingRequest struct {
+	Model string `json:"model"`
+	Input struct {
+		Texts []string `json:"texts"`
+	} `json:"input"`
+	Parameters struct {
+		TextType string `json:"text_type"`
+	} `json:"parameters"`
+}
+
+// EmbeddingResponse is the response body for dashscope text embedding
+type EmbeddingResponse struct {
+	Output struct {
+		Embeddings []struct {
+			TextIndex int       `json:"text_index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"embeddings"`
+	} `json:"output"`
+	RequestId string `json:"request_id"`
+	Usage     struct {
+		TotalTokens int `json:"total_tokens"`
+	} `json:"usage"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,49 @@
+package adapter
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/globals"
+	"fmt"
+	"strings"
+)
+
+type EmbeddingProps = adaptercommon.EmbeddingProps
+type EmbeddingResponse = adaptercommon.EmbeddingResponse
+
+// IsEmbeddingSupported returns whether the channel type supports the embeddings api
+func IsEmbeddingSupported(t string) bool {
+	_, ok := adaptercommon.GetProvider(t).(adaptercommon.Embedder)
+	return ok
+}
+
+func NewEmbeddingRequest(conf globals.ChannelConfig, props *EmbeddingProps) (*EmbeddingResponse, error) {
+	embedder, ok := adaptercommon.GetProvider(conf.GetType()).(adaptercommon.Embedder)
+	if !ok {
+		return nil, conf.ProcessError(fmt.Errorf("channel type %s does not support embeddings for model %s", conf.GetType(), props.Model))
+	}
+
+	return createRetryEmbeddingRequest(embedder, conf, props)
+}
+
+func createEmbeddingRequest(embedder adaptercommon.Embedder, conf globals.ChannelConfig, props *EmbeddingProps) (*EmbeddingResponse, error) {
+	// copy the props to avoid the reflection model leaking to the other channels
+	instance := *props
+	instance.Model = conf.GetModelReflect(props.Model)
+
+	return embedder.CreateEmbedding(conf, &instance)
+}
+
+func createRetryEmbeddingRequest(embedder adaptercommon.Embedder, conf globals.ChannelConfig, props *EmbeddingProps) (*EmbeddingResponse, error) {
+	resp, err := createEmbeddingRequest(embedder, conf, props)
+
+	retries := conf.GetRetry()
+	props.Current++
+
+	if IsAvailableError(err) && props.Current < retries {
+		content := strings.Replace(err.Error(), "\n", "", -1)
+		globals.Warn(fmt.Sprintf("retrying embedding request for %s (attempt %d/%d, error: %s)", props.Model, props.Current+1, retries, content))
+		return createRetryEmbeddingRequest(embedder, conf, props)
+	}
+
+	return resp, conf.ProcessError(err)
+}
@@ -2,6 +2,7 @@ package hunyuan
 
 import (
 	"chat/globals"
+	"chat/utils"
 	"context"
 	"fmt"
 )
@@ -11,6 +12,7 @@ type ChatProps struct {
 	Message     []globals.Message
 	Temperature *float32
 	TopP        *float32
+	Buffer      *utils.Buffer
 }
 
 func (c *ChatInstance) FormatMessages(messages []globals.Message) []globals.Message {
@@ -46,8 +48,15 @@ func (c *ChatInstance) CreateStreamChatRequest(props *ChatProps, callback global
 
 	for chunk := range channel {
 		if chunk.Error.Code != 0 {
-			fmt.Printf("tencent hunyuan error: %+v\n", chunk.Error)
-			break
+			return fmt.Errorf("tencent hunyuan error: %s (code: %d)", chunk.Error.Message, chunk.Error.Code)
+		}
+
+		// the usage of the chunk is accumulated
+		props.Buffer.SetInputTokens(int(chunk.Usage.PromptTokens))
+		props.Buffer.SetOutputTokens(int(chunk.Usage.CompletionTokens))
+
+		if len(chunk.Choices) == 0 {
+			continue
 		}
 
 		if err := callback(chunk.Choices[0].Delta.Content); err != nil {
@@ -0,0 +1,30 @@
+package hunyuan
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/globals"
+)
+
+func init() {
+	// the hunyuan hyllm api has no function calling, so tools are left undeclared
+	// and the ticker skips hunyuan channels for requests with tools
+	adaptercommon.Register(adaptercommon.NewProvider(
+		globals.HunyuanChannelType,
+		[]string{
+			adaptercommon.ParamTemperature,
+			adaptercommon.ParamTopP,
+		},
+		adaptercommon.TokenLimit{},
+		createChatRequest,
+	))
+}
+
+func createChatRequest(conf globals.ChannelConfig, props *adaptercommon.ChatProps, hook globals.Hook) error {
+	return NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&ChatProps{
+		Model:       props.Model,
+		Message:     props.Message,
+		Temperature: props.Temperature,
+		TopP:        props.TopP,
+		Buffer:      props.Buffer,
+	}, hook)
+}
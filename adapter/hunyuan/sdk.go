@@ -20,6 +20,7 @@ import (
 	"bufio"
 	"bytes"
 	"chat/globals"
+	"chat/utils"
 	"context"
 	"crypto/hmac"
 	"crypto/sha1"
@@ -143,8 +144,8 @@ func NewRequest(mod int, messages []globals.Message, temperature *float32, topP
 	return ChatRequest{
 		Timestamp:   int(time.Now().Unix()),
 		Expired:     int(time.Now().Unix()) + 24*60*60,
-		Temperature: 0,
-		TopP:        0.8,
+		Temperature: float64(utils.GetPtrVal(temperature, 0)),
+		TopP:        float64(utils.GetPtrVal(topP, 0.8)),
 		Messages:    messages,
 		QueryID:     queryID,
 		Stream:      mod,
@@ -246,7 +247,7 @@ func (t *Client) stream(httpResp *http.Response, res chan ChatResponse) {
 		}
 
 		res <- chatResponse
-		if chatResponse.Choices[0].FinishReason == "stop" {
+		if len(chatResponse.Choices) == 0 || chatResponse.Choices[0].FinishReason == "stop" {
 			return
 		}
 	}
@@ -0,0 +1,49 @@
+package adapter
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/globals"
+	"fmt"
+	"strings"
+)
+
+type ImageProps = adaptercommon.ImageProps
+type ImageResponse = adaptercommon.ImageResponse
+
+// IsImageSupported returns whether the channel type supports the images api
+func IsImageSupported(t string) bool {
+	_, ok := adaptercommon.GetProvider(t).(adaptercommon.ImageCreator)
+	return ok
+}
+
+func NewImageRequest(conf globals.ChannelConfig, props *ImageProps) (*ImageResponse, error) {
+	creator, ok := adaptercommon.GetProvider(conf.GetType()).(adaptercommon.ImageCreator)
+	if !ok {
+		return nil, conf.ProcessError(fmt.Errorf("channel type %s does not support images api for model %s", conf.GetType(), props.Model))
+	}
+
+	return createRetryImageRequest(creator, conf, props)
+}
+
+func createImageRequest(creator adaptercommon.ImageCreator, conf globals.ChannelConfig, props *ImageProps) (*ImageResponse, error) {
+	// copy the props to avoid the reflection model leaking to the other channels
+	instance := *props
+	instance.Model = conf.GetModelReflect(props.Model)
+
+	return creator.CreateImage(conf, &instance)
+}
+
+func createRetryImageRequest(creator adaptercommon.ImageCreator, conf globals.ChannelConfig, props *ImageProps) (*ImageResponse, error) {
+	resp, err := createImageRequest(creator, conf, props)
+
+	retries := conf.GetRetry()
+	props.Current++
+
+	if IsAvailableError(err) && props.Current < retries {
+		content := strings.Replace(err.Error(), "\n", "", -1)
+		globals.Warn(fmt.Sprintf("retrying image request for %s (attempt %d/%d, error: %s)", props.Model, props.Current+1, retries, content))
+		return createRetryImageRequest(creator, conf, props)
+	}
+
+	return resp, conf.ProcessError(err)
+}
@@ -27,17 +27,15 @@ func (c *ChatInstance) GetImagineHeaders() map[string]string {
 	}
 }
 
-func (c *ChatInstance) CreateImagineRequest(prompt string) (*ImagineResponse, error) {
+func (c *ChatInstance) GetNotifyHook() string {
+	return fmt.Sprintf("%s/mj/notify", globals.NotifyUrl)
+}
+
+func (c *ChatInstance) CreateSubmitRequest(path string, body interface{}) (*ImagineResponse, error) {
 	res, err := utils.Post(
-		c.GetImagineUrl(),
+		fmt.Sprintf("%s/mj/submit/%s", c.GetEndpoint(), path),
 		c.GetImagineHeaders(),
-		ImagineRequest{
-			NotifyHook: fmt.Sprintf(
-				"%s/mj/notify",
-				globals.NotifyUrl,
-			),
-			Prompt: prompt,
-		},
+		body,
 	)
 
 	if err != nil {
@@ -47,6 +45,125 @@ func (c *ChatInstance) CreateImagineRequest(prompt string) (*ImagineResponse, er
 	return utils.MapToStruct[ImagineResponse](res), nil
 }
 
+func (c *ChatInstance) CreateImagineRequest(prompt string) (*ImagineResponse, error) {
+	return c.CreateSubmitRequest("imagine", ImagineRequest{
+		NotifyHook: c.GetNotifyHook(),
+		Prompt:     prompt,
+	})
+}
+
+// CreateChangeRequest submits the upscale, variation or reroll action of the grid image
+func (c *ChatInstance) CreateChangeRequest(task string, action string, index int) (*ImagineResponse, error) {
+	return c.CreateSubmitRequest("change", ChangeRequest{
+		NotifyHook: c.GetNotifyHook(),
+		TaskId:     task,
+		Action:     action,
+		Index:      index,
+	})
+}
+
+// CreateActionRequest submits the button action (e.g. zoom, pan) of the task
+func (c *ChatInstance) CreateActionRequest(task string, customId string) (*ImagineResponse, error) {
+	return c.CreateSubmitRequest("action", ActionRequest{
+		NotifyHook: c.GetNotifyHook(),
+		TaskId:     task,
+		CustomId:   customId,
+	})
+}
+
+func (c *ChatInstance) CreateDescribeRequest(image globals.MessageContent) (*ImagineResponse, error) {
+	mimeType, data, err := utils.GetImageData(image)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the image: %s", err.Error())
+	}
+
+	return c.CreateSubmitRequest("describe", DescribeRequest{
+		NotifyHook: c.GetNotifyHook(),
+		Base64:     fmt.Sprintf("data:%s;base64,%s", mimeType, data),
+	})
+}
+
+// FetchTask fetches the task from the proxy, for the tasks which are not in the notify storage
+func (c *ChatInstance) FetchTask(task string) (*TaskResponse, error) {
+	res, err := utils.Get(fmt.Sprintf("%s/mj/task/%s/fetch", c.GetEndpoint(), task), c.GetImagineHeaders())
+	if err != nil {
+		return nil, err
+	}
+
+	form := utils.MapToStruct[TaskResponse](res)
+	if form == nil || form.Id == "" {
+		return nil, fmt.Errorf("task %s is not found", task)
+	}
+	return form, nil
+}
+
+func (c *ChatInstance) GetButtons(task string) ([]Button, error) {
+	if form := getStorage(task); form != nil && len(form.Buttons) > 0 {
+		return form.Buttons, nil
+	}
+
+	form, err := c.FetchTask(task)
+	if err != nil {
+		return nil, err
+	}
+	return form.Buttons, nil
+}
+
+func (c *ChatInstance) GetButtonId(task string, button string) (string, error) {
+	buttons, err := c.GetButtons(task)
+	if err != nil {
+		return "", err
+	}
+
+	prefix := ButtonPrefixes[button]
+	for _, item := range buttons {
+		if prefix != "" && strings.HasPrefix(item.CustomId, prefix) {
+			return item.CustomId, nil
+		}
+	}
+	return "", fmt.Errorf("action %s is not available for task %s, please upscale the image first", button, task)
+}
+
+// SubmitCommand submits the command to the proxy and returns the task id
+func (c *ChatInstance) SubmitCommand(command *Command) (string, error) {
+	var res *ImagineResponse
+	var err error
+
+	switch command.Action {
+	case UpscaleAction:
+		res, err = c.CreateChangeRequest(command.TaskId, UpscaleChange, command.Index)
+	case VariationAction:
+		res, err = c.CreateChangeRequest(command.TaskId, VariationChange, command.Index)
+	case RerollAction:
+		res, err = c.CreateChangeRequest(command.TaskId, RerollChange, 0)
+	case ZoomAction, PanAction:
+		customId, buttonErr := c.GetButtonId(command.TaskId, command.Button)
+		if buttonErr != nil {
+			return "", buttonErr
+		}
+		res, err = c.CreateActionRequest(command.TaskId, customId)
+	case DescribeAction:
+		res, err = c.CreateDescribeRequest(*command.Image)
+	default:
+		res, err = c.CreateImagineRequest(command.Prompt)
+	}
+
+	if err != nil {
+		return "", err
+	} else if res == nil {
+		return "", fmt.Errorf("cannot parse the response of midjourney proxy")
+	}
+
+	if err := getStatusCode(res); err != nil {
+		return "", err
+	}
+
+	if err := setChannel(res.Result, c.GetEndpoint()); err != nil {
+		globals.Warn(fmt.Sprintf("[midjourney] cannot bind task %s to the channel: %s", res.Result, err.Error()))
+	}
+	return res.Result, nil
+}
+
 func getStatusCode(response *ImagineResponse) error {
 	code := response.Code
 	switch code {
@@ -63,52 +180,23 @@ func getStatusCode(response *ImagineResponse) error {
 	}
 }
 
-func getProgress(value string) int {
-	progress := strings.TrimSuffix(value, "%")
-	return utils.ParseInt(progress)
-}
-
-func (c *ChatInstance) CreateStreamImagineTask(prompt string, hook func(progress int) error) (string, error) {
-	res, err := c.CreateImagineRequest(prompt)
+// CreateStreamTask submits the command and waits for the task, the hook is called with the progress of the task
+func (c *ChatInstance) CreateStreamTask(command *Command, hook TaskHook) (string, *StorageForm, error) {
+	task, err := c.SubmitCommand(command)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	if err := getStatusCode(res); err != nil {
-		return "", err
-	}
-
-	task := res.Result
-	progress := -1
-
-	for {
-		utils.Sleep(100)
-		form := getStorage(task)
-		if form == nil {
-			continue
-		}
+	form, err := c.WaitTask(task, hook)
+	return task, form, err
+}
 
-		switch form.Status {
-		case Success:
-			if err := hook(100); err != nil {
-				return "", err
-			}
-			return form.Url, nil
-		case Failure:
-			if err := hook(100); err != nil {
-				return "", err
-			}
-			return "", fmt.Errorf("task failed: %s", form.FailReason)
-		case InProgress:
-			current := getProgress(form.Progress)
-			if progress != current {
-				if err := hook(current); err != nil {
-					return "", err
-				}
-				progress = current
-			}
-		}
+func (c *ChatInstance) CreateStreamImagineTask(prompt string, hook TaskHook) (string, error) {
+	_, form, err := c.CreateStreamTask(&Command{Action: ImagineAction, Prompt: prompt}, hook)
+	if err != nil {
+		return "", err
 	}
+	return form.Url, nil
 }
 
 func (c *ChatInstance) CreateImagineTask(prompt string) (string, error) {
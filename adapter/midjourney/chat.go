@@ -45,6 +45,13 @@ func (c *ChatInstance) GetPrompt(props *ChatProps) string {
 	return c.GetCleanPrompt(props.Model, props.Messages[len(props.Messages)-1].Content)
 }
 
+func getTaskResult(command *Command, form *StorageForm) string {
+	if command.Action == DescribeAction {
+		return form.Prompt
+	}
+	return utils.GetImageMarkdown(form.Url)
+}
+
 func (c *ChatInstance) CreateStreamChatRequest(props *ChatProps, callback globals.Hook) error {
 	// partial response like:
 	// ```progress
@@ -53,17 +60,29 @@ func (c *ChatInstance) CreateStreamChatRequest(props *ChatProps, callback global
 	// 100
 	// ```
 	// ![image](...)
+	//
+	// > task: `...` · actions: U1-U4 · V1-V4 · /reroll
+
+	command, err := ParseCommand(props.Messages)
+	if err != nil {
+		return fmt.Errorf("format error: %s", err.Error())
+	}
 
-	prompt := c.GetPrompt(props)
-	if prompt == "" {
-		return fmt.Errorf("format error: please provide available prompt")
+	if command.Action == ImagineAction {
+		if command.Prompt = c.GetCleanPrompt(props.Model, command.Prompt); command.Prompt == "" {
+			return fmt.Errorf("format error: please provide available prompt")
+		}
 	}
 
 	if err := callback("```progress\n"); err != nil {
 		return err
 	}
 
-	url, err := c.CreateStreamImagineTask(prompt, func(progress int) error {
+	task, form, err := c.CreateStreamTask(command, func(progress int) error {
+		if progress == HeartbeatProgress {
+			// empty chunk to check the stop signal of the client
+			return callback("")
+		}
 		return callback(fmt.Sprintf("%d\n", progress))
 	})
 
@@ -75,5 +94,5 @@ func (c *ChatInstance) CreateStreamChatRequest(props *ChatProps, callback global
 		return fmt.Errorf("error from midjourney: %s", err.Error())
 	}
 
-	return callback(utils.GetImageMarkdown(url))
+	return callback(getTaskResult(command, form) + getTaskFooter(command.Action, task))
 }
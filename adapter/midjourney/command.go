@@ -0,0 +1,245 @@
+package midjourney
+
+import (
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Command is the midjourney task parsed from the chat message, like:
+//
+//	a cat in the space --ar 16:9     (imagine)
+//	/imagine a cat in the space      (imagine)
+//	U1, V2 [task]                    (upscale or variation of the grid image)
+//	/upscale [task] 1                (same as U1)
+//	/variation [task] 2              (same as V2)
+//	/reroll [task]                   (regenerate the grid image)
+//	/zoom [task] 2x|1.5x             (zoom out the upscaled image)
+//	/pan [task] left|right|up|down   (pan the upscaled image)
+//	/describe <image>                (describe the image url or the attached image)
+//
+// the task will be the latest task in the conversation if it is not provided
+type Command struct {
+	Action string
+	TaskId string
+	Index  int    // 1-4 of the upscale and variation action
+	Button string // zoom scale or pan direction
+	Prompt string
+	Image  *globals.MessageContent
+}
+
+var shortcutRegex = regexp.MustCompile(`(?i)^/?([UV])([1-4])(?:\s+(\S+))?$`)
+var taskRegex = regexp.MustCompile("> task: `([^`]+)`")
+var imageRegex = regexp.MustCompile(`!\[[^]]*]\(([^)]+)\)`)
+var progressRegex = regexp.MustCompile("```progress\n[\\s\\S]*?```\n?")
+
+func getLatestTask(messages []globals.Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != globals.Assistant {
+			continue
+		}
+
+		if matches := taskRegex.FindAllStringSubmatch(messages[i].Content, -1); len(matches) > 0 {
+			return matches[len(matches)-1][1]
+		}
+	}
+	return ""
+}
+
+func getIndex(arg string) int {
+	if index := utils.ParseInt(arg); index >= 1 && index <= 4 {
+		return index
+	}
+	return 0
+}
+
+func getZoomScale(arg string) string {
+	switch strings.ToLower(arg) {
+	case "2x", "2":
+		return "2x"
+	case "1.5x", "1.5":
+		return "1.5x"
+	default:
+		return ""
+	}
+}
+
+func getPanDirection(arg string) string {
+	direction := strings.ToLower(arg)
+	if utils.Contains(direction, []string{"left", "right", "up", "down"}) {
+		return direction
+	}
+	return ""
+}
+
+func getDescribeImage(message globals.Message, args []string) *globals.MessageContent {
+	for _, content := range message.Contents {
+		if content.IsImage() {
+			return &content
+		}
+	}
+
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://") {
+			return utils.ToPtr(globals.NewImageUrlContent(arg))
+		}
+	}
+	return nil
+}
+
+// parseArgs sets the arguments of the action command, the argument which is not available will be treated as the task id
+func (c *Command) parseArgs(args []string) error {
+	for _, arg := range args {
+		switch c.Action {
+		case UpscaleAction, VariationAction:
+			if index := getIndex(arg); index > 0 {
+				c.Index = index
+				continue
+			}
+		case ZoomAction:
+			if scale := getZoomScale(arg); scale != "" {
+				c.Button = scale
+				continue
+			}
+		case PanAction:
+			if direction := getPanDirection(arg); direction != "" {
+				c.Button = direction
+				continue
+			}
+		}
+
+		c.TaskId = arg
+	}
+
+	switch c.Action {
+	case UpscaleAction, VariationAction:
+		if c.Index == 0 {
+			return fmt.Errorf("please provide the index (1-4) of the image to %s", c.Action)
+		}
+	case ZoomAction:
+		if c.Button == "" {
+			c.Button = "2x"
+		}
+	case PanAction:
+		if c.Button == "" {
+			return fmt.Errorf("please provide the direction (left, right, up, down) to pan")
+		}
+	}
+	return nil
+}
+
+// ParseCommand parses the midjourney command from the latest message of the conversation
+func ParseCommand(messages []globals.Message) (*Command, error) {
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("please provide available prompt")
+	}
+
+	message := messages[len(messages)-1]
+	content := strings.TrimSpace(message.Content)
+	if content == "" {
+		return nil, fmt.Errorf("please provide available prompt")
+	}
+
+	command := &Command{Action: ImagineAction, Prompt: content}
+	if matches := shortcutRegex.FindStringSubmatch(content); matches != nil {
+		command.Action = utils.Multi(strings.ToUpper(matches[1]) == "U", UpscaleAction, VariationAction)
+		command.Index = getIndex(matches[2])
+		command.TaskId = matches[3]
+	} else if strings.HasPrefix(content, "/") {
+		fields := strings.Fields(content)
+		action := strings.ToLower(strings.TrimPrefix(fields[0], "/"))
+		if !utils.Contains(action, ActionArr) {
+			// not a command, e.g. the prompt starts with a slash
+			return command, nil
+		}
+
+		command.Action = action
+		switch action {
+		case ImagineAction:
+			command.Prompt = strings.TrimSpace(strings.TrimPrefix(content, fields[0]))
+			if command.Prompt == "" {
+				return nil, fmt.Errorf("please provide available prompt")
+			}
+			return command, nil
+		case DescribeAction:
+			if command.Image = getDescribeImage(message, fields[1:]); command.Image == nil {
+				return nil, fmt.Errorf("please provide the image url or attach the image to describe")
+			}
+			return command, nil
+		default:
+			if err := command.parseArgs(fields[1:]); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		return command, nil
+	}
+
+	if command.TaskId == "" {
+		if command.TaskId = getLatestTask(messages[:len(messages)-1]); command.TaskId == "" {
+			return nil, fmt.Errorf("cannot find the task to %s, please provide the task id", command.Action)
+		}
+	}
+	return command, nil
+}
+
+// GetBillingModel returns the model to bill the command by, the follow-up actions are billed as `<model>-<action>` (e.g. midjourney-fast-upscale)
+func GetBillingModel(model string, messages []globals.Message) string {
+	command, err := ParseCommand(messages)
+	if err != nil || command.Action == ImagineAction {
+		return model
+	}
+
+	return fmt.Sprintf("%s-%s", model, command.Action)
+}
+
+// getActionTips returns the follow-up actions of the task result
+func getActionTips(action string) string {
+	switch action {
+	case ImagineAction, VariationAction, RerollAction, ZoomAction, PanAction:
+		return "U1-U4 · V1-V4 · /reroll"
+	case UpscaleAction:
+		return "/zoom 2x|1.5x · /pan left|right|up|down"
+	default:
+		return ""
+	}
+}
+
+// getTaskFooter returns the footer of the task result, which carries the task id for the follow-up actions
+func getTaskFooter(action string, task string) string {
+	if tips := getActionTips(action); tips != "" {
+		return fmt.Sprintf("\n\n> task: `%s` · actions: %s", task, tips)
+	}
+	return fmt.Sprintf("\n\n> task: `%s`", task)
+}
+
+// Result is the task result parsed from the chat response, used by the relay api
+type Result struct {
+	TaskId string `json:"task_id"`
+	Url    string `json:"url,omitempty"`
+	Prompt string `json:"prompt,omitempty"`
+}
+
+// ParseResult parses the task id, the image url and the describe prompt from the chat response
+func ParseResult(content string) *Result {
+	matches := taskRegex.FindAllStringSubmatch(content, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	result := &Result{TaskId: matches[len(matches)-1][1]}
+
+	body := progressRegex.ReplaceAllString(content, "")
+	if index := strings.LastIndex(body, "\n\n> task: "); index >= 0 {
+		body = body[:index]
+	}
+
+	if images := imageRegex.FindAllStringSubmatch(body, -1); len(images) > 0 {
+		result.Url = images[len(images)-1][1]
+	} else {
+		result.Prompt = strings.TrimSpace(body)
+	}
+	return result
+}
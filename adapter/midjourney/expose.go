@@ -59,6 +59,8 @@ func NotifyAPI(c *gin.Context) {
 		FailReason: reason,
 		Progress:   form.Progress,
 		Status:     form.Status,
+		Prompt:     utils.Multi(len(form.Prompt) > 0, form.Prompt, form.PromptEn),
+		Buttons:    form.Buttons,
 	})
 
 	c.JSON(http.StatusOK, gin.H{
@@ -0,0 +1,35 @@
+package midjourney
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/globals"
+)
+
+func init() {
+	adaptercommon.Register(adaptercommon.NewProvider(
+		globals.MidjourneyChannelType,
+		[]string{
+			adaptercommon.ParamImages, // image of the describe command
+		},
+		adaptercommon.TokenLimit{},
+		createChatRequest,
+	).WithAcceptor(acceptRequest))
+}
+
+// acceptRequest returns whether the channel can serve the command, the follow-up actions must be sent to the proxy which owns the task
+func acceptRequest(conf globals.ChannelConfig, props *adaptercommon.ChatProps) bool {
+	command, err := ParseCommand(props.Message)
+	if err != nil || command.TaskId == "" {
+		return true
+	}
+
+	endpoint := getChannel(command.TaskId)
+	return endpoint == "" || endpoint == conf.GetEndpoint()
+}
+
+func createChatRequest(conf globals.ChannelConfig, props *adaptercommon.ChatProps, hook globals.Hook) error {
+	return NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&ChatProps{
+		Model:    props.Model,
+		Messages: props.Message,
+	}, hook)
+}
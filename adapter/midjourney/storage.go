@@ -17,3 +17,20 @@ func setStorage(task string, form StorageForm) error {
 func getStorage(task string) *StorageForm {
 	return utils.GetJson[StorageForm](connection.Cache, getTaskName(task))
 }
+
+func getChannelName(task string) string {
+	return fmt.Sprintf("nio:mj-channel:%s", task)
+}
+
+// setChannel binds the task to the endpoint of the channel, since the follow-up actions must be submitted to the same proxy
+func setChannel(task string, endpoint string) error {
+	return utils.SetCache(connection.Cache, getChannelName(task), endpoint, 7*24*60*60)
+}
+
+func getChannel(task string) string {
+	endpoint, err := utils.GetCache(connection.Cache, getChannelName(task))
+	if err != nil {
+		return ""
+	}
+	return endpoint
+}
@@ -0,0 +1,129 @@
+package midjourney
+
+import (
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTaskTimeout = 10 * time.Minute
+	taskPollInterval   = 100 * time.Millisecond // interval of polling the notify storage
+	taskFetchInterval  = 10 * time.Second       // interval of fetching the proxy when the notify storage is not updated
+	taskHeartbeat      = time.Second            // interval of the heartbeat to check the cancellation
+)
+
+// HeartbeatProgress is the progress passed to the task hook as the heartbeat, which is not a real progress
+const HeartbeatProgress = -1
+
+// TaskHook is called with the progress (0-100) of the task, and with HeartbeatProgress periodically while waiting,
+// the task is cancelled if the hook returns an error (e.g. the stop signal from the client)
+type TaskHook func(progress int) error
+
+func getTaskTimeout() time.Duration {
+	if globals.MidjourneyTimeout > 0 {
+		return time.Duration(globals.MidjourneyTimeout) * time.Second
+	}
+	return defaultTaskTimeout
+}
+
+func getProgress(value string) int {
+	progress := strings.TrimSuffix(value, "%")
+	return utils.ParseInt(progress)
+}
+
+func (t *TaskResponse) ToStorageForm() StorageForm {
+	return StorageForm{
+		Url:        t.ImageUrl,
+		FailReason: utils.Multi(len(t.FailReason) > 0, t.FailReason, "unknown"),
+		Progress:   t.Progress,
+		Status:     t.Status,
+		Prompt:     utils.Multi(len(t.Prompt) > 0, t.Prompt, t.PromptEn),
+		Buttons:    t.Buttons,
+	}
+}
+
+// syncTask fetches the task from the proxy and refreshes the notify storage, in case the notify callback never arrives
+func (c *ChatInstance) syncTask(task string) *StorageForm {
+	res, err := c.FetchTask(task)
+	if err != nil {
+		globals.Debug(fmt.Sprintf("[midjourney] cannot fetch task %s: %s", task, err.Error()))
+		return nil
+	}
+
+	if !utils.Contains(res.Status, []string{InProgress, Success, Failure}) {
+		return nil
+	}
+
+	form := res.ToStorageForm()
+	if err := setStorage(task, form); err != nil {
+		globals.Warn(fmt.Sprintf("[midjourney] cannot save task %s: %s", task, err.Error()))
+	}
+	return &form
+}
+
+// WaitTask waits for the task until it is finished, cancelled by the hook or timed out
+func (c *ChatInstance) WaitTask(task string, hook TaskHook) (*StorageForm, error) {
+	timeout := getTaskTimeout()
+	start := time.Now()
+	updated, fetched, beat := start, start, start
+
+	progress := -1
+	status := ""
+
+	for {
+		if time.Since(start) > timeout {
+			globals.Info(fmt.Sprintf("[midjourney] task %s timed out after %s (endpoint: %s)", task, timeout, c.GetEndpoint()))
+			return nil, fmt.Errorf("task timed out after %s, please try again later", timeout)
+		}
+
+		time.Sleep(taskPollInterval)
+
+		form := getStorage(task)
+		if form == nil || (form.Status == status && getProgress(form.Progress) == progress) {
+			if time.Since(updated) > taskFetchInterval && time.Since(fetched) > taskFetchInterval {
+				// notify storage is not updated for a while, fetch the task from the proxy
+				fetched = time.Now()
+				form = c.syncTask(task)
+			}
+		}
+
+		if form != nil && form.Status != status {
+			status = form.Status
+			updated = time.Now()
+		}
+
+		if form != nil {
+			switch form.Status {
+			case Success:
+				if err := hook(100); err != nil {
+					return nil, err
+				}
+				return form, nil
+			case Failure:
+				if err := hook(100); err != nil {
+					return nil, err
+				}
+				return nil, fmt.Errorf("task failed: %s", form.FailReason)
+			case InProgress:
+				if current := getProgress(form.Progress); current != progress {
+					if err := hook(current); err != nil {
+						return nil, err
+					}
+					progress = current
+					updated, beat = time.Now(), time.Now()
+					continue
+				}
+			}
+		}
+
+		if time.Since(beat) > taskHeartbeat {
+			if err := hook(HeartbeatProgress); err != nil {
+				return nil, err
+			}
+			beat = time.Now()
+		}
+	}
+}
@@ -24,6 +24,35 @@ const (
 
 var ModeArr = []string{TurboMode, FastMode, RelaxMode}
 
+const (
+	ImagineAction   = "imagine"
+	UpscaleAction   = "upscale"
+	VariationAction = "variation"
+	RerollAction    = "reroll"
+	ZoomAction      = "zoom"
+	PanAction       = "pan"
+	DescribeAction  = "describe"
+)
+
+var ActionArr = []string{ImagineAction, UpscaleAction, VariationAction, RerollAction, ZoomAction, PanAction, DescribeAction}
+
+// change actions of the midjourney proxy (`/mj/submit/change`)
+const (
+	UpscaleChange   = "UPSCALE"
+	VariationChange = "VARIATION"
+	RerollChange    = "REROLL"
+)
+
+// zoom and pan buttons of the upscaled image, matched by the prefix of the custom id
+var ButtonPrefixes = map[string]string{
+	"2x":    "MJ::Outpaint::50::",
+	"1.5x":  "MJ::Outpaint::75::",
+	"left":  "MJ::JOB::pan_left::",
+	"right": "MJ::JOB::pan_right::",
+	"up":    "MJ::JOB::pan_up::",
+	"down":  "MJ::JOB::pan_down::",
+}
+
 type ImagineHeader struct {
 	ContentType string `json:"Content-Type"`
 	MjApiSecret string `json:"mj-api-secret,omitempty"`
@@ -34,6 +63,43 @@ type ImagineRequest struct {
 	Prompt     string `json:"prompt"`
 }
 
+type ChangeRequest struct {
+	NotifyHook string `json:"notifyHook"`
+	TaskId     string `json:"taskId"`
+	Action     string `json:"action"`
+	Index      int    `json:"index,omitempty"`
+}
+
+type ActionRequest struct {
+	NotifyHook string `json:"notifyHook"`
+	TaskId     string `json:"taskId"`
+	CustomId   string `json:"customId"`
+}
+
+type DescribeRequest struct {
+	NotifyHook string `json:"notifyHook"`
+	Base64     string `json:"base64"` // data url of the image
+}
+
+type Button struct {
+	CustomId string `json:"customId"`
+	Emoji    string `json:"emoji"`
+	Label    string `json:"label"`
+}
+
+// TaskResponse is the response of the task fetch api (`/mj/task/{id}/fetch`)
+type TaskResponse struct {
+	Id         string   `json:"id"`
+	Action     string   `json:"action"`
+	Status     string   `json:"status"`
+	Prompt     string   `json:"prompt"`
+	PromptEn   string   `json:"promptEn"`
+	Progress   string   `json:"progress"`
+	ImageUrl   string   `json:"imageUrl"`
+	FailReason string   `json:"failReason"`
+	Buttons    []Button `json:"buttons"`
+}
+
 type ImagineResponse struct {
 	Code        int    `json:"code"`
 	Description string `json:"description"`
@@ -53,11 +119,14 @@ type NotifyForm struct {
 	Progress    string      `json:"progress"`
 	ImageUrl    string      `json:"imageUrl"`
 	FailReason  interface{} `json:"failReason"`
+	Buttons     []Button    `json:"buttons"`
 }
 
 type StorageForm struct {
-	Url        string `json:"url"`
-	FailReason string `json:"failReason"`
-	Progress   string `json:"progress"`
-	Status     string `json:"status"`
+	Url        string   `json:"url"`
+	FailReason string   `json:"failReason"`
+	Progress   string   `json:"progress"`
+	Status     string   `json:"status"`
+	Prompt     string   `json:"prompt"` // result of the describe task
+	Buttons    []Button `json:"buttons"`
 }
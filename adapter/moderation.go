@@ -0,0 +1,49 @@
+package adapter
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/globals"
+	"fmt"
+	"strings"
+)
+
+type ModerationProps = adaptercommon.ModerationProps
+type ModerationResponse = adaptercommon.ModerationResponse
+
+// IsModerationSupported returns whether the channel type supports the moderations api
+func IsModerationSupported(t string) bool {
+	_, ok := adaptercommon.GetProvider(t).(adaptercommon.Moderator)
+	return ok
+}
+
+func NewModerationRequest(conf globals.ChannelConfig, props *ModerationProps) (*ModerationResponse, error) {
+	moderator, ok := adaptercommon.GetProvider(conf.GetType()).(adaptercommon.Moderator)
+	if !ok {
+		return nil, conf.ProcessError(fmt.Errorf("channel type %s does not support moderations api for model %s", conf.GetType(), props.Model))
+	}
+
+	return createRetryModerationRequest(moderator, conf, props)
+}
+
+func createModerationRequest(moderator adaptercommon.Moderator, conf globals.ChannelConfig, props *ModerationProps) (*ModerationResponse, error) {
+	// copy the props to avoid the reflection model leaking to the other channels
+	instance := *props
+	instance.Model = conf.GetModelReflect(props.Model)
+
+	return moderator.CreateModeration(conf, &instance)
+}
+
+func createRetryModerationRequest(moderator adaptercommon.Moderator, conf globals.ChannelConfig, props *ModerationProps) (*ModerationResponse, error) {
+	resp, err := createModerationRequest(moderator, conf, props)
+
+	retries := conf.GetRetry()
+	props.Current++
+
+	if IsAvailableError(err) && props.Current < retries {
+		content := strings.Replace(err.Error(), "\n", "", -1)
+		globals.Warn(fmt.Sprintf("retrying moderation request for %s (attempt %d/%d, error: %s)", props.Model, props.Current+1, retries, content))
+		return createRetryModerationRequest(moderator, conf, props)
+	}
+
+	return resp, conf.ProcessError(err)
+}
@@ -0,0 +1,164 @@
+package ollama
+
+import (
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+	"strings"
+)
+
+type ChatProps struct {
+	Model             string
+	Message           []globals.Message
+	Token             *int
+	Temperature       *float32
+	TopP              *float32
+	TopK              *int
+	PresencePenalty   *float32
+	FrequencyPenalty  *float32
+	RepetitionPenalty *float32
+	Stop              []string
+	Seed              *int
+	Buffer            *utils.Buffer
+}
+
+func (c *ChatInstance) GetChatEndpoint() string {
+	return fmt.Sprintf("%s/api/chat", c.GetEndpoint())
+}
+
+// getContent returns the text and the base64 images of the typed parts
+func getContent(contents globals.MessageContents) (string, []string) {
+	text := ""
+	images := make([]string, 0)
+	for _, content := range contents {
+		if !content.IsImage() {
+			text += content.GetText()
+			continue
+		}
+
+		if _, data, err := utils.GetImageData(content); err == nil {
+			images = append(images, data)
+		}
+	}
+	return text, images
+}
+
+func (c *ChatInstance) GetMessages(message []globals.Message) []Message {
+	return utils.Each[globals.Message, Message](message, func(message globals.Message) Message {
+		role := message.Role
+		if role == globals.Tool {
+			// ollama does not support tool messages
+			role = globals.User
+		}
+
+		if message.Role != globals.User || len(message.Contents) == 0 {
+			return Message{
+				Role:    role,
+				Content: message.Content,
+			}
+		}
+
+		content, images := getContent(message.Contents)
+		return Message{
+			Role:    role,
+			Content: content,
+			Images:  images,
+		}
+	})
+}
+
+func (c *ChatInstance) GetChatBody(props *ChatProps, stream bool) *ChatRequest {
+	return &ChatRequest{
+		Model:    props.Model,
+		Messages: c.GetMessages(props.Message),
+		Stream:   stream,
+		Options: &Options{
+			Temperature:      props.Temperature,
+			TopP:             props.TopP,
+			TopK:             props.TopK,
+			NumPredict:       props.Token,
+			RepeatPenalty:    props.RepetitionPenalty,
+			PresencePenalty:  props.PresencePenalty,
+			FrequencyPenalty: props.FrequencyPenalty,
+			Stop:             props.Stop,
+			Seed:             props.Seed,
+		},
+	}
+}
+
+// CreateChatRequest is the request for ollama
+func (c *ChatInstance) CreateChatRequest(props *ChatProps) (string, error) {
+	res, err := utils.Post(c.GetChatEndpoint(), c.GetHeader(), c.GetChatBody(props, false))
+	if err != nil || res == nil {
+		return "", fmt.Errorf("ollama error: %s", utils.GetError(err))
+	}
+
+	data := utils.MapToStruct[ChatResponse](res)
+	if data == nil {
+		return "", fmt.Errorf("ollama error: cannot parse response")
+	} else if data.Error != "" {
+		return "", fmt.Errorf("ollama error: %s", data.Error)
+	}
+
+	props.Buffer.SetInputTokens(data.PromptEvalCount)
+	props.Buffer.SetOutputTokens(data.EvalCount)
+	return data.Message.Content, nil
+}
+
+// CreateStreamChatRequest is the stream request for ollama, the response is streamed as ndjson
+func (c *ChatInstance) CreateStreamChatRequest(props *ChatProps, hook globals.Hook) error {
+	// response example:
+	//
+	// {"model":"llama2","created_at":"2023-08-04T08:52:19.385406455-07:00","message":{"role":"assistant","content":"The"},"done":false}
+	// {"model":"llama2","created_at":"2023-08-04T19:22:45.499127Z","done":true,"prompt_eval_count":26,"eval_count":290}
+
+	buf := ""
+	empty := true
+
+	err := utils.EventSource(
+		"POST",
+		c.GetChatEndpoint(),
+		c.GetHeader(),
+		c.GetChatBody(props, true),
+		func(data string) error {
+			// the line may be cut off by the chunk, wait for the rest of it
+			buf += data
+			form := utils.UnmarshalForm[ChatResponse](buf)
+			if form == nil && buf != data {
+				// the previous segment is broken, try the current line only
+				if form = utils.UnmarshalForm[ChatResponse](data); form != nil {
+					globals.Warn(fmt.Sprintf("ollama error: cannot parse response: %s", strings.TrimSuffix(buf, data)))
+				}
+			}
+
+			if form == nil {
+				return nil
+			}
+
+			buf = ""
+			if form.Error != "" {
+				return fmt.Errorf("ollama error: %s", form.Error)
+			}
+
+			if form.Done {
+				props.Buffer.SetInputTokens(form.PromptEvalCount)
+				props.Buffer.SetOutputTokens(form.EvalCount)
+			}
+
+			if len(form.Message.Content) == 0 {
+				return nil
+			}
+
+			empty = false
+			return hook(form.Message.Content)
+		},
+	)
+
+	if err != nil {
+		return err
+	} else if empty {
+		return fmt.Errorf("empty response")
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package ollama
+
+import (
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+	"strings"
+)
+
+var llamaCppRoles = map[string]string{
+	globals.System:    "System",
+	globals.User:      "User",
+	globals.Assistant: "Assistant",
+	globals.Tool:      "User",
+}
+
+func (c *ChatInstance) GetCompletionEndpoint() string {
+	return fmt.Sprintf("%s/completion", c.GetEndpoint())
+}
+
+// GetCompletionPrompt formats the messages as a plain chat transcript, since llama.cpp `/completion` accepts raw prompt
+func (c *ChatInstance) GetCompletionPrompt(messages []globals.Message) string {
+	var result string
+	for _, message := range messages {
+		result += fmt.Sprintf("%s: %s\n", llamaCppRoles[message.Role], message.Content)
+	}
+	return fmt.Sprintf("%s%s:", result, llamaCppRoles[globals.Assistant])
+}
+
+func (c *ChatInstance) GetCompletionBody(props *ChatProps, stream bool) *CompletionRequest {
+	return &CompletionRequest{
+		Prompt:           c.GetCompletionPrompt(props.Message),
+		Stream:           stream,
+		NPredict:         props.Token,
+		Temperature:      props.Temperature,
+		TopP:             props.TopP,
+		TopK:             props.TopK,
+		RepeatPenalty:    props.RepetitionPenalty,
+		PresencePenalty:  props.PresencePenalty,
+		FrequencyPenalty: props.FrequencyPenalty,
+		Stop:             append([]string{fmt.Sprintf("\n%s:", llamaCppRoles[globals.User])}, props.Stop...),
+		Seed:             props.Seed,
+		CachePrompt:      true,
+	}
+}
+
+// CreateCompletionRequest is the request for llama.cpp server
+func (c *ChatInstance) CreateCompletionRequest(props *ChatProps) (string, error) {
+	res, err := utils.Post(c.GetCompletionEndpoint(), c.GetHeader(), c.GetCompletionBody(props, false))
+	if err != nil || res == nil {
+		return "", fmt.Errorf("llama.cpp error: %s", utils.GetError(err))
+	}
+
+	data := utils.MapToStruct[CompletionResponse](res)
+	if data == nil {
+		return "", fmt.Errorf("llama.cpp error: cannot parse response")
+	} else if data.Error != nil {
+		return "", fmt.Errorf("llama.cpp error: %s (type: %s)", data.Error.Message, data.Error.Type)
+	}
+
+	props.Buffer.SetInputTokens(data.TokensEvaluated)
+	props.Buffer.SetOutputTokens(data.TokensPredicted)
+	return data.Content, nil
+}
+
+// CreateStreamCompletionRequest is the stream request for llama.cpp server
+func (c *ChatInstance) CreateStreamCompletionRequest(props *ChatProps, hook globals.Hook) error {
+	// response example:
+	//
+	// data: {"content":"Hello","stop":false}
+	// data: {"content":"","stop":true,"tokens_evaluated":12,"tokens_predicted":20}
+
+	buf := ""
+	empty := true
+
+	err := utils.EventSource(
+		"POST",
+		c.GetCompletionEndpoint(),
+		c.GetHeader(),
+		c.GetCompletionBody(props, true),
+		func(data string) error {
+			if strings.HasPrefix(data, "data:") {
+				buf = ""
+				data = strings.TrimSpace(strings.TrimPrefix(data, "data:"))
+			}
+
+			// the line may be cut off by the chunk, wait for the rest of it
+			buf += data
+			form := utils.UnmarshalForm[CompletionResponse](buf)
+			if form == nil {
+				return nil
+			}
+
+			buf = ""
+			if form.Error != nil {
+				return fmt.Errorf("llama.cpp error: %s (type: %s)", form.Error.Message, form.Error.Type)
+			}
+
+			if form.Stop {
+				props.Buffer.SetInputTokens(form.TokensEvaluated)
+				props.Buffer.SetOutputTokens(form.TokensPredicted)
+			}
+
+			if len(form.Content) == 0 {
+				return nil
+			}
+
+			empty = false
+			return hook(form.Content)
+		},
+	)
+
+	if err != nil {
+		return err
+	} else if empty {
+		return fmt.Errorf("empty response")
+	}
+
+	return nil
+}
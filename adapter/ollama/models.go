@@ -0,0 +1,58 @@
+package ollama
+
+import (
+	"chat/utils"
+	"fmt"
+)
+
+func (c *ChatInstance) GetTagsEndpoint() string {
+	return fmt.Sprintf("%s/api/tags", c.GetEndpoint())
+}
+
+func (c *ChatInstance) GetModelsEndpoint() string {
+	return fmt.Sprintf("%s/v1/models", c.GetEndpoint())
+}
+
+// ListModels returns the local models of ollama from `/api/tags`
+func (c *ChatInstance) ListModels() ([]string, error) {
+	res, err := utils.Get(c.GetTagsEndpoint(), c.GetHeader())
+	if err != nil || res == nil {
+		return nil, fmt.Errorf("ollama error: %s", utils.GetError(err))
+	}
+
+	data := utils.MapToStruct[TagsResponse](res)
+	if data == nil {
+		return nil, fmt.Errorf("ollama error: cannot parse response")
+	} else if data.Error != "" {
+		return nil, fmt.Errorf("ollama error: %s", data.Error)
+	}
+
+	models := make([]string, 0)
+	for _, model := range data.Models {
+		if len(model.Name) > 0 && !utils.Contains(model.Name, models) {
+			models = append(models, model.Name)
+		}
+	}
+	return models, nil
+}
+
+// ListCompletionModels returns the loaded model of llama.cpp server from `/v1/models`
+func (c *ChatInstance) ListCompletionModels() ([]string, error) {
+	res, err := utils.Get(c.GetModelsEndpoint(), c.GetHeader())
+	if err != nil || res == nil {
+		return nil, fmt.Errorf("llama.cpp error: %s", utils.GetError(err))
+	}
+
+	data := utils.MapToStruct[ModelsResponse](res)
+	if data == nil {
+		return nil, fmt.Errorf("llama.cpp error: cannot parse response")
+	}
+
+	models := make([]string, 0)
+	for _, model := range data.Data {
+		if len(model.Id) > 0 && !utils.Contains(model.Id, models) {
+			models = append(models, model.Id)
+		}
+	}
+	return models, nil
+}
@@ -0,0 +1,65 @@
+package ollama
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/globals"
+)
+
+var tokenLimit = adaptercommon.TokenLimit{}
+
+var params = []string{
+	adaptercommon.ParamTemperature,
+	adaptercommon.ParamTopP,
+	adaptercommon.ParamTopK,
+	adaptercommon.ParamPresencePenalty,
+	adaptercommon.ParamFrequencyPenalty,
+	adaptercommon.ParamRepetitionPenalty,
+	adaptercommon.ParamStop,
+	adaptercommon.ParamSeed,
+}
+
+func init() {
+	// ollama accepts images for the multimodal models (e.g. llava), llama.cpp completion is text only
+	adaptercommon.Register(adaptercommon.NewListableProvider(
+		adaptercommon.NewProvider(globals.OllamaChannelType, append(params, adaptercommon.ParamImages), tokenLimit, createChatRequest),
+		listModels,
+	))
+
+	adaptercommon.Register(adaptercommon.NewListableProvider(
+		adaptercommon.NewProvider(globals.LlamaCppChannelType, params, tokenLimit, createCompletionRequest),
+		listCompletionModels,
+	))
+}
+
+func getChatProps(props *adaptercommon.ChatProps) *ChatProps {
+	return &ChatProps{
+		Model:             props.Model,
+		Message:           props.Message,
+		Token:             tokenLimit.GetToken(props),
+		Temperature:       props.Temperature,
+		TopP:              props.TopP,
+		TopK:              props.TopK,
+		PresencePenalty:   props.PresencePenalty,
+		FrequencyPenalty:  props.FrequencyPenalty,
+		RepetitionPenalty: props.RepetitionPenalty,
+		Stop:              props.Stop,
+		Seed:              props.Seed,
+		Buffer:            props.Buffer,
+	}
+}
+
+func createChatRequest(conf globals.ChannelConfig, props *adaptercommon.ChatProps, hook globals.Hook) error {
+	return NewChatInstanceFromConfig(conf).CreateStreamChatRequest(getChatProps(props), hook)
+}
+
+func createCompletionRequest(conf globals.ChannelConfig, props *adaptercommon.ChatProps, hook globals.Hook) error {
+	return NewChatInstanceFromConfig(conf).CreateStreamCompletionRequest(getChatProps(props), hook)
+}
+
+func listModels(conf globals.ChannelConfig) ([]string, error) {
+	return NewChatInstanceFromConfig(conf).ListModels()
+}
+
+func listCompletionModels(conf globals.ChannelConfig) ([]string, error) {
+	return NewChatInstanceFromConfig(conf).ListCompletionModels()
+}
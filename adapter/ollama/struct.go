@@ -0,0 +1,48 @@
+package ollama
+
+import (
+	"chat/globals"
+	"fmt"
+	"strings"
+)
+
+type ChatInstance struct {
+	Endpoint string
+	ApiKey   string
+}
+
+func NewChatInstance(endpoint, apiKey string) *ChatInstance {
+	return &ChatInstance{
+		Endpoint: strings.TrimSuffix(endpoint, "/"),
+		ApiKey:   apiKey,
+	}
+}
+
+func NewChatInstanceFromConfig(conf globals.ChannelConfig) *ChatInstance {
+	return NewChatInstance(
+		conf.GetEndpoint(),
+		conf.GetRandomSecret(),
+	)
+}
+
+func (c *ChatInstance) GetEndpoint() string {
+	return c.Endpoint
+}
+
+func (c *ChatInstance) GetApiKey() string {
+	return c.ApiKey
+}
+
+// GetHeader returns the request headers, local servers are usually deployed without authorization
+// so the api key is optional (e.g. behind a reverse proxy)
+func (c *ChatInstance) GetHeader() map[string]string {
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+
+	if key := strings.TrimSpace(c.GetApiKey()); len(key) > 0 {
+		headers["Authorization"] = fmt.Sprintf("Bearer %s", key)
+	}
+
+	return headers
+}
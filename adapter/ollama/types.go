@@ -0,0 +1,82 @@
+package ollama
+
+type Message struct {
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"` // base64 encoded images
+}
+
+type Options struct {
+	Temperature      *float32 `json:"temperature,omitempty"`
+	TopP             *float32 `json:"top_p,omitempty"`
+	TopK             *int     `json:"top_k,omitempty"`
+	NumPredict       *int     `json:"num_predict,omitempty"`
+	RepeatPenalty    *float32 `json:"repeat_penalty,omitempty"`
+	PresencePenalty  *float32 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float32 `json:"frequency_penalty,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+}
+
+// ChatRequest is the request body for ollama `/api/chat`
+type ChatRequest struct {
+	Model    string    `json:"model"`
+	Messages []Message `json:"messages"`
+	Stream   bool      `json:"stream"`
+	Options  *Options  `json:"options,omitempty"`
+}
+
+// ChatResponse is the native http response body and the ndjson stream line for ollama `/api/chat`
+type ChatResponse struct {
+	Model           string  `json:"model"`
+	Message         Message `json:"message"`
+	Done            bool    `json:"done"`
+	PromptEvalCount int     `json:"prompt_eval_count"`
+	EvalCount       int     `json:"eval_count"`
+	Error           string  `json:"error"`
+}
+
+// TagsResponse is the response body for ollama `/api/tags`
+type TagsResponse struct {
+	Models []struct {
+		Name  string `json:"name"`
+		Model string `json:"model"`
+	} `json:"models"`
+	Error string `json:"error"`
+}
+
+// CompletionRequest is the request body for llama.cpp server `/completion`
+type CompletionRequest struct {
+	Prompt           string   `json:"prompt"`
+	Stream           bool     `json:"stream"`
+	NPredict         *int     `json:"n_predict,omitempty"`
+	Temperature      *float32 `json:"temperature,omitempty"`
+	TopP             *float32 `json:"top_p,omitempty"`
+	TopK             *int     `json:"top_k,omitempty"`
+	RepeatPenalty    *float32 `json:"repeat_penalty,omitempty"`
+	PresencePenalty  *float32 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float32 `json:"frequency_penalty,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+	CachePrompt      bool     `json:"cache_prompt"`
+}
+
+// CompletionResponse is the native http response body and the stream event data for llama.cpp server `/completion`
+type CompletionResponse struct {
+	Content         string `json:"content"`
+	Stop            bool   `json:"stop"`
+	TokensEvaluated int    `json:"tokens_evaluated"`
+	TokensPredicted int    `json:"tokens_predicted"`
+	Error           *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error"`
+}
+
+// ModelsResponse is the response body for llama.cpp server `/v1/models`
+type ModelsResponse struct {
+	Data []struct {
+		Id string `json:"id"`
+	} `json:"data"`
+}
@@ -0,0 +1,84 @@
+package oneapi
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/utils"
+	"fmt"
+	"strings"
+)
+
+// SpeechRequest is the request body for oneapi text-to-speech
+type SpeechRequest struct {
+	Model          string   `json:"model"`
+	Input          string   `json:"input"`
+	Voice          string   `json:"voice"`
+	ResponseFormat *string  `json:"response_format,omitempty"`
+	Speed          *float32 `json:"speed,omitempty"`
+}
+
+// AudioErrorResponse is the error response body for oneapi audio api
+type AudioErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// TranscriptionResponse is the native http response body for oneapi transcriptions and translations (verbose json)
+type TranscriptionResponse struct {
+	adaptercommon.AudioResponse
+	AudioErrorResponse
+}
+
+func (c *ChatInstance) GetAudioEndpoint(path string) string {
+	return fmt.Sprintf("%s/v1/audio/%s", c.GetEndpoint(), path)
+}
+
+func (c *ChatInstance) GetSpeechBody(props *adaptercommon.SpeechProps) SpeechRequest {
+	return SpeechRequest{
+		Model:          props.Model,
+		Input:          props.Input,
+		Voice:          props.Voice,
+		ResponseFormat: props.ResponseFormat,
+		Speed:          props.Speed,
+	}
+}
+
+// CreateTranscriptionRequest is the native http request for oneapi transcriptions and translations
+func (c *ChatInstance) CreateTranscriptionRequest(props *adaptercommon.AudioProps) (*adaptercommon.AudioResponse, error) {
+	res, err := utils.PostMultipart(c.GetAudioEndpoint(props.GetPath()), c.GetHeader(), props.GetFields(), props.GetFiles())
+	if err != nil || res == nil {
+		return nil, fmt.Errorf("oneapi error: %s", utils.GetError(err))
+	}
+
+	data := utils.MapToStruct[TranscriptionResponse](res)
+	if data == nil {
+		return nil, fmt.Errorf("oneapi error: cannot parse response")
+	} else if data.Error.Message != "" {
+		return nil, fmt.Errorf("oneapi error: %s", data.Error.Message)
+	}
+
+	return &data.AudioResponse, nil
+}
+
+// CreateSpeechRequest is the native http request for oneapi text-to-speech, the audio is returned as binary
+func (c *ChatInstance) CreateSpeechRequest(props *adaptercommon.SpeechProps) (*adaptercommon.SpeechResponse, error) {
+	data, contentType, err := utils.PostRaw(c.GetAudioEndpoint("speech"), c.GetHeader(), c.GetSpeechBody(props))
+	if err != nil {
+		return nil, fmt.Errorf("oneapi error: %s", err.Error())
+	}
+
+	if strings.HasPrefix(contentType, "application/json") {
+		form := utils.UnmarshalForm[AudioErrorResponse](string(data))
+		if form == nil || form.Error.Message == "" {
+			return nil, fmt.Errorf("oneapi error: cannot parse response")
+		}
+		return nil, fmt.Errorf("oneapi error: %s", form.Error.Message)
+	} else if len(data) == 0 {
+		return nil, fmt.Errorf("oneapi error: empty speech response")
+	}
+
+	return &adaptercommon.SpeechResponse{
+		Data:        data,
+		ContentType: contentType,
+	}, nil
+}
@@ -0,0 +1,66 @@
+package oneapi
+
+import (
+	"chat/utils"
+	"fmt"
+	"time"
+)
+
+// SubscriptionResponse is the native http response body of the billing subscription
+type SubscriptionResponse struct {
+	HardLimitUsd float64 `json:"hard_limit_usd"`
+	Error        struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// UsageResponse is the native http response body of the billing usage, the total usage is in cents
+type UsageResponse struct {
+	TotalUsage float64 `json:"total_usage"`
+	Error      struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (c *ChatInstance) GetSubscriptionEndpoint() string {
+	return fmt.Sprintf("%s/v1/dashboard/billing/subscription", c.GetEndpoint())
+}
+
+func (c *ChatInstance) GetUsageEndpoint() string {
+	now := time.Now()
+	return fmt.Sprintf(
+		"%s/v1/dashboard/billing/usage?start_date=%s&end_date=%s",
+		c.GetEndpoint(),
+		now.AddDate(0, 0, -99).Format("2006-01-02"),
+		now.AddDate(0, 0, 1).Format("2006-01-02"),
+	)
+}
+
+// GetBalance returns the remaining balance of the api key in usd, the hard limit minus the usage
+func (c *ChatInstance) GetBalance() (float64, error) {
+	res, err := utils.Get(c.GetSubscriptionEndpoint(), c.GetHeader())
+	if err != nil || res == nil {
+		return 0, fmt.Errorf("oneapi error: %s", utils.GetError(err))
+	}
+
+	subscription := utils.MapToStruct[SubscriptionResponse](res)
+	if subscription == nil {
+		return 0, fmt.Errorf("oneapi error: cannot parse subscription response")
+	} else if subscription.Error.Message != "" {
+		return 0, fmt.Errorf("oneapi error: %s", subscription.Error.Message)
+	}
+
+	res, err = utils.Get(c.GetUsageEndpoint(), c.GetHeader())
+	if err != nil || res == nil {
+		return 0, fmt.Errorf("oneapi error: %s", utils.GetError(err))
+	}
+
+	usage := utils.MapToStruct[UsageResponse](res)
+	if usage == nil {
+		return 0, fmt.Errorf("oneapi error: cannot parse usage response")
+	} else if usage.Error.Message != "" {
+		return 0, fmt.Errorf("oneapi error: %s", usage.Error.Message)
+	}
+
+	return subscription.HardLimitUsd - usage.TotalUsage/100, nil
+}
@@ -1,6 +1,7 @@
 package oneapi
 
 import (
+	adaptercommon "chat/adapter/common"
 	"chat/globals"
 	"chat/utils"
 	"fmt"
@@ -17,7 +18,12 @@ type ChatProps struct {
 	TopP             *float32               `json:"top_p"`
 	Tools            *globals.FunctionTools `json:"tools"`
 	ToolChoice       *interface{}           `json:"tool_choice"` // string or object
-	Buffer           utils.Buffer
+	Stop             []string               `json:"stop"`
+	Seed             *int                   `json:"seed"`
+	LogitBias        *map[string]float32    `json:"logit_bias"`
+	ResponseFormat   *interface{}           `json:"response_format"`
+	User             *string                `json:"user"`
+	Buffer           *utils.Buffer
 }
 
 func (c *ChatInstance) GetChatEndpoint() string {
@@ -36,6 +42,12 @@ func (c *ChatInstance) GetChatBody(props *ChatProps, stream bool) ChatRequest {
 		TopP:             props.TopP,
 		Tools:            props.Tools,
 		ToolChoice:       props.ToolChoice,
+		Stop:             props.Stop,
+		Seed:             props.Seed,
+		LogitBias:        props.LogitBias,
+		ResponseFormat:   props.ResponseFormat,
+		User:             props.User,
+		StreamOptions:    utils.Multi[*StreamOptions](stream && !adaptercommon.IsStreamOptionsRejected(c.GetEndpoint()), &StreamOptions{IncludeUsage: true}, nil),
 	}
 }
 
@@ -64,31 +76,33 @@ func (c *ChatInstance) CreateChatRequest(props *ChatProps) (string, error) {
 func (c *ChatInstance) CreateStreamChatRequest(props *ChatProps, callback globals.Hook) error {
 	buf := ""
 
-	return utils.EventSource(
-		"POST",
-		c.GetChatEndpoint(),
-		c.GetHeader(),
-		c.GetChatBody(props, true),
-		func(data string) error {
-			data, err := c.ProcessLine(props.Buffer, buf, data)
+	return adaptercommon.WithStreamOptions(c.GetEndpoint(), func() error {
+		return utils.EventSource(
+			"POST",
+			c.GetChatEndpoint(),
+			c.GetHeader(),
+			c.GetChatBody(props, true),
+			func(data string) error {
+				data, err := c.ProcessLine(props.Buffer, buf, data)
 
-			if err != nil {
-				if strings.HasPrefix(err.Error(), "oneapi error") {
-					return err
-				}
+				if err != nil {
+					if strings.HasPrefix(err.Error(), "oneapi error") {
+						return err
+					}
 
-				// error when break line
-				buf = buf + data
-				return nil
-			}
+					// error when break line
+					buf = buf + data
+					return nil
+				}
 
-			buf = ""
-			if data != "" {
-				if err := callback(data); err != nil {
-					return err
+				buf = ""
+				if data != "" {
+					if err := callback(data); err != nil {
+						return err
+					}
 				}
-			}
-			return nil
-		},
-	)
+				return nil
+			},
+		)
+	})
 }
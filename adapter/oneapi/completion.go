@@ -0,0 +1,108 @@
+package oneapi
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/utils"
+	"fmt"
+	"strings"
+)
+
+// CompletionBody is the request body for oneapi legacy completions api with the full parameters
+type CompletionBody struct {
+	Model            string         `json:"model"`
+	Prompt           string         `json:"prompt"`
+	Suffix           *string        `json:"suffix,omitempty"`
+	Echo             bool           `json:"echo,omitempty"`
+	Stop             []string       `json:"stop,omitempty"`
+	Logprobs         *int           `json:"logprobs,omitempty"`
+	MaxTokens        *int           `json:"max_tokens,omitempty"`
+	Temperature      *float32       `json:"temperature,omitempty"`
+	TopP             *float32       `json:"top_p,omitempty"`
+	PresencePenalty  *float32       `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float32       `json:"frequency_penalty,omitempty"`
+	User             *string        `json:"user,omitempty"`
+	Stream           bool           `json:"stream"`
+	StreamOptions    *StreamOptions `json:"stream_options,omitempty"`
+}
+
+// CompletionNativeResponse is the stream response body for oneapi legacy completions api
+type CompletionNativeResponse struct {
+	adaptercommon.CompletionResponse
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error"`
+}
+
+func (c *ChatInstance) GetCompletionEndpoint() string {
+	return fmt.Sprintf("%s/v1/completions", c.GetEndpoint())
+}
+
+func (c *ChatInstance) GetCompletionBody(props *adaptercommon.CompletionProps) CompletionBody {
+	return CompletionBody{
+		Model:            props.Model,
+		Prompt:           props.Prompt,
+		Suffix:           props.Suffix,
+		Echo:             props.Echo,
+		Stop:             props.Stop,
+		Logprobs:         props.Logprobs,
+		MaxTokens:        props.MaxTokens,
+		Temperature:      props.Temperature,
+		TopP:             props.TopP,
+		PresencePenalty:  props.PresencePenalty,
+		FrequencyPenalty: props.FrequencyPenalty,
+		User:             props.User,
+		Stream:           true,
+		StreamOptions:    utils.Multi[*StreamOptions](adaptercommon.IsStreamOptionsRejected(c.GetEndpoint()), nil, &StreamOptions{IncludeUsage: true}),
+	}
+}
+
+// CreateCompletionRequest is the native http request for oneapi legacy completions api,
+// the request is always streamed and the hook is called with each chunk
+func (c *ChatInstance) CreateCompletionRequest(props *adaptercommon.CompletionProps, hook adaptercommon.CompletionHook) error {
+	buf := ""
+	chunks := 0
+	err := adaptercommon.WithStreamOptions(c.GetEndpoint(), func() error {
+		return utils.EventSource(
+			"POST",
+			c.GetCompletionEndpoint(),
+			c.GetHeader(),
+			c.GetCompletionBody(props),
+			func(data string) error {
+				item := strings.TrimSpace(strings.TrimPrefix(buf+data, "data:"))
+				if item == "" || item == "[DONE]" {
+					buf = ""
+					return nil
+				}
+
+				form := utils.UnmarshalForm[CompletionNativeResponse](item)
+				if form == nil {
+					// error when break line
+					buf = buf + data
+					return nil
+				}
+
+				buf = ""
+				if form.Error.Message != "" {
+					return fmt.Errorf("oneapi error: %s (type: %s)", form.Error.Message, form.Error.Type)
+				}
+
+				if form.Usage != nil {
+					props.Buffer.SetInputTokens(form.Usage.PromptTokens)
+					props.Buffer.SetOutputTokens(form.Usage.CompletionTokens)
+				}
+
+				chunks++
+				return hook(&form.CompletionResponse)
+			},
+		)
+	})
+
+	if err != nil {
+		return err
+	} else if chunks == 0 {
+		return fmt.Errorf("empty response")
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package oneapi
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/utils"
+	"fmt"
+)
+
+// ImagesRequest is the request body for oneapi images generation api
+type ImagesRequest struct {
+	Model          string  `json:"model"`
+	Prompt         string  `json:"prompt"`
+	N              *int    `json:"n,omitempty"`
+	Size           *string `json:"size,omitempty"`
+	Quality        *string `json:"quality,omitempty"`
+	Style          *string `json:"style,omitempty"`
+	ResponseFormat *string `json:"response_format,omitempty"`
+	User           *string `json:"user,omitempty"`
+}
+
+// ImagesResponse is the native http response body for oneapi images api
+type ImagesResponse struct {
+	adaptercommon.ImageResponse
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (c *ChatInstance) GetImagesEndpoint(props *adaptercommon.ImageProps) string {
+	return fmt.Sprintf("%s/v1/images/%s", c.GetEndpoint(), props.GetPath())
+}
+
+func (c *ChatInstance) GetImagesBody(props *adaptercommon.ImageProps) ImagesRequest {
+	return ImagesRequest{
+		Model:          props.Model,
+		Prompt:         props.Prompt,
+		N:              props.N,
+		Size:           props.Size,
+		Quality:        props.Quality,
+		Style:          props.Style,
+		ResponseFormat: props.ResponseFormat,
+		User:           props.User,
+	}
+}
+
+// CreateImagesRequest is the native http request for oneapi images api, the edit and variation requests are sent as the multipart form
+func (c *ChatInstance) CreateImagesRequest(props *adaptercommon.ImageProps) (*adaptercommon.ImageResponse, error) {
+	var res interface{}
+	var err error
+	if props.IsMultipart() {
+		res, err = utils.PostMultipart(c.GetImagesEndpoint(props), c.GetHeader(), props.GetFields(), props.GetFiles())
+	} else {
+		res, err = utils.Post(c.GetImagesEndpoint(props), c.GetHeader(), c.GetImagesBody(props))
+	}
+
+	if err != nil || res == nil {
+		return nil, fmt.Errorf("oneapi error: %s", utils.GetError(err))
+	}
+
+	data := utils.MapToStruct[ImagesResponse](res)
+	if data == nil {
+		return nil, fmt.Errorf("oneapi error: cannot parse response")
+	} else if data.Error.Message != "" {
+		return nil, fmt.Errorf("oneapi error: %s", data.Error.Message)
+	} else if len(data.Data) == 0 {
+		return nil, fmt.Errorf("oneapi error: empty image response")
+	}
+
+	return &data.ImageResponse, nil
+}
@@ -0,0 +1,47 @@
+package oneapi
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/utils"
+	"fmt"
+)
+
+// ModerationRequest is the request body for oneapi moderations
+type ModerationRequest struct {
+	Model string   `json:"model,omitempty"`
+	Input []string `json:"input"`
+}
+
+// ModerationResponse is the native http response body for oneapi moderations
+type ModerationResponse struct {
+	adaptercommon.ModerationResponse
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (c *ChatInstance) GetModerationEndpoint() string {
+	return fmt.Sprintf("%s/v1/moderations", c.GetEndpoint())
+}
+
+// CreateModerationRequest is the native http request for oneapi moderations
+func (c *ChatInstance) CreateModerationRequest(props *adaptercommon.ModerationProps) (*adaptercommon.ModerationResponse, error) {
+	res, err := utils.Post(c.GetModerationEndpoint(), c.GetHeader(), ModerationRequest{
+		Model: props.Model,
+		Input: props.Input,
+	})
+	if err != nil || res == nil {
+		return nil, fmt.Errorf("oneapi error: %s", utils.GetError(err))
+	}
+
+	data := utils.MapToStruct[ModerationResponse](res)
+	if data == nil {
+		return nil, fmt.Errorf("oneapi error: cannot parse response")
+	} else if data.Error.Message != "" {
+		return nil, fmt.Errorf("oneapi error: %s", data.Error.Message)
+	} else if len(data.Results) != len(props.Input) {
+		return nil, fmt.Errorf("oneapi error: unexpected moderation results")
+	}
+
+	return &data.ModerationResponse, nil
+}
@@ -39,6 +39,11 @@ func processChatResponse(data string) *ChatStreamResponse {
 		if form = utils.UnmarshalForm[ChatStreamResponse](data[:len(data)-1]); form != nil {
 			return form
 		}
+
+		// the usage chunk ends with the nested object, so the closing brace is not appended by the formatter
+		if form = utils.UnmarshalForm[ChatStreamResponse](data + "}"); form != nil && form.Data.Usage != nil {
+			return form
+		}
 	}
 
 	return nil
@@ -96,7 +101,7 @@ func getRobustnessResult(chunk string) string {
 	}
 }
 
-func (c *ChatInstance) ProcessLine(obj utils.Buffer, buf, data string) (string, error) {
+func (c *ChatInstance) ProcessLine(obj *utils.Buffer, buf, data string) (string, error) {
 	item := processFormat(buf + data)
 	if isDone(item) {
 		return "", nil
@@ -120,6 +125,11 @@ func (c *ChatInstance) ProcessLine(obj utils.Buffer, buf, data string) (string,
 		}
 
 	} else {
+		if usage := form.Data.Usage; usage != nil {
+			obj.SetInputTokens(usage.PromptTokens)
+			obj.SetOutputTokens(usage.CompletionTokens)
+		}
+
 		obj.SetToolCalls(getToolCalls(form))
 		return getChoices(form), nil
 	}
@@ -0,0 +1,99 @@
+package oneapi
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/globals"
+)
+
+var tokenLimit = adaptercommon.TokenLimit{Default: 2500, Infinity: true}
+
+// provider composes the capabilities of the openai api onto the chat provider
+type provider struct {
+	*adaptercommon.BaseProvider
+	adaptercommon.EmbeddingHandler
+	adaptercommon.ImageHandler
+	adaptercommon.AudioHandler
+	adaptercommon.SpeechHandler
+	adaptercommon.CompletionHandler
+	adaptercommon.ModerationHandler
+	adaptercommon.BalanceHandler
+}
+
+func init() {
+	base := adaptercommon.NewProvider(
+		globals.OneAPIChannelType,
+		[]string{
+			adaptercommon.ParamTemperature,
+			adaptercommon.ParamTopP,
+			adaptercommon.ParamPresencePenalty,
+			adaptercommon.ParamFrequencyPenalty,
+			adaptercommon.ParamTools,
+			adaptercommon.ParamToolChoice,
+			adaptercommon.ParamStop,
+			adaptercommon.ParamSeed,
+			adaptercommon.ParamLogitBias,
+			adaptercommon.ParamResponseFormat,
+		},
+		tokenLimit,
+		createChatRequest,
+	)
+
+	adaptercommon.Register(&provider{
+		BaseProvider:      base,
+		EmbeddingHandler:  createEmbeddingRequest,
+		ImageHandler:      createImageRequest,
+		AudioHandler:      createTranscriptionRequest,
+		SpeechHandler:     createSpeechRequest,
+		CompletionHandler: createCompletionRequest,
+		ModerationHandler: createModerationRequest,
+		BalanceHandler:    getBalance,
+	})
+}
+
+func createChatRequest(conf globals.ChannelConfig, props *adaptercommon.ChatProps, hook globals.Hook) error {
+	return NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&ChatProps{
+		Model:            props.Model,
+		Message:          props.Message,
+		Token:            tokenLimit.GetToken(props),
+		PresencePenalty:  props.PresencePenalty,
+		FrequencyPenalty: props.FrequencyPenalty,
+		Temperature:      props.Temperature,
+		TopP:             props.TopP,
+		Tools:            props.Tools,
+		ToolChoice:       props.ToolChoice,
+		Stop:             props.Stop,
+		Seed:             props.Seed,
+		LogitBias:        props.LogitBias,
+		ResponseFormat:   props.ResponseFormat,
+		User:             props.User,
+		Buffer:           props.Buffer,
+	}, hook)
+}
+
+func createEmbeddingRequest(conf globals.ChannelConfig, props *adaptercommon.EmbeddingProps) (*adaptercommon.EmbeddingResponse, error) {
+	return NewChatInstanceFromConfig(conf).CreateEmbeddingRequest(props)
+}
+
+func createImageRequest(conf globals.ChannelConfig, props *adaptercommon.ImageProps) (*adaptercommon.ImageResponse, error) {
+	return NewChatInstanceFromConfig(conf).CreateImagesRequest(props)
+}
+
+func createTranscriptionRequest(conf globals.ChannelConfig, props *adaptercommon.AudioProps) (*adaptercommon.AudioResponse, error) {
+	return NewChatInstanceFromConfig(conf).CreateTranscriptionRequest(props)
+}
+
+func createSpeechRequest(conf globals.ChannelConfig, props *adaptercommon.SpeechProps) (*adaptercommon.SpeechResponse, error) {
+	return NewChatInstanceFromConfig(conf).CreateSpeechRequest(props)
+}
+
+func createCompletionRequest(conf globals.ChannelConfig, props *adaptercommon.CompletionProps, hook adaptercommon.CompletionHook) error {
+	return NewChatInstanceFromConfig(conf).CreateCompletionRequest(props, hook)
+}
+
+func createModerationRequest(conf globals.ChannelConfig, props *adaptercommon.ModerationProps) (*adaptercommon.ModerationResponse, error) {
+	return NewChatInstanceFromConfig(conf).CreateModerationRequest(props)
+}
+
+func getBalance(conf globals.ChannelConfig) (float64, error) {
+	return NewChatInstanceFromConfig(conf).GetBalance()
+}
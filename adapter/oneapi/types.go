@@ -14,6 +14,23 @@ type ChatRequest struct {
 	TopP             *float32               `json:"top_p,omitempty"`
 	Tools            *globals.FunctionTools `json:"tools,omitempty"`
 	ToolChoice       *interface{}           `json:"tool_choice,omitempty"` // string or object
+	Stop             []string               `json:"stop,omitempty"`
+	Seed             *int                   `json:"seed,omitempty"`
+	LogitBias        *map[string]float32    `json:"logit_bias,omitempty"`
+	ResponseFormat   *interface{}           `json:"response_format,omitempty"`
+	User             *string                `json:"user,omitempty"`
+	StreamOptions    *StreamOptions         `json:"stream_options,omitempty"`
+}
+
+// StreamOptions asks the upstream to send the usage chunk at the end of the stream
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
 }
 
 // ChatResponse is the native http request body for oneapi
@@ -44,6 +61,7 @@ type ChatStreamResponse struct {
 			Index        int             `json:"index"`
 			FinishReason string          `json:"finish_reason"`
 		} `json:"choices"`
+		Usage *Usage `json:"usage,omitempty"` // only the last chunk if the usage is included
 	} `json:"data"`
 }
 
@@ -4,6 +4,7 @@ import (
 	"chat/globals"
 	"chat/utils"
 	"fmt"
+	"strings"
 )
 
 var geminiMaxImages = 16
@@ -15,6 +16,10 @@ type ChatProps struct {
 	TopP            *float64
 	TopK            *int
 	MaxOutputTokens *int
+	Tools           *globals.FunctionTools
+	ToolChoice      *interface{}
+	StopSequences   []string
+	Buffer          *utils.Buffer
 }
 
 func (c *ChatInstance) GetChatEndpoint(model string) string {
@@ -25,6 +30,10 @@ func (c *ChatInstance) GetChatEndpoint(model string) string {
 	return fmt.Sprintf("%s/v1beta/models/%s:generateContent?key=%s", c.Endpoint, model, c.ApiKey)
 }
 
+func (c *ChatInstance) GetStreamChatEndpoint(model string) string {
+	return fmt.Sprintf("%s/v1beta/models/%s:streamGenerateContent?alt=sse&key=%s", c.Endpoint, model, c.ApiKey)
+}
+
 func (c *ChatInstance) ConvertMessage(message []globals.Message) []PalmMessage {
 	var result []PalmMessage
 	for i, item := range message {
@@ -61,13 +70,16 @@ func (c *ChatInstance) GetPalm2ChatBody(props *ChatProps) *PalmChatBody {
 
 func (c *ChatInstance) GetGeminiChatBody(props *ChatProps) *GeminiChatBody {
 	return &GeminiChatBody{
-		Contents: c.GetGeminiContents(props.Model, props.Message),
+		Contents: c.GetGeminiContents(props.Message),
 		GenerationConfig: GeminiConfig{
 			Temperature:     props.Temperature,
 			MaxOutputTokens: props.MaxOutputTokens,
 			TopP:            props.TopP,
 			TopK:            props.TopK,
+			StopSequences:   props.StopSequences,
 		},
+		Tools:      getGeminiTools(props.Tools),
+		ToolConfig: getGeminiToolConfig(props.ToolChoice),
 	}
 }
 
@@ -81,17 +93,111 @@ func (c *ChatInstance) GetPalm2ChatResponse(data interface{}) (string, error) {
 	return "", fmt.Errorf("palm2 error: cannot parse response")
 }
 
-func (c *ChatInstance) GetGeminiChatResponse(data interface{}) (string, error) {
-	if form := utils.MapToStruct[GeminiChatResponse](data); form != nil {
-		if len(form.Candidates) != 0 && len(form.Candidates[0].Content.Parts) != 0 {
-			return form.Candidates[0].Content.Parts[0].Text, nil
+// getSafetyCategories returns the readable categories which are blocked or rated as medium / high probability
+func getSafetyCategories(ratings []GeminiSafetyRating) string {
+	categories := make([]string, 0)
+	for _, rating := range ratings {
+		if rating.Blocked || rating.Probability == "MEDIUM" || rating.Probability == "HIGH" {
+			category := strings.TrimPrefix(rating.Category, "HARM_CATEGORY_")
+			categories = append(categories, strings.ToLower(strings.ReplaceAll(category, "_", " ")))
 		}
 	}
 
-	if form := utils.MapToStruct[GeminiChatErrorResponse](data); form != nil {
+	if len(categories) == 0 {
+		return "unknown"
+	}
+	return strings.Join(categories, ", ")
+}
+
+// getPromptFeedbackError maps the blocked prompt feedback to a readable error
+func getPromptFeedbackError(feedback *GeminiPromptFeedback) error {
+	if feedback == nil || feedback.BlockReason == "" {
+		return nil
+	}
+
+	switch feedback.BlockReason {
+	case "SAFETY":
+		return fmt.Errorf("gemini error: the prompt was blocked by safety filters (categories: %s)", getSafetyCategories(feedback.SafetyRatings))
+	default:
+		return fmt.Errorf("gemini error: the prompt was blocked (reason: %s)", strings.ToLower(feedback.BlockReason))
+	}
+}
+
+// getFinishReasonError maps the abnormal finish reason of the candidate to a readable error
+func getFinishReasonError(candidate GeminiCandidate) error {
+	switch candidate.FinishReason {
+	case "", "STOP", "MAX_TOKENS", "FINISH_REASON_UNSPECIFIED":
+		return nil
+	case "SAFETY":
+		return fmt.Errorf("gemini error: the response was blocked by safety filters (categories: %s)", getSafetyCategories(candidate.SafetyRatings))
+	case "RECITATION":
+		return fmt.Errorf("gemini error: the response was blocked for reciting copyrighted material")
+	default:
+		return fmt.Errorf("gemini error: the response was stopped (reason: %s)", strings.ToLower(candidate.FinishReason))
+	}
+}
+
+// getGeminiCandidate returns the text and function calls of the first candidate
+func getGeminiCandidate(form *GeminiChatResponse) (string, globals.ToolCalls, error) {
+	if err := getPromptFeedbackError(form.PromptFeedback); err != nil {
+		return "", nil, err
+	}
+
+	if len(form.Candidates) == 0 {
+		return "", nil, nil
+	}
+
+	result := ""
+	calls := make(globals.ToolCalls, 0)
+	for _, part := range form.Candidates[0].Content.Parts {
+		result += part.Text
+
+		if part.FunctionCall != nil {
+			calls = append(calls, globals.ToolCall{
+				Type: "function",
+				Id:   globals.ToolCallId(fmt.Sprintf("call_%s", utils.GenerateChar(24))),
+				Function: globals.ToolCallFunction{
+					Name:      part.FunctionCall.Name,
+					Arguments: utils.Marshal(part.FunctionCall.Args),
+				},
+			})
+		}
+	}
+
+	return result, calls, getFinishReasonError(form.Candidates[0])
+}
+
+func setGeminiUsage(form *GeminiChatResponse, buffer *utils.Buffer) {
+	if form.UsageMetadata == nil {
+		return
+	}
+
+	buffer.SetInputTokens(form.UsageMetadata.PromptTokenCount)
+	buffer.SetOutputTokens(form.UsageMetadata.CandidatesTokenCount)
+}
+
+func (c *ChatInstance) GetGeminiChatResponse(data interface{}, buffer *utils.Buffer) (string, error) {
+	if form := utils.MapToStruct[GeminiChatErrorResponse](data); form != nil && form.Error.Code != 0 {
 		return "", fmt.Errorf("gemini error: %s (code: %d, status: %s)", form.Error.Message, form.Error.Code, form.Error.Status)
 	}
 
+	if form := utils.MapToStruct[GeminiChatResponse](data); form != nil {
+		result, calls, err := getGeminiCandidate(form)
+		if err != nil {
+			return "", err
+		}
+
+		if len(result) == 0 && len(calls) == 0 {
+			return "", fmt.Errorf("gemini error: empty response")
+		}
+
+		if len(calls) > 0 {
+			buffer.SetToolCalls(&calls)
+		}
+		setGeminiUsage(form, buffer)
+		return result, nil
+	}
+
 	return "", fmt.Errorf("gemini: cannot parse response")
 }
 
@@ -117,21 +223,92 @@ func (c *ChatInstance) CreateChatRequest(props *ChatProps) (string, error) {
 		return "", fmt.Errorf("gemini error: %s", err.Error())
 	}
 
-	return c.GetGeminiChatResponse(data)
+	return c.GetGeminiChatResponse(data, props.Buffer)
 }
 
-// CreateStreamChatRequest is the mock stream request for palm2
-// tips: palm2 does not support stream request
+// CreateStreamChatRequest is the stream request for gemini
+// tips: palm2 (chat-bison-001) does not support stream request, the response will be mocked as stream
 func (c *ChatInstance) CreateStreamChatRequest(props *ChatProps, callback globals.Hook) error {
-	response, err := c.CreateChatRequest(props)
+	if props.Model == globals.ChatBison001 {
+		response, err := c.CreateChatRequest(props)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range utils.SplitItem(response, " ") {
+			if err := callback(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return c.CreateGeminiStreamChatRequest(props, callback)
+}
+
+// CreateGeminiStreamChatRequest is the sse stream request for gemini (streamGenerateContent with alt=sse)
+func (c *ChatInstance) CreateGeminiStreamChatRequest(props *ChatProps, callback globals.Hook) error {
+	// response example:
+	//
+	// data: {"candidates": [{"content": {"parts": [{"text": "Hello"}],"role": "model"},"index": 0}],"usageMetadata": {...}}
+	// data: {"candidates": [{"content": {"parts": [{"text": "!"}],"role": "model"},"finishReason": "STOP","index": 0}]}
+
+	buf := ""
+	empty := true
+	calls := make(globals.ToolCalls, 0)
+
+	err := utils.EventSource(
+		"POST",
+		c.GetStreamChatEndpoint(props.Model),
+		map[string]string{
+			"Content-Type": "application/json",
+		},
+		c.GetGeminiChatBody(props),
+		func(data string) error {
+			// the line may be cut off by the chunk, wait for the rest of it
+			if strings.HasPrefix(data, "data:") {
+				if len(buf) > 0 {
+					globals.Warn(fmt.Sprintf("gemini error: cannot parse response: %s", buf))
+				}
+				buf = data
+			} else {
+				buf += data
+			}
+
+			if !strings.HasPrefix(buf, "data:") {
+				return nil
+			}
+
+			form := utils.UnmarshalForm[GeminiChatResponse](strings.TrimSpace(strings.TrimPrefix(buf, "data:")))
+			if form == nil {
+				return nil
+			}
+
+			buf = ""
+			setGeminiUsage(form, props.Buffer)
+
+			result, chunk, err := getGeminiCandidate(form)
+			calls = append(calls, chunk...)
+			if len(result) > 0 {
+				empty = false
+				if err := callback(result); err != nil {
+					return err
+				}
+			}
+
+			return err
+		},
+	)
+
 	if err != nil {
 		return err
 	}
 
-	for _, item := range utils.SplitItem(response, " ") {
-		if err := callback(item); err != nil {
-			return err
-		}
+	if len(calls) > 0 {
+		props.Buffer.SetToolCalls(&calls)
+	} else if empty {
+		return fmt.Errorf("gemini error: empty response")
 	}
+
 	return nil
 }
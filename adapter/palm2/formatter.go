@@ -3,69 +3,45 @@ package palm2
 import (
 	"chat/globals"
 	"chat/utils"
-	"strings"
 )
 
 func getGeminiRole(role string) string {
 	switch role {
 	case globals.User:
 		return GeminiUserType
-	case globals.Assistant, globals.Tool, globals.System:
+	case globals.Tool:
+		return GeminiFunctionType
+	case globals.Assistant, globals.System:
 		return GeminiModelType
 	default:
 		return GeminiUserType
 	}
 }
 
-func getMimeType(content string) string {
-	segment := strings.Split(content, ".")
-	if len(segment) == 0 || len(segment) == 1 {
-		return "image/png"
-	}
-
-	suffix := strings.TrimSpace(strings.ToLower(segment[len(segment)-1]))
-
-	switch suffix {
-	case "png":
-		return "image/png"
-	case "jpg", "jpeg":
-		return "image/jpeg"
-	case "gif":
-		return "image/gif"
-	case "webp":
-		return "image/webp"
-	case "heif":
-		return "image/heif"
-	case "heic":
-		return "image/heic"
-	default:
-		return "image/png"
-	}
-}
-
-func getGeminiContent(parts []GeminiChatPart, content string, model string) []GeminiChatPart {
-	parts = append(parts, GeminiChatPart{
-		Text: &content,
-	})
-
-	if model == globals.GeminiPro {
-		return parts
-	}
+// getGeminiContent converts the typed parts of the message to the gemini parts, images are sent as inline data
+func getGeminiContent(parts []GeminiChatPart, contents globals.MessageContents) []GeminiChatPart {
+	images := 0
+	for _, content := range contents {
+		if !content.IsImage() {
+			parts = append(parts, GeminiChatPart{
+				Text: utils.ToPtr(content.GetText()),
+			})
+			continue
+		}
 
-	urls := utils.ExtractImageUrls(content)
-	if len(urls) > geminiMaxImages {
-		urls = urls[:geminiMaxImages]
-	}
+		if images >= geminiMaxImages {
+			continue
+		}
 
-	for _, url := range urls {
-		data, err := utils.ConvertToBase64(url)
+		mimeType, data, err := utils.GetImageData(content)
 		if err != nil {
 			continue
 		}
 
+		images++
 		parts = append(parts, GeminiChatPart{
 			InlineData: &GeminiInlineData{
-				MimeType: getMimeType(url),
+				MimeType: mimeType,
 				Data:     data,
 			},
 		})
@@ -74,13 +50,83 @@ func getGeminiContent(parts []GeminiChatPart, content string, model string) []Ge
 	return parts
 }
 
-func (c *ChatInstance) GetGeminiContents(model string, message []globals.Message) []GeminiContent {
+func getFunctionCallParts(parts []GeminiChatPart, calls *globals.ToolCalls) []GeminiChatPart {
+	if calls == nil {
+		return parts
+	}
+
+	for _, call := range *calls {
+		var args interface{} = map[string]interface{}{}
+		if form := utils.UnmarshalForm[map[string]interface{}](call.Function.Arguments); form != nil {
+			args = *form
+		}
+
+		parts = append(parts, GeminiChatPart{
+			FunctionCall: &GeminiFunctionCall{
+				Name: call.Function.Name,
+				Args: args,
+			},
+		})
+	}
+	return parts
+}
+
+// getFunctionName returns the function name of the tool call id, since gemini identifies the function response by name
+func getFunctionName(message []globals.Message, id *string) string {
+	if id == nil {
+		return ""
+	}
+
+	for _, item := range message {
+		if item.ToolCalls == nil {
+			continue
+		}
+
+		for _, call := range *item.ToolCalls {
+			if string(call.Id) == *id {
+				return call.Function.Name
+			}
+		}
+	}
+	return ""
+}
+
+func getFunctionResponsePart(message []globals.Message, item globals.Message) GeminiChatPart {
+	// gemini model: function response must be an object
+	var response interface{} = map[string]interface{}{"content": item.Content}
+	if form := utils.UnmarshalForm[map[string]interface{}](item.Content); form != nil {
+		response = *form
+	}
+
+	return GeminiChatPart{
+		FunctionResponse: &GeminiFunctionResponse{
+			Name:     getFunctionName(message, item.ToolCallId),
+			Response: response,
+		},
+	}
+}
+
+func (c *ChatInstance) getGeminiParts(parts []GeminiChatPart, message []globals.Message, item globals.Message) []GeminiChatPart {
+	switch item.Role {
+	case globals.Tool:
+		return append(parts, getFunctionResponsePart(message, item))
+	case globals.Assistant:
+		if len(item.Content) > 0 {
+			parts = append(parts, GeminiChatPart{Text: utils.ToPtr(item.Content)})
+		}
+		return getFunctionCallParts(parts, item.ToolCalls)
+	default:
+		return getGeminiContent(parts, item.GetContents())
+	}
+}
+
+func (c *ChatInstance) GetGeminiContents(message []globals.Message) []GeminiContent {
 	// gemini role should be user-model
 
 	result := make([]GeminiContent, 0)
 	for _, item := range message {
 		role := getGeminiRole(item.Role)
-		if len(item.Content) == 0 {
+		if len(item.Content) == 0 && len(item.Contents) == 0 && item.ToolCalls == nil && item.Role != globals.Tool {
 			// gemini model: message must include non empty content
 			continue
 		}
@@ -90,21 +136,74 @@ func (c *ChatInstance) GetGeminiContents(model string, message []globals.Message
 
 			result = append(result, GeminiContent{
 				Role:  GeminiUserType,
-				Parts: getGeminiContent(make([]GeminiChatPart, 0), "", model),
+				Parts: []GeminiChatPart{{Text: utils.ToPtr("")}},
 			})
 		}
 
 		if len(result) > 0 && role == result[len(result)-1].Role {
 			// gemini model: messages must alternate between authors
-			result[len(result)-1].Parts = getGeminiContent(result[len(result)-1].Parts, item.Content, model)
+			result[len(result)-1].Parts = c.getGeminiParts(result[len(result)-1].Parts, message, item)
 			continue
 		}
 
 		result = append(result, GeminiContent{
-			Role:  getGeminiRole(item.Role),
-			Parts: getGeminiContent(make([]GeminiChatPart, 0), item.Content, model),
+			Role:  role,
+			Parts: c.getGeminiParts(make([]GeminiChatPart, 0), message, item),
 		})
 	}
 
 	return result
 }
+
+func getGeminiTools(tools *globals.FunctionTools) []GeminiTool {
+	if tools == nil || len(*tools) == 0 {
+		return nil
+	}
+
+	return []GeminiTool{
+		{
+			FunctionDeclarations: utils.Each[globals.ToolObject, GeminiFunctionDeclaration](*tools, func(tool globals.ToolObject) GeminiFunctionDeclaration {
+				declaration := GeminiFunctionDeclaration{
+					Name:        tool.Function.Name,
+					Description: tool.Function.Description,
+				}
+
+				if len(tool.Function.Parameters.Properties) > 0 {
+					// gemini model: object parameters must include non empty properties
+					declaration.Parameters = &tool.Function.Parameters
+				}
+				return declaration
+			}),
+		},
+	}
+}
+
+// getGeminiToolConfig converts the openai tool choice (string or object) to the gemini function calling config
+func getGeminiToolConfig(choice *interface{}) *GeminiToolConfig {
+	if choice == nil {
+		return nil
+	}
+
+	switch value := (*choice).(type) {
+	case string:
+		switch value {
+		case "auto":
+			return &GeminiToolConfig{FunctionCallingConfig: GeminiFunctionCallingConfig{Mode: "AUTO"}}
+		case "none":
+			return &GeminiToolConfig{FunctionCallingConfig: GeminiFunctionCallingConfig{Mode: "NONE"}}
+		case "required":
+			return &GeminiToolConfig{FunctionCallingConfig: GeminiFunctionCallingConfig{Mode: "ANY"}}
+		}
+	case map[string]interface{}:
+		if function, ok := value["function"].(map[string]interface{}); ok {
+			if name, ok := function["name"].(string); ok && len(name) > 0 {
+				return &GeminiToolConfig{FunctionCallingConfig: GeminiFunctionCallingConfig{
+					Mode:                 "ANY",
+					AllowedFunctionNames: []string{name},
+				}}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package palm2
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/globals"
+	"chat/utils"
+)
+
+var tokenLimit = adaptercommon.TokenLimit{}
+
+func init() {
+	adaptercommon.Register(adaptercommon.NewProvider(
+		globals.PalmChannelType,
+		[]string{
+			adaptercommon.ParamTemperature,
+			adaptercommon.ParamTopP,
+			adaptercommon.ParamTopK,
+			adaptercommon.ParamTools,
+			adaptercommon.ParamToolChoice,
+			adaptercommon.ParamImages,
+			adaptercommon.ParamStop,
+		},
+		tokenLimit,
+		createChatRequest,
+	).WithSupport(supportParam))
+}
+
+// supportParam returns whether the model supports the parameter, function calling is only supported by gemini text models,
+// vision input is not supported by palm2 and gemini-pro and stop sequences are only sent to gemini
+func supportParam(model string, param string) bool {
+	switch param {
+	case adaptercommon.ParamStop:
+		return model != globals.ChatBison001
+	case adaptercommon.ParamTools, adaptercommon.ParamToolChoice:
+		return model != globals.ChatBison001 && model != globals.GeminiProVision
+	case adaptercommon.ParamImages:
+		return model != globals.ChatBison001 && model != globals.GeminiPro
+	default:
+		return true
+	}
+}
+
+func toFloat64(value *float32) *float64 {
+	if value == nil {
+		return nil
+	}
+	return utils.ToPtr(float64(*value))
+}
+
+func createChatRequest(conf globals.ChannelConfig, props *adaptercommon.ChatProps, hook globals.Hook) error {
+	return NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&ChatProps{
+		Model:           props.Model,
+		Message:         props.Message,
+		Temperature:     toFloat64(props.Temperature),
+		TopP:            toFloat64(props.TopP),
+		TopK:            props.TopK,
+		MaxOutputTokens: tokenLimit.GetToken(props),
+		Tools:           props.Tools,
+		ToolChoice:      props.ToolChoice,
+		StopSequences:   props.Stop,
+		Buffer:          props.Buffer,
+	}, hook)
+}
@@ -1,8 +1,11 @@
 package palm2
 
+import "chat/globals"
+
 const (
-	GeminiUserType  = "user"
-	GeminiModelType = "model"
+	GeminiUserType     = "user"
+	GeminiModelType    = "model"
+	GeminiFunctionType = "function"
 )
 
 type PalmMessage struct {
@@ -26,8 +29,29 @@ type PalmChatResponse struct {
 
 // GeminiChatBody is the native http request body for gemini
 type GeminiChatBody struct {
-	Contents         []GeminiContent `json:"contents"`
-	GenerationConfig GeminiConfig    `json:"generationConfig"`
+	Contents         []GeminiContent   `json:"contents"`
+	GenerationConfig GeminiConfig      `json:"generationConfig"`
+	Tools            []GeminiTool      `json:"tools,omitempty"`
+	ToolConfig       *GeminiToolConfig `json:"toolConfig,omitempty"`
+}
+
+type GeminiTool struct {
+	FunctionDeclarations []GeminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type GeminiFunctionDeclaration struct {
+	Name        string                  `json:"name"`
+	Description string                  `json:"description"`
+	Parameters  *globals.ToolParameters `json:"parameters,omitempty"`
+}
+
+type GeminiToolConfig struct {
+	FunctionCallingConfig GeminiFunctionCallingConfig `json:"functionCallingConfig"`
+}
+
+type GeminiFunctionCallingConfig struct {
+	Mode                 string   `json:"mode"` // AUTO, ANY or NONE
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
 }
 
 type GeminiConfig struct {
@@ -35,6 +59,7 @@ type GeminiConfig struct {
 	MaxOutputTokens *int     `json:"maxOutputTokens,omitempty"`
 	TopP            *float64 `json:"topP,omitempty"`
 	TopK            *int     `json:"topK,omitempty"`
+	StopSequences   []string `json:"stopSequences,omitempty"`
 }
 
 type GeminiContent struct {
@@ -43,8 +68,20 @@ type GeminiContent struct {
 }
 
 type GeminiChatPart struct {
-	Text       *string           `json:"text,omitempty"`
-	InlineData *GeminiInlineData `json:"inline_data,omitempty"`
+	Text             *string                 `json:"text,omitempty"`
+	InlineData       *GeminiInlineData       `json:"inline_data,omitempty"`
+	FunctionCall     *GeminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *GeminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type GeminiFunctionCall struct {
+	Name string      `json:"name"`
+	Args interface{} `json:"args"`
+}
+
+type GeminiFunctionResponse struct {
+	Name     string      `json:"name"`
+	Response interface{} `json:"response"`
 }
 
 type GeminiInlineData struct {
@@ -52,15 +89,40 @@ type GeminiInlineData struct {
 	Data     string `json:"data"`
 }
 
+type GeminiSafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked"`
+}
+
+type GeminiCandidate struct {
+	Content struct {
+		Parts []struct {
+			Text         string              `json:"text"`
+			FunctionCall *GeminiFunctionCall `json:"functionCall"`
+		} `json:"parts"`
+		Role string `json:"role"`
+	} `json:"content"`
+	FinishReason  string               `json:"finishReason"`
+	SafetyRatings []GeminiSafetyRating `json:"safetyRatings"`
+}
+
+type GeminiPromptFeedback struct {
+	BlockReason   string               `json:"blockReason"`
+	SafetyRatings []GeminiSafetyRating `json:"safetyRatings"`
+}
+
+type GeminiUsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+// GeminiChatResponse is the native http response body for gemini, also used as the stream chunk of streamGenerateContent
 type GeminiChatResponse struct {
-	Candidates []struct {
-		Content struct {
-			Parts []struct {
-				Text string `json:"text"`
-			} `json:"parts"`
-			Role string `json:"role"`
-		} `json:"content"`
-	} `json:"candidates"`
+	Candidates     []GeminiCandidate     `json:"candidates"`
+	PromptFeedback *GeminiPromptFeedback `json:"promptFeedback,omitempty"`
+	UsageMetadata  *GeminiUsageMetadata  `json:"usageMetadata,omitempty"`
 }
 
 type GeminiChatErrorResponse struct {
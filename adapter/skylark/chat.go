@@ -20,7 +20,7 @@ type ChatProps struct {
 	TopP             *float32
 	TopK             *int
 	Tools            *globals.FunctionTools
-	Buffer           utils.Buffer
+	Buffer           *utils.Buffer
 }
 
 func getMessages(messages []globals.Message) []*api.Message {
@@ -56,12 +56,14 @@ func (c *ChatInstance) CreateRequest(props *ChatProps) *api.ChatReq {
 	}
 }
 
-func getChoice(choice *api.ChatResp, buffer utils.Buffer) string {
-	if choice == nil {
+func getChoice(choice *api.ChatResp, buffer *utils.Buffer) string {
+	// the last chunk may only carry the usage without the choice
+	message := choice.GetChoice().GetMessage()
+	if message == nil {
 		return ""
 	}
 
-	calls := choice.Choice.Message.FunctionCall
+	calls := message.GetFunctionCall()
 	if calls != nil {
 		buffer.SetToolCalls(&globals.ToolCalls{
 			globals.ToolCall{
@@ -74,7 +76,7 @@ func getChoice(choice *api.ChatResp, buffer utils.Buffer) string {
 			},
 		})
 	}
-	return choice.Choice.Message.Content
+	return message.GetContent()
 }
 
 func (c *ChatInstance) CreateStreamChatRequest(props *ChatProps, callback globals.Hook) error {
@@ -89,6 +91,11 @@ func (c *ChatInstance) CreateStreamChatRequest(props *ChatProps, callback global
 			return partial.Error
 		}
 
+		if usage := partial.GetUsage(); usage != nil {
+			props.Buffer.SetInputTokens(int(usage.GetPromptTokens()))
+			props.Buffer.SetOutputTokens(int(usage.GetCompletionTokens()))
+		}
+
 		if err := callback(getChoice(partial, props.Buffer)); err != nil {
 			return err
 		}
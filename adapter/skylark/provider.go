@@ -0,0 +1,41 @@
+package skylark
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/globals"
+)
+
+var tokenLimit = adaptercommon.TokenLimit{Default: 4096}
+
+func init() {
+	adaptercommon.Register(adaptercommon.NewProvider(
+		globals.SkylarkChannelType,
+		[]string{
+			adaptercommon.ParamTemperature,
+			adaptercommon.ParamTopP,
+			adaptercommon.ParamTopK,
+			adaptercommon.ParamPresencePenalty,
+			adaptercommon.ParamFrequencyPenalty,
+			adaptercommon.ParamRepetitionPenalty,
+			adaptercommon.ParamTools,
+		},
+		tokenLimit,
+		createChatRequest,
+	))
+}
+
+func createChatRequest(conf globals.ChannelConfig, props *adaptercommon.ChatProps, hook globals.Hook) error {
+	return NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&ChatProps{
+		Model:            props.Model,
+		Message:          props.Message,
+		Token:            tokenLimit.GetTokenValue(props),
+		TopP:             props.TopP,
+		TopK:             props.TopK,
+		Temperature:      props.Temperature,
+		FrequencyPenalty: props.FrequencyPenalty,
+		PresencePenalty:  props.PresencePenalty,
+		RepeatPenalty:    props.RepetitionPenalty,
+		Tools:            props.Tools,
+		Buffer:           props.Buffer,
+	}, hook)
+}
@@ -7,6 +7,7 @@ import (
 )
 
 const (
+	defaultScheme = "https"
 	defaultHost   = "maas-api.ml-platform-cn-beijing.volces.com"
 	defaultRegion = "cn-beijing"
 )
@@ -15,6 +16,15 @@ type ChatInstance struct {
 	Instance *maas.MaaS
 }
 
+func getScheme(endpoint string) string {
+	seg := strings.Split(endpoint, "://")
+	if len(seg) > 1 && seg[0] != "" {
+		return seg[0]
+	}
+
+	return defaultScheme
+}
+
 func getHost(endpoint string) string {
 	seg := strings.Split(endpoint, "://")
 	if len(seg) > 1 && seg[1] != "" {
@@ -36,6 +46,7 @@ func getRegion(endpoint string) string {
 
 func NewChatInstance(endpoint, accessKey, secretKey string) *ChatInstance {
 	instance := maas.NewInstance(getHost(endpoint), getRegion(endpoint))
+	instance.SetScheme(getScheme(endpoint))
 	instance.SetAccessKey(accessKey)
 	instance.SetSecretKey(secretKey)
 	return &ChatInstance{
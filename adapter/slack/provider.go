@@ -0,0 +1,21 @@
+package slack
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/globals"
+)
+
+func init() {
+	adaptercommon.Register(adaptercommon.NewProvider(
+		globals.SlackChannelType,
+		[]string{},
+		adaptercommon.TokenLimit{},
+		createChatRequest,
+	))
+}
+
+func createChatRequest(conf globals.ChannelConfig, props *adaptercommon.ChatProps, hook globals.Hook) error {
+	return NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&ChatProps{
+		Message: props.Message,
+	}, hook)
+}
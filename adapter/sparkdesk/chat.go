@@ -13,7 +13,7 @@ type ChatProps struct {
 	Temperature *float32
 	TopK        *int
 	Tools       *globals.FunctionTools
-	Buffer      utils.Buffer
+	Buffer      *utils.Buffer
 }
 
 func GetToken(props *ChatProps) *int {
@@ -66,7 +66,7 @@ func (c *ChatInstance) GetFunctionCalling(props *ChatProps) *FunctionsPayload {
 	}
 }
 
-func getChoice(form *ChatResponse, buffer utils.Buffer) string {
+func getChoice(form *ChatResponse, buffer *utils.Buffer) string {
 	resp := form.Payload.Choices.Text
 	if len(resp) == 0 {
 		return ""
@@ -107,8 +107,10 @@ func (c *ChatInstance) CreateStreamChatRequest(props *ChatProps, hook globals.Ho
 		},
 		Parameter: RequestParameter{
 			Chat: ChatParameter{
-				Domain:   c.Model,
-				MaxToken: GetToken(props),
+				Domain:      c.Model,
+				MaxToken:    GetToken(props),
+				Temperature: props.Temperature,
+				TopK:        props.TopK,
 			},
 		},
 	}); err != nil {
@@ -125,6 +127,11 @@ func (c *ChatInstance) CreateStreamChatRequest(props *ChatProps, hook globals.Ho
 			return fmt.Errorf("sparkdesk error: %s (sid: %s)", form.Header.Message, form.Header.Sid)
 		}
 
+		// the usage is only sent with the last frame
+		usage := form.Payload.Usage.Text
+		props.Buffer.SetInputTokens(usage.PromptTokens)
+		props.Buffer.SetOutputTokens(usage.CompletionTokens)
+
 		if err := hook(getChoice(form, props.Buffer)); err != nil {
 			return err
 		}
@@ -0,0 +1,33 @@
+package sparkdesk
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/globals"
+)
+
+var tokenLimit = adaptercommon.TokenLimit{}
+
+func init() {
+	adaptercommon.Register(adaptercommon.NewProvider(
+		globals.SparkdeskChannelType,
+		[]string{
+			adaptercommon.ParamTemperature,
+			adaptercommon.ParamTopK,
+			adaptercommon.ParamTools,
+		},
+		tokenLimit,
+		createChatRequest,
+	))
+}
+
+func createChatRequest(conf globals.ChannelConfig, props *adaptercommon.ChatProps, hook globals.Hook) error {
+	return NewChatInstance(conf, props.Model).CreateStreamChatRequest(&ChatProps{
+		Model:       props.Model,
+		Message:     props.Message,
+		Token:       tokenLimit.GetToken(props),
+		Temperature: props.Temperature,
+		TopK:        props.TopK,
+		Tools:       props.Tools,
+		Buffer:      props.Buffer,
+	}, hook)
+}
@@ -0,0 +1,253 @@
+package adapter_test
+
+import (
+	"bytes"
+	"chat/adapter"
+	"chat/adapter/midjourney"
+	"chat/connection/redistest"
+	"chat/globals"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// upstream starts the fake upstream of the case and returns its base url
+type upstream func(t *testing.T) string
+
+// readFixture reads the recorded upstream response from the testdata directory
+func readFixture(t *testing.T, name string) []byte {
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("cannot read fixture %s: %s", name, err)
+	}
+	return data
+}
+
+// readLines reads the non-empty lines of the fixture, one message per line
+func readLines(t *testing.T, name string) []string {
+	var lines []string
+	for _, line := range strings.Split(string(readFixture(t, name)), "\n") {
+		if line = strings.TrimSpace(line); len(line) > 0 {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func newServer(t *testing.T, handler http.Handler) *httptest.Server {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// matchPath rejects the request if it is not sent to the path of the protocol
+func matchPath(t *testing.T, w http.ResponseWriter, r *http.Request, path string) bool {
+	if r.URL.Path != path {
+		t.Errorf("unexpected request %s %s, want path %s", r.Method, r.URL.Path, path)
+		http.NotFound(w, r)
+		return false
+	}
+	return true
+}
+
+// sse replays the fixture as the event stream, the events are flushed one line at a time like the real upstream
+func sse(path string, fixture string) upstream {
+	return func(t *testing.T) string {
+		data := readFixture(t, fixture)
+
+		return newServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !matchPath(t, w, r, path) {
+				return
+			}
+
+			writeEvents(w, data)
+		})).URL
+	}
+}
+
+// ndjson replays the fixture as the newline delimited json stream, one object flushed at a time like ollama
+func ndjson(path string, fixture string) upstream {
+	return func(t *testing.T) string {
+		data := readFixture(t, fixture)
+
+		return newServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !matchPath(t, w, r, path) {
+				return
+			}
+
+			writeLines(w, "application/x-ndjson", data)
+		})).URL
+	}
+}
+
+func writeEvents(w http.ResponseWriter, data []byte) {
+	writeLines(w, "text/event-stream", data)
+}
+
+func writeLines(w http.ResponseWriter, contentType string, data []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		_, _ = w.Write(line)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// reply responds the fixture as the json body with the status code, e.g. the error response before the stream starts
+func reply(path string, status int, fixture string) upstream {
+	return func(t *testing.T) string {
+		data := readFixture(t, fixture)
+
+		return newServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !matchPath(t, w, r, path) {
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			_, _ = w.Write(data)
+		})).URL
+	}
+}
+
+// legacy replays the fixture like sse, but rejects the request with `stream_options` like the older openai compatible upstreams
+func legacy(path string, fixture string) upstream {
+	return func(t *testing.T) string {
+		data := readFixture(t, fixture)
+
+		return newServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !matchPath(t, w, r, path) {
+				return
+			}
+
+			if body, _ := io.ReadAll(r.Body); bytes.Contains(body, []byte("stream_options")) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":{"message":"Unrecognized request argument supplied: stream_options","type":"invalid_request_error"}}`))
+				return
+			}
+			writeEvents(w, data)
+		})).URL
+	}
+}
+
+// ws replays the fixture as the websocket messages after the request frame is received, then closes the connection
+func ws(path string, fixture string) upstream {
+	return func(t *testing.T) string {
+		lines := readLines(t, fixture)
+		upgrader := websocket.Upgrader{}
+
+		server := newServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !matchPath(t, w, r, path) {
+				return
+			}
+
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				t.Errorf("cannot upgrade the websocket connection: %s", err)
+				return
+			}
+			defer conn.Close()
+
+			if _, _, err := conn.ReadMessage(); err != nil {
+				t.Errorf("cannot read the request frame: %s", err)
+				return
+			}
+
+			for _, line := range lines {
+				if err := conn.WriteMessage(websocket.TextMessage, []byte(line)); err != nil {
+					return
+				}
+			}
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		}))
+
+		return "ws" + strings.TrimPrefix(server.URL, "http")
+	}
+}
+
+// midjourneyProxy fakes the midjourney proxy, the task is submitted with the submit fixture and
+// the notify fixture is posted to the notify hook of the task one by one
+func midjourneyProxy(submit string, notify string) upstream {
+	return func(t *testing.T) string {
+		body := readFixture(t, submit)
+		var events []string
+		if notify != "" {
+			events = readLines(t, notify)
+		}
+
+		useNotifyServer(t)
+
+		return newServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !matchPath(t, w, r, "/mj/submit/imagine") {
+				return
+			}
+
+			var form midjourney.ImagineRequest
+			if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+				t.Errorf("cannot parse the imagine request: %s", err)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(body)
+
+			go func() {
+				for _, event := range events {
+					time.Sleep(300 * time.Millisecond)
+
+					res, err := http.Post(form.NotifyHook, "application/json", strings.NewReader(event))
+					if err != nil {
+						t.Errorf("cannot post the notify: %s", err)
+						return
+					}
+					_ = res.Body.Close()
+				}
+			}()
+		})).URL
+	}
+}
+
+// useNotifyServer serves the notify api of the midjourney adapter, the task storage is backed by the redis stub
+func useNotifyServer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	adapter.Register(engine.Group(""))
+
+	server := newServer(t, engine)
+	notify := globals.NotifyUrl
+	globals.NotifyUrl = server.URL
+	redistest.Use(t)
+
+	t.Cleanup(func() {
+		globals.NotifyUrl = notify
+	})
+}
+
+// capture replays the fixture like sse and records the request body sent by the adapter
+func capture(path string, fixture string, body *[]byte) upstream {
+	return func(t *testing.T) string {
+		data := readFixture(t, fixture)
+
+		return newServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !matchPath(t, w, r, path) {
+				return
+			}
+
+			*body, _ = io.ReadAll(r.Body)
+			writeEvents(w, data)
+		})).URL
+	}
+}
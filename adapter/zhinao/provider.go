@@ -0,0 +1,35 @@
+package zhinao
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/globals"
+)
+
+// 2048 is the max token for 360GPT
+var tokenLimit = adaptercommon.TokenLimit{Default: 2048, Max: 2048, Infinity: true}
+
+func init() {
+	adaptercommon.Register(adaptercommon.NewProvider(
+		globals.ZhinaoChannelType,
+		[]string{
+			adaptercommon.ParamTemperature,
+			adaptercommon.ParamTopP,
+			adaptercommon.ParamTopK,
+			adaptercommon.ParamRepetitionPenalty,
+		},
+		tokenLimit,
+		createChatRequest,
+	))
+}
+
+func createChatRequest(conf globals.ChannelConfig, props *adaptercommon.ChatProps, hook globals.Hook) error {
+	return NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&ChatProps{
+		Model:             props.Model,
+		Message:           props.Message,
+		Token:             tokenLimit.GetToken(props),
+		TopP:              props.TopP,
+		TopK:              props.TopK,
+		Temperature:       props.Temperature,
+		RepetitionPenalty: props.RepetitionPenalty,
+	}, hook)
+}
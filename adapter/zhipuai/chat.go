@@ -12,6 +12,9 @@ type ChatProps struct {
 	Message     []globals.Message
 	Temperature *float32 `json:"temperature,omitempty"`
 	TopP        *float32 `json:"top_p,omitempty"`
+	Tools       *globals.FunctionTools
+	ToolChoice  *interface{}
+	Buffer      *utils.Buffer
 }
 
 func (c *ChatInstance) GetChatEndpoint(model string) string {
@@ -56,6 +59,10 @@ func (c *ChatInstance) GetBody(props *ChatProps) ChatRequest {
 }
 
 func (c *ChatInstance) CreateStreamChatRequest(props *ChatProps, hook globals.Hook) error {
+	if IsV4Model(props.Model) {
+		return c.CreateV4StreamChatRequest(props, hook)
+	}
+
 	return utils.EventSource(
 		"POST",
 		c.GetChatEndpoint(props.Model),
@@ -64,9 +71,7 @@ func (c *ChatInstance) CreateStreamChatRequest(props *ChatProps, hook globals.Ho
 			"Accept":        "text/event-stream",
 			"Authorization": c.GetToken(),
 		},
-		ChatRequest{
-			Prompt: c.FormatMessages(props.Message),
-		},
+		c.GetBody(props),
 		func(data string) error {
 			if !strings.HasPrefix(data, "data:") {
 				return nil
@@ -0,0 +1,42 @@
+package zhipuai
+
+import (
+	adaptercommon "chat/adapter/common"
+	"chat/globals"
+)
+
+func init() {
+	adaptercommon.Register(adaptercommon.NewProvider(
+		globals.ChatGLMChannelType,
+		[]string{
+			adaptercommon.ParamTemperature,
+			adaptercommon.ParamTopP,
+			adaptercommon.ParamTools,
+			adaptercommon.ParamToolChoice,
+		},
+		adaptercommon.TokenLimit{},
+		createChatRequest,
+	).WithSupport(supportParam))
+}
+
+// supportParam returns whether the model supports the param, tools are only available with the v4 api
+func supportParam(model string, param string) bool {
+	switch param {
+	case adaptercommon.ParamTools, adaptercommon.ParamToolChoice:
+		return IsV4Model(model) && model != globals.GLM4Vision
+	default:
+		return true
+	}
+}
+
+func createChatRequest(conf globals.ChannelConfig, props *adaptercommon.ChatProps, hook globals.Hook) error {
+	return NewChatInstanceFromConfig(conf).CreateStreamChatRequest(&ChatProps{
+		Model:       props.Model,
+		Message:     props.Message,
+		Temperature: props.Temperature,
+		TopP:        props.TopP,
+		Tools:       props.Tools,
+		ToolChoice:  props.ToolChoice,
+		Buffer:      props.Buffer,
+	}, hook)
+}
@@ -32,3 +32,46 @@ type Occurrence struct {
 	Msg     string `json:"msg"`
 	Success bool   `json:"success"`
 }
+
+// V4ChatRequest is the openai-compatible request body for the zhipuai v4 api
+type V4ChatRequest struct {
+	Model       string                 `json:"model"`
+	Messages    []V4Message            `json:"messages"`
+	Stream      bool                   `json:"stream"`
+	Temperature *float32               `json:"temperature,omitempty"`
+	TopP        *float32               `json:"top_p,omitempty"`
+	Tools       *globals.FunctionTools `json:"tools,omitempty"`
+	ToolChoice  *interface{}           `json:"tool_choice,omitempty"`
+}
+
+type V4Message struct {
+	Role       string             `json:"role"`
+	Content    string             `json:"content"`
+	ToolCallId *string            `json:"tool_call_id,omitempty"` // only `tool` role
+	ToolCalls  *globals.ToolCalls `json:"tool_calls,omitempty"`   // only `assistant` role
+}
+
+// V4StreamResponse is the stream response chunk of the zhipuai v4 api
+type V4StreamResponse struct {
+	Id      string `json:"id"`
+	Choices []struct {
+		Index int `json:"index"`
+		Delta struct {
+			Role      string             `json:"role"`
+			Content   string             `json:"content"`
+			ToolCalls *globals.ToolCalls `json:"tool_calls,omitempty"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage,omitempty"`
+}
+
+type V4ErrorResponse struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
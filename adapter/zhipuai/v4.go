@@ -0,0 +1,128 @@
+package zhipuai
+
+import (
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+	"strings"
+)
+
+// IsV4Model returns whether the model is served by the openai-compatible v4 api
+func IsV4Model(model string) bool {
+	return model == globals.GLM4 || model == globals.GLM4Vision || model == globals.GLM3Turbo
+}
+
+func (c *ChatInstance) GetV4ChatEndpoint() string {
+	return fmt.Sprintf("%s/api/paas/v4/chat/completions", c.GetEndpoint())
+}
+
+func (c *ChatInstance) FormatV4Messages(messages []globals.Message, tools bool) []V4Message {
+	result := make([]V4Message, 0)
+	for _, message := range messages {
+		if message.Role == globals.Tool && !tools {
+			continue
+		}
+
+		result = append(result, V4Message{
+			Role:       message.Role,
+			Content:    message.Content,
+			ToolCallId: message.ToolCallId,
+			ToolCalls:  utils.Multi(tools, message.ToolCalls, nil),
+		})
+	}
+	return result
+}
+
+func (c *ChatInstance) GetV4Body(props *ChatProps) V4ChatRequest {
+	tools := props.Tools != nil && len(*props.Tools) > 0
+
+	body := V4ChatRequest{
+		Model:       props.Model,
+		Messages:    c.FormatV4Messages(props.Message, tools),
+		Stream:      true,
+		Temperature: props.Temperature,
+		TopP:        props.TopP,
+	}
+
+	if tools {
+		body.Tools = props.Tools
+		body.ToolChoice = props.ToolChoice
+	}
+	return body
+}
+
+// mergeToolCalls merges the tool calls of the stream chunks by index order
+func mergeToolCalls(calls globals.ToolCalls, delta *globals.ToolCalls) globals.ToolCalls {
+	if delta == nil {
+		return calls
+	}
+
+	for _, call := range *delta {
+		if len(calls) == 0 || (len(call.Id) > 0 && call.Id != calls[len(calls)-1].Id) {
+			calls = append(calls, call)
+			continue
+		}
+
+		last := &calls[len(calls)-1]
+		last.Function.Name += call.Function.Name
+		last.Function.Arguments += call.Function.Arguments
+	}
+	return calls
+}
+
+func (c *ChatInstance) CreateV4StreamChatRequest(props *ChatProps, hook globals.Hook) error {
+	calls := make(globals.ToolCalls, 0)
+
+	err := utils.EventSource(
+		"POST",
+		c.GetV4ChatEndpoint(),
+		map[string]string{
+			"Content-Type":  "application/json",
+			"Accept":        "text/event-stream",
+			"Authorization": fmt.Sprintf("Bearer %s", c.GetToken()),
+		},
+		c.GetV4Body(props),
+		func(data string) error {
+			data = strings.TrimSpace(data)
+			if !strings.HasPrefix(data, "data:") {
+				if form := utils.UnmarshalForm[V4ErrorResponse](data); form != nil && form.Error.Message != "" {
+					return fmt.Errorf("zhipuai error: %s (code: %s)", form.Error.Message, form.Error.Code)
+				}
+				return nil
+			}
+
+			data = strings.TrimSpace(strings.TrimPrefix(data, "data:"))
+			if data == "[DONE]" {
+				return nil
+			}
+
+			form := utils.UnmarshalForm[V4StreamResponse](data)
+			if form == nil {
+				globals.Debug(fmt.Sprintf("zhipuai error: cannot unmarshal data %s", data))
+				return nil
+			}
+
+			if form.Usage != nil {
+				props.Buffer.SetInputTokens(form.Usage.PromptTokens)
+				props.Buffer.SetOutputTokens(form.Usage.CompletionTokens)
+			}
+
+			if len(form.Choices) == 0 {
+				return nil
+			}
+
+			delta := form.Choices[0].Delta
+			calls = mergeToolCalls(calls, delta.ToolCalls)
+			return hook(delta.Content)
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if len(calls) > 0 {
+		props.Buffer.SetToolCalls(&calls)
+	}
+	return nil
+}
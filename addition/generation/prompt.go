@@ -22,7 +22,7 @@ func CreateGeneration(group, model, prompt, path string, plan bool, hook func(bu
 		Message:  message,
 		Plan:     plan,
 		Infinity: true,
-		Buffer:   *buffer,
+		Buffer:   buffer,
 	}, func(data string) error {
 		buffer.Write(data)
 		hook(buffer, data)
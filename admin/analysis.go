@@ -112,6 +112,17 @@ func GetErrorData(cache *redis.Client) ErrorChartForm {
 	}
 }
 
+func GetModerationData(cache *redis.Client) ModerationChartForm {
+	dates := getDays(7)
+
+	return ModerationChartForm{
+		Date: getDates(dates),
+		Value: utils.Each[time.Time, int64](dates, func(date time.Time) int64 {
+			return utils.MustInt(cache, getModerationFormat(getFormat(date)))
+		}),
+	}
+}
+
 func GetUserTypeData(db *sql.DB) (UserTypeForm, error) {
 	var form UserTypeForm
 
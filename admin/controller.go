@@ -88,6 +88,11 @@ func ErrorAnalysisAPI(c *gin.Context) {
 	c.JSON(http.StatusOK, GetErrorData(cache))
 }
 
+func ModerationAnalysisAPI(c *gin.Context) {
+	cache := utils.GetCacheFromContext(c)
+	c.JSON(http.StatusOK, GetModerationData(cache))
+}
+
 func UserTypeAnalysisAPI(c *gin.Context) {
 	db := utils.GetDBFromContext(c)
 	if form, err := GetUserTypeData(db); err != nil {
@@ -29,6 +29,10 @@ func getErrorFormat(t string) string {
 	return fmt.Sprintf("nio:err-analysis-%s", t)
 }
 
+func getModerationFormat(t string) string {
+	return fmt.Sprintf("nio:moderation-analysis-%s", t)
+}
+
 func getBillingFormat(t string) string {
 	return fmt.Sprintf("nio:billing-analysis-%s", t)
 }
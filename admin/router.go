@@ -13,6 +13,7 @@ func Register(app *gin.RouterGroup) {
 	app.GET("/admin/analytics/request", RequestAnalysisAPI)
 	app.GET("/admin/analytics/billing", BillingAnalysisAPI)
 	app.GET("/admin/analytics/error", ErrorAnalysisAPI)
+	app.GET("/admin/analytics/moderation", ModerationAnalysisAPI)
 	app.GET("/admin/analytics/user", UserTypeAnalysisAPI)
 
 	app.GET("/admin/invitation/list", InvitationPaginationAPI)
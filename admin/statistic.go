@@ -11,6 +11,11 @@ func IncrErrorRequest(cache *redis.Client) {
 	utils.IncrOnce(cache, getErrorFormat(getDay()), time.Hour*24*7*2)
 }
 
+// IncrModerationRequest counts the request which is flagged by the moderation
+func IncrModerationRequest(cache *redis.Client) {
+	utils.IncrOnce(cache, getModerationFormat(getDay()), time.Hour*24*7*2)
+}
+
 func IncrBillingRequest(cache *redis.Client, amount int64) {
 	utils.IncrWithExpire(cache, getBillingFormat(getDay()), amount, time.Hour*24*30*2)
 	utils.IncrWithExpire(cache, getMonthBillingFormat(getMonth()), amount, time.Hour*24*30*2)
@@ -33,6 +33,11 @@ type ErrorChartForm struct {
 	Value []int64  `json:"value"`
 }
 
+type ModerationChartForm struct {
+	Date  []string `json:"date"`
+	Value []int64  `json:"value"`
+}
+
 type PaginationForm struct {
 	Status  bool          `json:"status"`
 	Total   int           `json:"total"`
@@ -11,6 +11,71 @@ export type GetChannelResponse = CommonResponse & {
   data?: Channel;
 };
 
+export type BreakerState = {
+  state: string;
+  since: number;
+  reason: string;
+};
+
+export type BreakerEvent = {
+  channel: number;
+  name: string;
+  from: string;
+  to: string;
+  reason: string;
+  time: number;
+};
+
+export type BreakerListResponse = CommonResponse & {
+  data?: {
+    enabled: boolean;
+    states: Record<number, BreakerState>;
+    events: BreakerEvent[];
+  };
+};
+
+export type HealthRecord = {
+  key: number;
+  secret: string;
+  model: string;
+  success: boolean;
+  latency: number;
+  error: string;
+  skipped: boolean;
+  time: number;
+};
+
+export type HealthListResponse = CommonResponse & {
+  data?: {
+    enabled: boolean;
+    interval: number;
+    records: Record<number, HealthRecord[]>;
+  };
+};
+
+export type HealthTestResponse = CommonResponse & {
+  data?: HealthRecord[];
+};
+
+export type SecretState = {
+  key: number;
+  secret: string;
+  requests: number;
+  failures: number;
+  total_failures: number;
+  last_error: string;
+  last_error_time: number;
+  cooldown_until: number;
+  disabled: boolean;
+  disabled_reason: string;
+  balance?: number | null;
+  balance_time: number;
+};
+
+export type SecretListResponse = CommonResponse & {
+  data?: SecretState[];
+};
+
 export async function listChannel(): Promise<ChannelListResponse> {
   try {
     const response = await axios.get("/admin/channel/list");
@@ -76,3 +141,62 @@ export async function deactivateChannel(id: number): Promise<CommonResponse> {
     return { status: false, error: getErrorMessage(e) };
   }
 }
+
+export async function listBreaker(): Promise<BreakerListResponse> {
+  try {
+    const response = await axios.get("/admin/channel/breaker");
+    return response.data as BreakerListResponse;
+  } catch (e) {
+    return { status: false, error: getErrorMessage(e) };
+  }
+}
+
+export async function resetBreaker(id: number): Promise<CommonResponse> {
+  try {
+    const response = await axios.get(`/admin/channel/breaker/reset/${id}`);
+    return response.data as CommonResponse;
+  } catch (e) {
+    return { status: false, error: getErrorMessage(e) };
+  }
+}
+
+export async function listHealth(): Promise<HealthListResponse> {
+  try {
+    const response = await axios.get("/admin/channel/health");
+    return response.data as HealthListResponse;
+  } catch (e) {
+    return { status: false, error: getErrorMessage(e) };
+  }
+}
+
+export async function testHealth(id: number): Promise<HealthTestResponse> {
+  try {
+    const response = await axios.get(`/admin/channel/health/test/${id}`);
+    return response.data as HealthTestResponse;
+  } catch (e) {
+    return { status: false, error: getErrorMessage(e) };
+  }
+}
+
+export async function listSecret(id: number): Promise<SecretListResponse> {
+  try {
+    const response = await axios.get(`/admin/channel/secret/${id}`);
+    return response.data as SecretListResponse;
+  } catch (e) {
+    return { status: false, error: getErrorMessage(e) };
+  }
+}
+
+export async function enableSecret(
+  id: number,
+  key: number,
+): Promise<CommonResponse> {
+  try {
+    const response = await axios.get(
+      `/admin/channel/secret/${id}/enable/${key}`,
+    );
+    return response.data as CommonResponse;
+  } catch (e) {
+    return { status: false, error: getErrorMessage(e) };
+  }
+}
@@ -6,6 +6,7 @@ import {
   InvitationGenerateResponse,
   InvitationResponse,
   ModelChartResponse,
+  ModerationChartResponse,
   RedeemResponse,
   RequestChartResponse,
   UserResponse,
@@ -69,6 +70,16 @@ export async function getErrorChart(): Promise<ErrorChartResponse> {
   }
 }
 
+export async function getModerationChart(): Promise<ModerationChartResponse> {
+  try {
+    const response = await axios.get("/admin/analytics/moderation");
+    return response.data as ModerationChartResponse;
+  } catch (e) {
+    console.warn(e);
+    return { date: [], value: [] };
+  }
+}
+
 export async function getUserTypeChart(): Promise<UserTypeChartResponse> {
   try {
     const response = await axios.get("/admin/analytics/user");
@@ -36,6 +36,52 @@ export type SearchState = {
   query: number;
 };
 
+export type MidjourneyState = {
+  timeout: number;
+};
+
+export type ModerationState = {
+  enabled: boolean;
+  model: string;
+  action: string;
+  groups: Record<string, string>;
+};
+
+export const moderationActions = ["block", "log", "allow"];
+
+export type BreakerConfigState = {
+  enabled: boolean;
+  failures: number;
+  window: number;
+  window_failures: number;
+  cooldown: number;
+};
+
+export type HealthState = {
+  enabled: boolean;
+  interval: number;
+  model: string;
+};
+
+export type RoutingRule = {
+  model: string;
+  strategy: string;
+};
+
+export type RoutingState = {
+  strategy: string;
+  models: RoutingRule[];
+  groups: Record<string, string>;
+};
+
+export const routingStrategies = [
+  "weighted",
+  "round-robin",
+  "least-inflight",
+  "latency",
+  "cost",
+];
+
 export type SiteState = {
   quota: number;
   buy_link: string;
@@ -48,6 +94,11 @@ export type SystemProps = {
   phone: PhoneState;
   mail: MailState;
   search: SearchState;
+  midjourney: MidjourneyState;
+  moderation: ModerationState;
+  breaker: BreakerConfigState;
+  health: HealthState;
+  routing: RoutingState;
 };
 
 export type SystemResponse = CommonResponse & {
@@ -136,4 +187,30 @@ export const initialSystemState: SystemProps = {
     endpoint: "https://duckduckgo-api.vercel.app",
     query: 5,
   },
+  midjourney: {
+    timeout: 600,
+  },
+  moderation: {
+    enabled: false,
+    model: "text-moderation-latest",
+    action: "block",
+    groups: {},
+  },
+  breaker: {
+    enabled: false,
+    failures: 5,
+    window: 60,
+    window_failures: 10,
+    cooldown: 60,
+  },
+  health: {
+    enabled: false,
+    interval: 300,
+    model: "gpt-3.5-turbo",
+  },
+  routing: {
+    strategy: "weighted",
+    models: [],
+    groups: {},
+  },
 };
@@ -6,11 +6,26 @@ export type Channel = {
   priority: number;
   weight: number;
   retry: number;
+  rpm: number;
+  tpm: number;
+  concurrency: number;
+  queue: number;
+  cost: number;
   secret: string;
   endpoint: string;
   mapper: string;
   state: boolean;
   group?: string[];
+  azure?: AzureConfig;
+};
+
+export type AzureConfig = {
+  api_version: string;
+  deployments: string;
+  chat: string;
+  image: string;
+  embedding: string;
+  audio: string;
 };
 
 export type ChannelInfo = {
@@ -18,12 +33,14 @@ export type ChannelInfo = {
   endpoint: string;
   format: string;
   models: string[];
+  dynamic?: boolean; // models are listed from the upstream if empty
 };
 
 export const ChannelTypes: Record<string, string> = {
   openai: "OpenAI",
   azure: "Azure OpenAI",
   claude: "Claude",
+  anthropic: "Anthropic Messages",
   slack: "Slack",
   sparkdesk: "讯飞星火",
   chatglm: "智谱 ChatGLM",
@@ -36,6 +53,8 @@ export const ChannelTypes: Record<string, string> = {
   palm: "Google Gemini",
   midjourney: "Midjourney",
   oneapi: "Nio API",
+  ollama: "Ollama",
+  llamacpp: "llama.cpp",
 };
 
 export const ChannelInfos: Record<string, ChannelInfo> = {
@@ -98,6 +117,20 @@ export const ChannelInfos: Record<string, ChannelInfo> = {
     format: "<x-api-key>",
     models: ["claude-instant-1", "claude-2", "claude-2.1"],
   },
+  anthropic: {
+    endpoint: "https://api.anthropic.com",
+    format: "<x-api-key>",
+    description:
+      "> Anthropic Messages API (/v1/messages)，支持 System 提示词、图片输入和工具调用。\n",
+    models: [
+      "claude-instant-1.2",
+      "claude-2.0",
+      "claude-2.1",
+      "claude-3-opus-20240229",
+      "claude-3-sonnet-20240229",
+      "claude-3-haiku-20240307",
+    ],
+  },
   slack: {
     endpoint: "your-channel",
     format: "<bot-id>|<xoxp-token>",
@@ -121,10 +154,14 @@ export const ChannelInfos: Record<string, ChannelInfo> = {
       "zhipu-chatglm-pro",
       "zhipu-chatglm-std",
       "zhipu-chatglm-lite",
+      "glm-4",
+      "glm-4v",
+      "glm-3-turbo",
     ],
     description:
       "> 智谱 ChatGLM 密钥格式为 **api-key**，接入点填写 *https://open.bigmodel.cn* \n" +
-      "> 智谱 ChatGLM 模型为了区分和 LocalAI 的开源 ChatGLM 模型，规定模型名称前缀为 **zhipu-**，系统内部已经做好适配，正常填入模板模型即可，无需额外任何设置 \n",
+      "> 智谱 ChatGLM 模型为了区分和 LocalAI 的开源 ChatGLM 模型，规定模型名称前缀为 **zhipu-**，系统内部已经做好适配，正常填入模板模型即可，无需额外任何设置 \n" +
+      "> glm-4, glm-4v, glm-3-turbo 模型使用 v4 接口，支持工具调用 (glm-4v 除外) \n",
   },
   qwen: {
     endpoint: "https://dashscope.aliyuncs.com",
@@ -196,6 +233,24 @@ export const ChannelInfos: Record<string, ChannelInfo> = {
     format: "<api-key>",
     models: [],
   },
+  ollama: {
+    endpoint: "http://localhost:11434",
+    format: "<api-key>",
+    description:
+      "> 本地模型服务，无鉴权时密钥可填写任意值，如 *ollama* \n" +
+      "> 模型留空时保存渠道会自动从 **/api/tags** 拉取本地模型列表 \n",
+    models: [],
+    dynamic: true,
+  },
+  llamacpp: {
+    endpoint: "http://localhost:8080",
+    format: "<api-key>",
+    description:
+      "> llama.cpp server，无鉴权时密钥可填写任意值，如 *llamacpp* \n" +
+      "> 模型留空时保存渠道会自动从 **/v1/models** 拉取已加载的模型 \n",
+    models: [],
+    dynamic: true,
+  },
 };
 
 export const channelModels: string[] = Object.values(ChannelInfos).flatMap(
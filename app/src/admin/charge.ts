@@ -1,9 +1,15 @@
 export const tokenBilling = "token-billing";
 export const timesBilling = "times-billing";
 export const nonBilling = "non-billing";
+export const audioBilling = "audio-billing";
 
 export const defaultChargeType = tokenBilling;
-export const chargeTypes = [nonBilling, timesBilling, tokenBilling];
+export const chargeTypes = [
+  nonBilling,
+  timesBilling,
+  tokenBilling,
+  audioBilling,
+];
 
 export type ChargeProps = {
   id: number;
@@ -32,6 +32,11 @@ export type ErrorChartResponse = {
   value: number[];
 };
 
+export type ModerationChartResponse = {
+  date: string[];
+  value: number[];
+};
+
 export type UserTypeChartResponse = {
   total: number;
   normal: number;
@@ -14,9 +14,18 @@ export type StreamMessage = {
   plan?: boolean;
 };
 
+export type MessageContent = {
+  type: "text" | "image_url" | "image" | "file";
+  text?: string;
+  image_url?: { url: string; detail?: string };
+  image?: { mime_type: string; data: string };
+  file?: { name: string; mime_type?: string; content: string };
+};
+
 export type ChatProps = {
   type?: string;
   message: string;
+  contents?: MessageContent[];
   model: string;
   web?: boolean;
   context?: number;
@@ -64,7 +73,9 @@ export class Connection {
     this.init();
   }
 
-  public send(data: Record<string, string | boolean | number>): boolean {
+  public send(
+    data: Record<string, string | boolean | number | MessageContent[]>,
+  ): boolean {
     if (!this.state || !this.connection) {
       if (this.connection === undefined) this.init();
       console.debug("[connection] connection not ready, retrying in 500ms...");
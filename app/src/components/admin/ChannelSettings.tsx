@@ -1,7 +1,16 @@
 import { useReducer, useState } from "react";
 import ChannelTable from "@/components/admin/assemblies/ChannelTable.tsx";
 import ChannelEditor from "@/components/admin/assemblies/ChannelEditor.tsx";
-import { Channel, getChannelInfo } from "@/admin/channel.ts";
+import { AzureConfig, Channel, getChannelInfo } from "@/admin/channel.ts";
+
+const emptyAzureConfig: AzureConfig = {
+  api_version: "",
+  deployments: "",
+  chat: "",
+  image: "",
+  embedding: "",
+  audio: "",
+};
 
 const initialState: Channel = {
   id: -1,
@@ -11,6 +20,11 @@ const initialState: Channel = {
   priority: 0,
   weight: 1,
   retry: 3,
+  rpm: 0,
+  tpm: 0,
+  concurrency: 0,
+  queue: 0,
+  cost: 1,
   secret: "",
   endpoint: getChannelInfo().endpoint,
   mapper: "",
@@ -61,6 +75,21 @@ function reducer(state: Channel, action: any): Channel {
       return { ...state, mapper: action.value };
     case "retry":
       return { ...state, retry: action.value };
+    case "rpm":
+      return { ...state, rpm: action.value };
+    case "tpm":
+      return { ...state, tpm: action.value };
+    case "concurrency":
+      return { ...state, concurrency: action.value };
+    case "queue":
+      return { ...state, queue: action.value };
+    case "cost":
+      return { ...state, cost: action.value };
+    case "azure":
+      return {
+        ...state,
+        azure: { ...emptyAzureConfig, ...state.azure, ...action.value },
+      };
     case "clear":
       return { ...initialState };
     case "add-group":
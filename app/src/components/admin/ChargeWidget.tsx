@@ -1,6 +1,7 @@
 import { RadioGroup, RadioGroupItem } from "@/components/ui/radio-group.tsx";
 import { Label } from "@/components/ui/label.tsx";
 import {
+  audioBilling,
   ChargeProps,
   chargeTypes,
   defaultChargeType,
@@ -131,6 +132,7 @@ function preflight(state: ChargeProps): ChargeProps {
       state.anonymous = false;
       break;
     case tokenBilling:
+    case audioBilling:
       state.anonymous = false;
       break;
   }
@@ -519,6 +521,45 @@ function ChargeEditor({
         </div>
       )}
 
+      {form.type === audioBilling && (
+        <div className={`flex flex-col w-full h-max gap-2`}>
+          <div className={`flex flex-row w-full h-max items-center`}>
+            <UploadCloud className={`w-4 h-4 mr-2`} />
+            <Label className={`grow`}>
+              {t("admin.charge.speech-count")}
+              <span className={`token`}> / 1k chars</span>
+            </Label>
+            <NumberInput
+              value={form.input}
+              onValueChange={(value) =>
+                dispatch({ type: "set-input", payload: value })
+              }
+              acceptNegative={false}
+              className={`w-20`}
+              min={0}
+              max={99999}
+            />
+          </div>
+          <div className={`flex flex-row w-full h-max items-center`}>
+            <DownloadCloud className={`w-4 h-4 mr-2`} />
+            <Label className={`grow`}>
+              {t("admin.charge.audio-count")}
+              <span className={`token`}> / min</span>
+            </Label>
+            <NumberInput
+              value={form.output}
+              onValueChange={(value) =>
+                dispatch({ type: "set-output", payload: value })
+              }
+              acceptNegative={false}
+              className={`w-20`}
+              min={0}
+              max={99999}
+            />
+          </div>
+        </div>
+      )}
+
       <div
         className={`flex flex-row w-full h-max mt-5 gap-2 items-center flex-wrap`}
       >
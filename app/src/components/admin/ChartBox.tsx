@@ -4,6 +4,7 @@ import {
   BillingChartResponse,
   ErrorChartResponse,
   ModelChartResponse,
+  ModerationChartResponse,
   RequestChartResponse,
   UserTypeChartResponse,
 } from "@/admin/types.ts";
@@ -24,11 +25,13 @@ import { themeEvent } from "@/events/theme.ts";
 import RequestChart from "@/components/admin/assemblies/RequestChart.tsx";
 import BillingChart from "@/components/admin/assemblies/BillingChart.tsx";
 import ErrorChart from "@/components/admin/assemblies/ErrorChart.tsx";
+import ModerationChart from "@/components/admin/assemblies/ModerationChart.tsx";
 import { useEffectAsync } from "@/utils/hook.ts";
 import {
   getBillingChart,
   getErrorChart,
   getModelChart,
+  getModerationChart,
   getRequestChart,
   getUserTypeChart,
 } from "@/admin/api/chart.ts";
@@ -100,6 +103,11 @@ function ChartBox() {
     value: [],
   });
 
+  const [moderation, setModeration] = useState<ModerationChartResponse>({
+    date: [],
+    value: [],
+  });
+
   const [user, setUser] = useState<UserTypeChartResponse>({
     total: 0,
     normal: 0,
@@ -114,6 +122,7 @@ function ChartBox() {
     setRequest(await getRequestChart());
     setBilling(await getBillingChart());
     setError(await getErrorChart());
+    setModeration(await getModerationChart());
     setUser(await getUserTypeChart());
   }, []);
 
@@ -149,6 +158,13 @@ function ChartBox() {
       <div className={`chart-box`}>
         <ErrorChart labels={error.date} datasets={error.value} dark={dark} />
       </div>
+      <div className={`chart-box`}>
+        <ModerationChart
+          labels={moderation.date}
+          datasets={moderation.value}
+          dark={dark}
+        />
+      </div>
     </div>
   );
 }
@@ -83,7 +83,7 @@ function CustomAction({ onPost }: CustomActionProps) {
 function validator(state: Channel): boolean {
   return (
     state.name.trim() !== "" &&
-    state.models.length > 0 &&
+    (state.models.length > 0 || !!getChannelInfo(state.type).dynamic) &&
     state.secret.trim() !== "" &&
     state.endpoint.trim() !== ""
   );
@@ -114,6 +114,22 @@ function handler(data: Channel): Channel {
   data.group = data.group
     ? data.group.filter((group) => group.trim() !== "")
     : [];
+  if (data.type !== "azure" || !data.azure) {
+    data.azure = undefined;
+  } else {
+    data.azure = {
+      api_version: data.azure.api_version.trim(),
+      deployments: data.azure.deployments
+        .trim()
+        .split("\n")
+        .filter((line) => line.trim() !== "")
+        .join("\n"),
+      chat: data.azure.chat.trim(),
+      image: data.azure.image.trim(),
+      embedding: data.azure.embedding.trim(),
+      audio: data.azure.audio.trim(),
+    };
+  }
   return data;
 }
 
@@ -353,6 +369,64 @@ function ChannelEditor({
               onValueChange={(value) => dispatch({ type: "retry", value })}
             />
           </div>
+          <div className={`channel-row`}>
+            <div className={`channel-content`}>
+              {t("admin.channels.rpm")}
+              <Tips content={t("admin.channels.rpm-tip")} />
+            </div>
+            <NumberInput
+              value={edit.rpm}
+              min={0}
+              onValueChange={(value) => dispatch({ type: "rpm", value })}
+            />
+          </div>
+          <div className={`channel-row`}>
+            <div className={`channel-content`}>
+              {t("admin.channels.tpm")}
+              <Tips content={t("admin.channels.tpm-tip")} />
+            </div>
+            <NumberInput
+              value={edit.tpm}
+              min={0}
+              onValueChange={(value) => dispatch({ type: "tpm", value })}
+            />
+          </div>
+          <div className={`channel-row`}>
+            <div className={`channel-content`}>
+              {t("admin.channels.concurrency")}
+              <Tips content={t("admin.channels.concurrency-tip")} />
+            </div>
+            <NumberInput
+              value={edit.concurrency}
+              min={0}
+              onValueChange={(value) =>
+                dispatch({ type: "concurrency", value })
+              }
+            />
+          </div>
+          <div className={`channel-row`}>
+            <div className={`channel-content`}>
+              {t("admin.channels.queue")}
+              <Tips content={t("admin.channels.queue-tip")} />
+            </div>
+            <NumberInput
+              value={edit.queue}
+              min={0}
+              max={60}
+              onValueChange={(value) => dispatch({ type: "queue", value })}
+            />
+          </div>
+          <div className={`channel-row`}>
+            <div className={`channel-content`}>
+              {t("admin.channels.cost")}
+              <Tips content={t("admin.channels.cost-tip")} />
+            </div>
+            <NumberInput
+              value={edit.cost || 1}
+              min={0.01}
+              onValueChange={(value) => dispatch({ type: "cost", value })}
+            />
+          </div>
           <div className={`channel-row`}>
             <div className={`channel-content`}>
               {t("admin.channels.mapper")}
@@ -366,6 +440,110 @@ function ChannelEditor({
               }
             />
           </div>
+          {edit.type === "azure" && (
+            <>
+              <div className={`channel-row`}>
+                <div className={`channel-content`}>
+                  {t("admin.channels.azure-api-version")}
+                  <Tips content={t("admin.channels.azure-api-version-tip")} />
+                </div>
+                <Input
+                  value={edit.azure?.api_version || ""}
+                  placeholder={t(
+                    "admin.channels.azure-api-version-placeholder",
+                  )}
+                  onChange={(e) =>
+                    dispatch({
+                      type: "azure",
+                      value: { api_version: e.target.value },
+                    })
+                  }
+                />
+              </div>
+              <div className={`channel-row`}>
+                <div className={`channel-content`}>
+                  {t("admin.channels.azure-deployments")}
+                  <Tips content={t("admin.channels.azure-deployments-tip")} />
+                </div>
+                <Textarea
+                  value={edit.azure?.deployments || ""}
+                  placeholder={t(
+                    "admin.channels.azure-deployments-placeholder",
+                  )}
+                  onChange={(e) =>
+                    dispatch({
+                      type: "azure",
+                      value: { deployments: e.target.value },
+                    })
+                  }
+                />
+              </div>
+              <div className={`channel-row`}>
+                <div className={`channel-content`}>
+                  {t("admin.channels.azure-chat")}
+                  <Tips content={t("admin.channels.azure-chat-tip")} />
+                </div>
+                <Input
+                  value={edit.azure?.chat || ""}
+                  placeholder={t("admin.channels.azure-chat-placeholder")}
+                  onChange={(e) =>
+                    dispatch({
+                      type: "azure",
+                      value: { chat: e.target.value },
+                    })
+                  }
+                />
+              </div>
+              <div className={`channel-row`}>
+                <div className={`channel-content`}>
+                  {t("admin.channels.azure-image")}
+                  <Tips content={t("admin.channels.azure-image-tip")} />
+                </div>
+                <Input
+                  value={edit.azure?.image || ""}
+                  placeholder={t("admin.channels.azure-image-placeholder")}
+                  onChange={(e) =>
+                    dispatch({
+                      type: "azure",
+                      value: { image: e.target.value },
+                    })
+                  }
+                />
+              </div>
+              <div className={`channel-row`}>
+                <div className={`channel-content`}>
+                  {t("admin.channels.azure-embedding")}
+                  <Tips content={t("admin.channels.azure-embedding-tip")} />
+                </div>
+                <Input
+                  value={edit.azure?.embedding || ""}
+                  placeholder={t("admin.channels.azure-embedding-placeholder")}
+                  onChange={(e) =>
+                    dispatch({
+                      type: "azure",
+                      value: { embedding: e.target.value },
+                    })
+                  }
+                />
+              </div>
+              <div className={`channel-row`}>
+                <div className={`channel-content`}>
+                  {t("admin.channels.azure-audio")}
+                  <Tips content={t("admin.channels.azure-audio-tip")} />
+                </div>
+                <Input
+                  value={edit.azure?.audio || ""}
+                  placeholder={t("admin.channels.azure-audio-placeholder")}
+                  onChange={(e) =>
+                    dispatch({
+                      type: "azure",
+                      value: { audio: e.target.value },
+                    })
+                  }
+                />
+              </div>
+            </>
+          )}
           <Paragraph title={t("admin.channels.advanced")} isCollapsed={true}>
             <ParagraphItem>
               <div className={`channel-row column-layout`}>
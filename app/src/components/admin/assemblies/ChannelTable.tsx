@@ -9,9 +9,12 @@ import { Badge } from "@/components/ui/badge.tsx";
 import {
   Activity,
   Check,
+  HeartPulse,
+  KeyRound,
   Plus,
   RotateCw,
   Settings2,
+  Unplug,
   Trash,
   X,
 } from "lucide-react";
@@ -24,15 +27,30 @@ import { useTranslation } from "react-i18next";
 import { useEffectAsync } from "@/utils/hook.ts";
 import {
   activateChannel,
+  BreakerState,
   deactivateChannel,
   deleteChannel,
+  enableSecret,
+  HealthRecord,
+  listBreaker,
   listChannel,
+  listHealth,
+  listSecret,
+  resetBreaker,
+  SecretState,
+  testHealth,
 } from "@/admin/api/channel.ts";
 import { useToast } from "@/components/ui/use-toast.ts";
 import { cn } from "@/components/ui/lib/utils.ts";
 import PopupDialog from "@/components/PopupDialog.tsx";
 import { getApiModels, getV1Path } from "@/api/v1.ts";
 import { getHostName } from "@/utils/base.ts";
+import {
+  Dialog,
+  DialogContent,
+  DialogHeader,
+  DialogTitle,
+} from "@/components/ui/dialog.tsx";
 
 type ChannelTableProps = {
   display: boolean;
@@ -55,6 +73,167 @@ function TypeBadge({ type }: TypeBadgeProps) {
   );
 }
 
+type BreakerBadgeProps = {
+  state?: BreakerState;
+};
+
+function BreakerBadge({ state }: BreakerBadgeProps) {
+  const { t } = useTranslation();
+  const value = state?.state || "closed";
+
+  return (
+    <Badge
+      variant={value === "closed" ? `outline` : `destructive`}
+      className={`select-none w-max whitespace-nowrap`}
+      title={state?.reason || ""}
+    >
+      {t(`admin.channels.breaker-states.${value}`)}
+    </Badge>
+  );
+}
+
+type HealthBadgeProps = {
+  records?: HealthRecord[];
+};
+
+function HealthBadge({ records }: HealthBadgeProps) {
+  const { t } = useTranslation();
+  if (!records || records.length === 0)
+    return <span className={`text-secondary`}>-</span>;
+
+  if (records[0].skipped)
+    return (
+      <Badge
+        variant={`outline`}
+        className={`select-none w-max whitespace-nowrap text-secondary`}
+        title={records[0].error}
+      >
+        {t("admin.channels.health-skipped")}
+      </Badge>
+    );
+
+  // the records of the latest check share the same time
+  const latest = records.filter((record) => record.time === records[0].time);
+  const success = latest.filter((record) => record.success);
+  const latency = success.length
+    ? Math.round(
+        success.reduce((sum, record) => sum + record.latency, 0) /
+          success.length,
+      )
+    : 0;
+  const errors = latest
+    .filter((record) => !record.success)
+    .map((record) => `${record.secret}: ${record.error}`)
+    .join("\n");
+
+  return (
+    <Badge
+      variant={success.length === latest.length ? `outline` : `destructive`}
+      className={`select-none w-max whitespace-nowrap`}
+      title={errors}
+    >
+      {t("admin.channels.health-result", {
+        success: success.length,
+        total: latest.length,
+        latency,
+      })}
+    </Badge>
+  );
+}
+
+type SecretDialogProps = {
+  id: number;
+  setId: (id: number) => void;
+};
+
+function SecretDialog({ id, setId }: SecretDialogProps) {
+  const { t } = useTranslation();
+  const { toast } = useToast();
+  const [data, setData] = useState<SecretState[]>([]);
+
+  const refresh = async () => {
+    if (id === -1) return;
+    const resp = await listSecret(id);
+    if (!resp.status) toastState(toast, t, resp);
+    else setData(resp.data || []);
+  };
+  useEffectAsync(refresh, [id]);
+
+  const getState = (state: SecretState): string => {
+    if (state.disabled) return "disabled";
+    if (state.cooldown_until * 1000 > Date.now()) return "cooldown";
+    return "healthy";
+  };
+
+  return (
+    <Dialog
+      open={id !== -1}
+      onOpenChange={(open: boolean) => {
+        if (!open) setId(-1);
+      }}
+    >
+      <DialogContent className={`max-w-[90vw] w-max`}>
+        <DialogHeader>
+          <DialogTitle>{t("admin.channels.secrets")}</DialogTitle>
+        </DialogHeader>
+        <Table>
+          <TableHeader>
+            <TableRow className={`select-none whitespace-nowrap`}>
+              <TableCell>{t("admin.channels.secret")}</TableCell>
+              <TableCell>{t("admin.channels.secret-requests")}</TableCell>
+              <TableCell>{t("admin.channels.secret-failures")}</TableCell>
+              <TableCell>{t("admin.channels.secret-balance")}</TableCell>
+              <TableCell>{t("admin.channels.state")}</TableCell>
+              <TableCell>{t("admin.channels.action")}</TableCell>
+            </TableRow>
+          </TableHeader>
+          <TableBody>
+            {data.map((state, idx) => (
+              <TableRow key={idx}>
+                <TableCell className={`font-mono`}>{state.secret}</TableCell>
+                <TableCell>{state.requests}</TableCell>
+                <TableCell>
+                  {state.failures} / {state.total_failures}
+                </TableCell>
+                <TableCell>
+                  {typeof state.balance === "number"
+                    ? `$${state.balance.toFixed(2)}`
+                    : "-"}
+                </TableCell>
+                <TableCell>
+                  <Badge
+                    variant={
+                      getState(state) === "healthy" ? `outline` : `destructive`
+                    }
+                    className={`select-none w-max whitespace-nowrap`}
+                    title={state.disabled_reason || state.last_error}
+                  >
+                    {t(`admin.channels.secret-states.${getState(state)}`)}
+                  </Badge>
+                </TableCell>
+                <TableCell>
+                  {state.disabled && (
+                    <OperationAction
+                      tooltip={t("admin.channels.enable")}
+                      onClick={async () => {
+                        const resp = await enableSecret(id, state.key);
+                        toastState(toast, t, resp, true);
+                        await refresh();
+                      }}
+                    >
+                      <Check className={`h-4 w-4`} />
+                    </OperationAction>
+                  )}
+                </TableCell>
+              </TableRow>
+            ))}
+          </TableBody>
+        </Table>
+      </DialogContent>
+    </Dialog>
+  );
+}
+
 type SyncDialogProps = {
   dispatch: Dispatch<any>;
   open: boolean;
@@ -89,6 +268,11 @@ function SyncDialog({ dispatch, open, setOpen }: SyncDialogProps) {
       priority: 0,
       weight: 1,
       retry: 3,
+      rpm: 0,
+      tpm: 0,
+      concurrency: 0,
+      queue: 0,
+      cost: 1,
       secret: "",
       endpoint,
       mapper: "",
@@ -124,13 +308,25 @@ function ChannelTable({
   const [data, setData] = useState<Channel[]>([]);
   const [loading, setLoading] = useState<boolean>(false);
   const [open, setOpen] = useState<boolean>(false);
+  const [breaker, setBreaker] = useState<Record<number, BreakerState>>({});
+  const [breakerEnabled, setBreakerEnabled] = useState<boolean>(false);
+  const [health, setHealth] = useState<Record<number, HealthRecord[]>>({});
+  const [secretId, setSecretId] = useState<number>(-1);
 
   const refresh = async () => {
     setLoading(true);
     const resp = await listChannel();
+    const state = await listBreaker();
+    const records = await listHealth();
     setLoading(false);
     if (!resp.status) toastState(toast, t, resp);
     else setData(resp.data);
+
+    if (state.status && state.data) {
+      setBreaker(state.data.states || {});
+      setBreakerEnabled(state.data.enabled);
+    }
+    if (records.status && records.data) setHealth(records.data.records || {});
   };
   useEffectAsync(refresh, []);
   useEffectAsync(refresh, [display]);
@@ -142,6 +338,7 @@ function ChannelTable({
   return (
     display && (
       <div>
+        <SecretDialog id={secretId} setId={setSecretId} />
         <SyncDialog
           open={open}
           setOpen={setOpen}
@@ -189,6 +386,10 @@ function ChannelTable({
               <TableCell>{t("admin.channels.priority")}</TableCell>
               <TableCell>{t("admin.channels.weight")}</TableCell>
               <TableCell>{t("admin.channels.state")}</TableCell>
+              {breakerEnabled && (
+                <TableCell>{t("admin.channels.breaker")}</TableCell>
+              )}
+              <TableCell>{t("admin.channels.health")}</TableCell>
               <TableCell>{t("admin.channels.action")}</TableCell>
             </TableRow>
           </TableHeader>
@@ -211,6 +412,14 @@ function ChannelTable({
                     <X className={`h-4 w-4 text-destructive`} />
                   )}
                 </TableCell>
+                {breakerEnabled && (
+                  <TableCell>
+                    <BreakerBadge state={breaker[chan.id]} />
+                  </TableCell>
+                )}
+                <TableCell>
+                  <HealthBadge records={health[chan.id]} />
+                </TableCell>
                 <TableCell className={`flex flex-row flex-wrap gap-2`}>
                   <OperationAction
                     tooltip={t("admin.channels.edit")}
@@ -245,6 +454,35 @@ function ChannelTable({
                       <Check className={`h-4 w-4`} />
                     </OperationAction>
                   )}
+                  <OperationAction
+                    tooltip={t("admin.channels.secrets")}
+                    onClick={() => setSecretId(chan.id)}
+                  >
+                    <KeyRound className={`h-4 w-4`} />
+                  </OperationAction>
+                  <OperationAction
+                    tooltip={t("admin.channels.test-health")}
+                    onClick={async () => {
+                      const resp = await testHealth(chan.id);
+                      toastState(toast, t, resp, true);
+                      await refresh();
+                    }}
+                  >
+                    <HeartPulse className={`h-4 w-4`} />
+                  </OperationAction>
+                  {breakerEnabled &&
+                    (breaker[chan.id]?.state || "closed") !== "closed" && (
+                      <OperationAction
+                        tooltip={t("admin.channels.reset-breaker")}
+                        onClick={async () => {
+                          const resp = await resetBreaker(chan.id);
+                          toastState(toast, t, resp, true);
+                          await refresh();
+                        }}
+                      >
+                        <Unplug className={`h-4 w-4`} />
+                      </OperationAction>
+                    )}
                   <OperationAction
                     tooltip={t("admin.channels.delete")}
                     variant={`destructive`}
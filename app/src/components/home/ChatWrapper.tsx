@@ -10,7 +10,7 @@ import {
   selectWeb,
 } from "@/store/chat.ts";
 import { manager } from "@/api/manager.ts";
-import { formatMessage } from "@/utils/processor.ts";
+import { formatContents, formatMessage } from "@/utils/processor.ts";
 import ChatInterface from "@/components/home/ChatInterface.tsx";
 import EditorAction from "@/components/EditorProvider.tsx";
 import ModelFinder from "./ModelFinder.tsx";
@@ -83,6 +83,7 @@ function ChatWrapper() {
         await manager.send(t, auth, {
           type: "chat",
           message,
+          contents: files.length > 0 ? formatContents(files, data) : undefined,
           web,
           model,
           context: history,
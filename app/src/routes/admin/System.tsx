@@ -14,16 +14,24 @@ import Paragraph, {
 import { Button } from "@/components/ui/button.tsx";
 import { Label } from "@/components/ui/label.tsx";
 import { Input } from "@/components/ui/input.tsx";
-import { useMemo, useReducer, useState } from "react";
+import { useEffect, useMemo, useReducer, useState } from "react";
 import { formReducer } from "@/utils/form.ts";
 import { NumberInput } from "@/components/ui/number-input.tsx";
 import {
+  BreakerConfigState,
   commonWhiteList,
   GeneralState,
   getConfig,
+  HealthState,
   initialSystemState,
   PhoneState,
   MailState,
+  RoutingRule,
+  RoutingState,
+  routingStrategies,
+  MidjourneyState,
+  ModerationState,
+  moderationActions,
   SearchState,
   setConfig,
   SiteState,
@@ -50,6 +58,15 @@ import Tips from "@/components/Tips.tsx";
 import { cn } from "@/components/ui/lib/utils.ts";
 import { Switch } from "@/components/ui/switch.tsx";
 import { MultiCombobox } from "@/components/ui/multi-combobox.tsx";
+import {
+  Select,
+  SelectContent,
+  SelectGroup,
+  SelectItem,
+  SelectTrigger,
+  SelectValue,
+} from "@/components/ui/select.tsx";
+import { channelGroups } from "@/admin/channel.ts";
 
 type CompProps<T> = {
   data: T;
@@ -622,6 +639,437 @@ function Search({ data, dispatch, onChange }: CompProps<SearchState>) {
   );
 }
 
+function Midjourney({
+  data,
+  dispatch,
+  onChange,
+}: CompProps<MidjourneyState>) {
+  const { t } = useTranslation();
+
+  return (
+    <Paragraph
+      title={t("admin.system.midjourney")}
+      configParagraph={true}
+      isCollapsed={true}
+    >
+      <ParagraphItem>
+        <Label>{t("admin.system.midjourneyTimeout")}</Label>
+        <NumberInput
+          value={data.timeout}
+          onValueChange={(value) =>
+            dispatch({ type: "update:midjourney.timeout", value })
+          }
+          placeholder={`600`}
+          min={0}
+          max={86400}
+        />
+      </ParagraphItem>
+      <ParagraphDescription>
+        {t("admin.system.midjourneyTimeoutTip")}
+      </ParagraphDescription>
+      <ParagraphFooter>
+        <div className={`grow`} />
+        <Button
+          size={`sm`}
+          loading={true}
+          onClick={async () => await onChange()}
+        >
+          {t("admin.system.save")}
+        </Button>
+      </ParagraphFooter>
+    </Paragraph>
+  );
+}
+
+type ModerationActionSelectProps = {
+  value: string;
+  onValueChange: (value: string) => void;
+  inherit?: boolean;
+};
+
+function ModerationActionSelect({
+  value,
+  onValueChange,
+  inherit,
+}: ModerationActionSelectProps) {
+  const { t } = useTranslation();
+  const actions = inherit
+    ? ["default", ...moderationActions]
+    : moderationActions;
+
+  return (
+    <Select value={value} onValueChange={onValueChange}>
+      <SelectTrigger className={`w-48`}>
+        <SelectValue />
+      </SelectTrigger>
+      <SelectContent>
+        <SelectGroup>
+          {actions.map((action, idx) => (
+            <SelectItem key={idx} value={action}>
+              {t(`admin.system.moderationActions.${action}`)}
+            </SelectItem>
+          ))}
+        </SelectGroup>
+      </SelectContent>
+    </Select>
+  );
+}
+
+function Moderation({
+  data,
+  dispatch,
+  onChange,
+}: CompProps<ModerationState>) {
+  const { t } = useTranslation();
+  const groups = data.groups || {};
+
+  const setGroupAction = (group: string, action: string) => {
+    const value = { ...groups };
+    if (action === "default") delete value[group];
+    else value[group] = action;
+
+    dispatch({ type: "update:moderation.groups", value });
+  };
+
+  return (
+    <Paragraph
+      title={t("admin.system.moderation")}
+      configParagraph={true}
+      isCollapsed={true}
+    >
+      <ParagraphItem>
+        <Label>{t("admin.system.moderationEnabled")}</Label>
+        <Switch
+          checked={data.enabled}
+          onCheckedChange={(value) =>
+            dispatch({ type: "update:moderation.enabled", value })
+          }
+        />
+      </ParagraphItem>
+      <ParagraphItem>
+        <Label>{t("admin.system.moderationModel")}</Label>
+        <Input
+          value={data.model}
+          onChange={(e) =>
+            dispatch({
+              type: "update:moderation.model",
+              value: e.target.value,
+            })
+          }
+          placeholder={`text-moderation-latest`}
+        />
+      </ParagraphItem>
+      <ParagraphItem>
+        <Label>{t("admin.system.moderationAction")}</Label>
+        <ModerationActionSelect
+          value={data.action || "block"}
+          onValueChange={(value) =>
+            dispatch({ type: "update:moderation.action", value })
+          }
+        />
+      </ParagraphItem>
+      <ParagraphSpace />
+      {channelGroups.map((group, idx) => (
+        <ParagraphItem key={idx}>
+          <Label>{t(`admin.channels.groups.${group}`)}</Label>
+          <ModerationActionSelect
+            value={groups[group] || "default"}
+            onValueChange={(value) => setGroupAction(group, value)}
+            inherit={true}
+          />
+        </ParagraphItem>
+      ))}
+      <ParagraphDescription>
+        {t("admin.system.moderationTip")}
+      </ParagraphDescription>
+      <ParagraphFooter>
+        <div className={`grow`} />
+        <Button
+          size={`sm`}
+          loading={true}
+          onClick={async () => await onChange()}
+        >
+          {t("admin.system.save")}
+        </Button>
+      </ParagraphFooter>
+    </Paragraph>
+  );
+}
+
+function Breaker({
+  data,
+  dispatch,
+  onChange,
+}: CompProps<BreakerConfigState>) {
+  const { t } = useTranslation();
+
+  return (
+    <Paragraph
+      title={t("admin.system.breaker")}
+      configParagraph={true}
+      isCollapsed={true}
+    >
+      <ParagraphItem>
+        <Label>{t("admin.system.breakerEnabled")}</Label>
+        <Switch
+          checked={data.enabled}
+          onCheckedChange={(value) =>
+            dispatch({ type: "update:breaker.enabled", value })
+          }
+        />
+      </ParagraphItem>
+      <ParagraphItem>
+        <Label>{t("admin.system.breakerFailures")}</Label>
+        <NumberInput
+          value={data.failures}
+          onValueChange={(value) =>
+            dispatch({ type: "update:breaker.failures", value })
+          }
+          placeholder={`5`}
+          min={1}
+          max={1000}
+        />
+      </ParagraphItem>
+      <ParagraphItem>
+        <Label>{t("admin.system.breakerWindow")}</Label>
+        <NumberInput
+          value={data.window}
+          onValueChange={(value) =>
+            dispatch({ type: "update:breaker.window", value })
+          }
+          placeholder={`60`}
+          min={1}
+          max={86400}
+        />
+      </ParagraphItem>
+      <ParagraphItem>
+        <Label>{t("admin.system.breakerWindowFailures")}</Label>
+        <NumberInput
+          value={data.window_failures}
+          onValueChange={(value) =>
+            dispatch({ type: "update:breaker.window_failures", value })
+          }
+          placeholder={`10`}
+          min={1}
+          max={10000}
+        />
+      </ParagraphItem>
+      <ParagraphItem>
+        <Label>{t("admin.system.breakerCooldown")}</Label>
+        <NumberInput
+          value={data.cooldown}
+          onValueChange={(value) =>
+            dispatch({ type: "update:breaker.cooldown", value })
+          }
+          placeholder={`60`}
+          min={1}
+          max={86400}
+        />
+      </ParagraphItem>
+      <ParagraphDescription>
+        {t("admin.system.breakerTip")}
+      </ParagraphDescription>
+      <ParagraphFooter>
+        <div className={`grow`} />
+        <Button
+          size={`sm`}
+          loading={true}
+          onClick={async () => await onChange()}
+        >
+          {t("admin.system.save")}
+        </Button>
+      </ParagraphFooter>
+    </Paragraph>
+  );
+}
+
+function Health({ data, dispatch, onChange }: CompProps<HealthState>) {
+  const { t } = useTranslation();
+
+  return (
+    <Paragraph
+      title={t("admin.system.health")}
+      configParagraph={true}
+      isCollapsed={true}
+    >
+      <ParagraphItem>
+        <Label>{t("admin.system.healthEnabled")}</Label>
+        <Switch
+          checked={data.enabled}
+          onCheckedChange={(value) =>
+            dispatch({ type: "update:health.enabled", value })
+          }
+        />
+      </ParagraphItem>
+      <ParagraphItem>
+        <Label>{t("admin.system.healthInterval")}</Label>
+        <NumberInput
+          value={data.interval}
+          onValueChange={(value) =>
+            dispatch({ type: "update:health.interval", value })
+          }
+          placeholder={`300`}
+          min={60}
+          max={86400}
+        />
+      </ParagraphItem>
+      <ParagraphItem>
+        <Label>{t("admin.system.healthModel")}</Label>
+        <Input
+          value={data.model}
+          onChange={(e) =>
+            dispatch({
+              type: "update:health.model",
+              value: e.target.value,
+            })
+          }
+          placeholder={`gpt-3.5-turbo`}
+        />
+      </ParagraphItem>
+      <ParagraphDescription>{t("admin.system.healthTip")}</ParagraphDescription>
+      <ParagraphFooter>
+        <div className={`grow`} />
+        <Button
+          size={`sm`}
+          loading={true}
+          onClick={async () => await onChange()}
+        >
+          {t("admin.system.save")}
+        </Button>
+      </ParagraphFooter>
+    </Paragraph>
+  );
+}
+
+type RoutingStrategySelectProps = {
+  value: string;
+  onValueChange: (value: string) => void;
+  inherit?: boolean;
+};
+
+function RoutingStrategySelect({
+  value,
+  onValueChange,
+  inherit,
+}: RoutingStrategySelectProps) {
+  const { t } = useTranslation();
+  const strategies = inherit
+    ? ["default", ...routingStrategies]
+    : routingStrategies;
+
+  return (
+    <Select value={value} onValueChange={onValueChange}>
+      <SelectTrigger className={`w-48`}>
+        <SelectValue />
+      </SelectTrigger>
+      <SelectContent>
+        <SelectGroup>
+          {strategies.map((strategy, idx) => (
+            <SelectItem key={idx} value={strategy}>
+              {t(`admin.system.routingStrategies.${strategy}`)}
+            </SelectItem>
+          ))}
+        </SelectGroup>
+      </SelectContent>
+    </Select>
+  );
+}
+
+// the strategies per model are edited as the `model>strategy` lines
+function parseRoutingRules(value: string): RoutingRule[] {
+  const rules: RoutingRule[] = [];
+  value.split("\n").forEach((line) => {
+    const [model, strategy] = line.split(">").map((item) => item.trim());
+    if (model && routingStrategies.includes(strategy)) {
+      rules.push({ model, strategy });
+    }
+  });
+  return rules;
+}
+
+function formatRoutingRules(rules: RoutingRule[]): string {
+  return rules.map(({ model, strategy }) => `${model}>${strategy}`).join("\n");
+}
+
+function Routing({ data, dispatch, onChange }: CompProps<RoutingState>) {
+  const { t } = useTranslation();
+  const groups = data.groups || {};
+  const models = data.models || [];
+  const [rules, setRules] = useState<string>(formatRoutingRules(models));
+
+  useEffect(() => {
+    const parsed = parseRoutingRules(rules);
+    if (formatRoutingRules(parsed) !== formatRoutingRules(models)) {
+      setRules(formatRoutingRules(models));
+    }
+  }, [data.models]);
+
+  const setGroupStrategy = (group: string, strategy: string) => {
+    const value = { ...groups };
+    if (strategy === "default") delete value[group];
+    else value[group] = strategy;
+
+    dispatch({ type: "update:routing.groups", value });
+  };
+
+  return (
+    <Paragraph
+      title={t("admin.system.routing")}
+      configParagraph={true}
+      isCollapsed={true}
+    >
+      <ParagraphItem>
+        <Label>{t("admin.system.routingStrategy")}</Label>
+        <RoutingStrategySelect
+          value={data.strategy || "weighted"}
+          onValueChange={(value) =>
+            dispatch({ type: "update:routing.strategy", value })
+          }
+        />
+      </ParagraphItem>
+      <ParagraphSpace />
+      {channelGroups.map((group, idx) => (
+        <ParagraphItem key={idx}>
+          <Label>{t(`admin.channels.groups.${group}`)}</Label>
+          <RoutingStrategySelect
+            value={groups[group] || "default"}
+            onValueChange={(value) => setGroupStrategy(group, value)}
+            inherit={true}
+          />
+        </ParagraphItem>
+      ))}
+      <ParagraphSpace />
+      <ParagraphItem>
+        <Label>{t("admin.system.routingModels")}</Label>
+        <Textarea
+          value={rules}
+          placeholder={`gpt-4>latency\ngpt-3.5-turbo>least-inflight`}
+          onChange={(e) => {
+            setRules(e.target.value);
+            dispatch({
+              type: "update:routing.models",
+              value: parseRoutingRules(e.target.value),
+            });
+          }}
+        />
+      </ParagraphItem>
+      <ParagraphDescription>
+        {t("admin.system.routingTip")}
+      </ParagraphDescription>
+      <ParagraphFooter>
+        <div className={`grow`} />
+        <Button
+          size={`sm`}
+          loading={true}
+          onClick={async () => await onChange()}
+        >
+          {t("admin.system.save")}
+        </Button>
+      </ParagraphFooter>
+    </Paragraph>
+  );
+}
+
 function System() {
   const { t } = useTranslation();
   const { toast } = useToast();
@@ -663,6 +1111,31 @@ function System() {
           <Phone data={data.phone} dispatch={setData} onChange={doSaving} />
           <Mail data={data.mail} dispatch={setData} onChange={doSaving} />
           <Search data={data.search} dispatch={setData} onChange={doSaving} />
+          <Midjourney
+            data={data.midjourney}
+            dispatch={setData}
+            onChange={doSaving}
+          />
+          <Moderation
+            data={data.moderation}
+            dispatch={setData}
+            onChange={doSaving}
+          />
+          <Breaker
+            data={data.breaker || initialSystemState.breaker}
+            dispatch={setData}
+            onChange={doSaving}
+          />
+          <Health
+            data={data.health || initialSystemState.health}
+            dispatch={setData}
+            onChange={doSaving}
+          />
+          <Routing
+            data={data.routing || initialSystemState.routing}
+            dispatch={setData}
+            onChange={doSaving}
+          />
         </CardContent>
       </Card>
     </div>
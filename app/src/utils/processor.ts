@@ -1,4 +1,5 @@
 import { FileArray, FileObject } from "@/api/file.ts";
+import { MessageContent } from "@/api/connection.ts";
 
 export function getFile(file: FileObject): string {
   return `\`\`\`file
@@ -14,6 +15,25 @@ export function formatMessage(files: FileArray, message: string): string {
   return files.length > 0 ? `${data}\n\n${message}` : message;
 }
 
+export function isImageUrl(content: string): boolean {
+  return /^https?:\/\/\S+\.(png|jpg|jpeg|gif|webp|heif|heic)$/i.test(
+    content.trim(),
+  );
+}
+
+export function formatContents(
+  files: FileArray,
+  message: string,
+): MessageContent[] {
+  const contents: MessageContent[] = files.map((file) =>
+    isImageUrl(file.content)
+      ? { type: "image_url", image_url: { url: file.content.trim() } }
+      : { type: "file", file: { name: file.name, content: file.content } },
+  );
+
+  return [...contents, { type: "text", text: message.trim() }];
+}
+
 export function filterMessage(message: string): string {
   return message.replace(/```file\n\[\[.*]]\n[\s\S]*?\n```\n\n/g, "");
 }
@@ -0,0 +1,154 @@
+package channel
+
+import (
+	"chat/globals"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	azureApiVersionRegex = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(-preview)?$`)
+	azureDeploymentRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+)
+
+// parseAzureDeployments parses the `model>deployment` lines, the empty lines are ignored
+func parseAzureDeployments(text string) (map[string]string, error) {
+	deployments := make(map[string]string)
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); len(line) == 0 {
+			continue
+		}
+
+		pair := strings.Split(line, ">")
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("azure deployment %q must be in the format of model>deployment", line)
+		}
+
+		model, deployment := strings.TrimSpace(pair[0]), strings.TrimSpace(pair[1])
+		if len(model) == 0 {
+			return nil, fmt.Errorf("azure deployment %q has no model", line)
+		} else if !azureDeploymentRegex.MatchString(deployment) {
+			return nil, fmt.Errorf("azure deployment name %q of model %s is invalid", deployment, model)
+		} else if _, ok := deployments[model]; ok {
+			return nil, fmt.Errorf("azure deployment of model %s is duplicated", model)
+		}
+
+		deployments[model] = deployment
+	}
+
+	return deployments, nil
+}
+
+func (c *AzureConfig) Load() {
+	deployments, err := parseAzureDeployments(c.Deployments)
+	if err != nil {
+		globals.Warn(fmt.Sprintf("[channel] %s", err.Error()))
+	}
+	c.Deployment = &deployments
+}
+
+// GetDeployment returns the deployment of the model, or the deployment of the kind if the model is not listed
+func (c *AzureConfig) GetDeployment(model string, kind string) string {
+	if c.Deployment != nil {
+		if deployment, ok := (*c.Deployment)[model]; ok {
+			return deployment
+		}
+	}
+
+	switch kind {
+	case globals.AzureChatDeployment:
+		return c.Chat
+	case globals.AzureImageDeployment:
+		return c.Image
+	case globals.AzureEmbeddingDeployment:
+		return c.Embedding
+	case globals.AzureAudioDeployment:
+		return c.Audio
+	default:
+		return ""
+	}
+}
+
+// Validate returns the error of the settings, the api version falls back to the endpoint of the channel
+func (c *AzureConfig) Validate(endpoint string) error {
+	version := c.ApiVersion
+	if len(version) == 0 {
+		version = endpoint
+	}
+	if !azureApiVersionRegex.MatchString(version) {
+		return fmt.Errorf("azure api version %q is invalid, it should be like 2024-06-01 or 2024-05-01-preview", version)
+	}
+
+	if _, err := parseAzureDeployments(c.Deployments); err != nil {
+		return err
+	}
+
+	for kind, deployment := range map[string]string{
+		globals.AzureChatDeployment:      c.Chat,
+		globals.AzureImageDeployment:     c.Image,
+		globals.AzureEmbeddingDeployment: c.Embedding,
+		globals.AzureAudioDeployment:     c.Audio,
+	} {
+		if len(deployment) > 0 && !azureDeploymentRegex.MatchString(deployment) {
+			return fmt.Errorf("azure %s deployment name %q is invalid", kind, deployment)
+		}
+	}
+
+	return nil
+}
+
+// validateAzureSecret checks the secrets of the azure channel, each line is `api-key|api-endpoint`
+func validateAzureSecret(secret string) error {
+	for _, line := range strings.Split(secret, "\n") {
+		if line = strings.TrimSpace(line); len(line) == 0 {
+			continue
+		}
+
+		pair := strings.Split(line, "|")
+		if len(pair) != 2 || len(pair[0]) == 0 {
+			return fmt.Errorf("azure secret must be in the format of <api-key>|<api-endpoint>")
+		}
+
+		if instance, err := url.Parse(pair[1]); err != nil || instance.Host == "" || (instance.Scheme != "http" && instance.Scheme != "https") {
+			return fmt.Errorf("azure api endpoint %q is not a valid url", pair[1])
+		}
+	}
+
+	return nil
+}
+
+// GetAzureApiVersion returns the api version of the azure channel, the endpoint is the api version of the legacy channels
+func (c *Channel) GetAzureApiVersion() string {
+	if c.Azure != nil && len(c.Azure.ApiVersion) > 0 {
+		return c.Azure.ApiVersion
+	}
+	return c.GetEndpoint()
+}
+
+func (c *Channel) GetAzureDeployment(model string, kind string) string {
+	if c.Azure == nil {
+		return ""
+	}
+	return c.Azure.GetDeployment(model, kind)
+}
+
+func (c *Channel) validateAzure() error {
+	if c.GetType() != globals.AzureOpenAIChannelType {
+		if c.Azure != nil {
+			return fmt.Errorf("azure settings are only available for azure openai channels")
+		}
+		return nil
+	}
+
+	if err := validateAzureSecret(c.GetSecret()); err != nil {
+		return err
+	}
+
+	conf := c.Azure
+	if conf == nil {
+		conf = &AzureConfig{}
+	}
+	return conf.Validate(c.GetEndpoint())
+}
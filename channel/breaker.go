@@ -0,0 +1,265 @@
+package channel
+
+import (
+	"chat/connection"
+	"chat/globals"
+	"chat/utils"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxBreakerEvents is the max state transitions kept for the admin panel
+const maxBreakerEvents = 100
+
+// BreakerState is the circuit breaker state of the channel, shared by the nodes via redis
+type BreakerState struct {
+	State  string `json:"state"`
+	Since  int64  `json:"since"` // unix seconds of the last transition
+	Reason string `json:"reason"`
+}
+
+// BreakerEvent is the state transition of the circuit breaker
+type BreakerEvent struct {
+	Channel int    `json:"channel"`
+	Name    string `json:"name"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Reason  string `json:"reason"`
+	Time    int64  `json:"time"`
+}
+
+func getBreakerStateKey(id int) string {
+	return fmt.Sprintf(":breaker:state:%d", id)
+}
+
+func getBreakerFailuresKey(id int) string {
+	return fmt.Sprintf(":breaker:failures:%d", id)
+}
+
+func getBreakerWindowKey(id int) string {
+	return fmt.Sprintf(":breaker:window:%d", id)
+}
+
+func getBreakerProbeKey(id int) string {
+	return fmt.Sprintf(":breaker:probe:%d", id)
+}
+
+const breakerEventsKey = ":breaker:events"
+
+var (
+	breakerStatusPattern = regexp.MustCompile(`status: 5\d\d`)
+	breakerErrors        = []string{
+		"server_error", "internal server error", "bad gateway", "service unavailable", "gateway timeout", "overloaded",
+		"timeout", "timed out", "deadline exceeded", "connection refused", "connection reset", "broken pipe",
+		"no such host", "network is unreachable", "tls handshake", "unexpected eof",
+	}
+)
+
+// isBreakerError returns whether the error is the failure of the upstream, i.e. the 5xx status, the timeout or
+// the connection error, the client errors like the invalid request or the context length are not the failure
+func isBreakerError(err error) bool {
+	if err == nil || err.Error() == "signal" {
+		return false
+	}
+
+	content := strings.ToLower(err.Error())
+	if breakerStatusPattern.MatchString(content) {
+		return true
+	}
+
+	for _, pattern := range breakerErrors {
+		if strings.Contains(content, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func isBreakerEnabled() bool {
+	return connection.Cache != nil && SystemInstance != nil && SystemInstance.IsBreakerEnabled()
+}
+
+// GetBreakerState returns the circuit breaker state of the channel, closed if it is not recorded
+func (c *Channel) GetBreakerState() BreakerState {
+	if connection.Cache != nil {
+		if state := utils.GetJson[BreakerState](connection.Cache, getBreakerStateKey(c.GetId())); state != nil {
+			return *state
+		}
+	}
+
+	return BreakerState{State: globals.BreakerClosed}
+}
+
+func (c *Channel) setBreakerState(to string, reason string) {
+	from := c.GetBreakerState().State
+	now := time.Now().Unix()
+
+	if err := utils.SetJson(connection.Cache, getBreakerStateKey(c.GetId()), BreakerState{
+		State:  to,
+		Since:  now,
+		Reason: reason,
+	}, 0); err != nil {
+		globals.Warn(fmt.Sprintf("[channel] cannot set breaker state of channel %s: %s", c.GetName(), err.Error()))
+		return
+	}
+
+	if from == to {
+		return
+	}
+
+	ctx := context.Background()
+	connection.Cache.LPush(ctx, breakerEventsKey, utils.Marshal(BreakerEvent{
+		Channel: c.GetId(),
+		Name:    c.GetName(),
+		From:    from,
+		To:      to,
+		Reason:  reason,
+		Time:    now,
+	}))
+	connection.Cache.LTrim(ctx, breakerEventsKey, 0, maxBreakerEvents-1)
+
+	globals.Info(fmt.Sprintf("[channel] breaker of channel %s turns from %s to %s (reason: %s)", c.GetName(), from, to, reason))
+}
+
+// IsBreakerAvailable returns whether the channel can be selected by the ticker, it only reads the state,
+// the open breaker is available after the cooldown if no probe request is in flight
+func (c *Channel) IsBreakerAvailable() bool {
+	if !isBreakerEnabled() {
+		return true
+	}
+
+	state := c.GetBreakerState()
+	if state.State == globals.BreakerClosed {
+		return true
+	} else if state.State == globals.BreakerOpen && time.Now().Unix()-state.Since < SystemInstance.GetBreakerCooldown() {
+		return false
+	}
+
+	count, err := connection.Cache.Exists(context.Background(), getBreakerProbeKey(c.GetId())).Result()
+	return err == nil && count == 0
+}
+
+// acquireProbe is called after the ticker picks the channel, the open breaker lets one probe request pass among
+// the nodes after the cooldown, probing is true if the request holds the probe lock
+func (c *Channel) acquireProbe() (probing bool, ok bool) {
+	if !isBreakerEnabled() {
+		return false, true
+	}
+
+	state := c.GetBreakerState()
+	if state.State == globals.BreakerClosed {
+		return false, true
+	}
+
+	cooldown := SystemInstance.GetBreakerCooldown()
+	if state.State == globals.BreakerOpen && time.Now().Unix()-state.Since < cooldown {
+		return false, false
+	}
+
+	// the probe lock expires with the cooldown, so a lost probe request does not hold the channel forever
+	acquired, err := connection.Cache.SetNX(context.Background(), getBreakerProbeKey(c.GetId()), 1, time.Duration(cooldown)*time.Second).Result()
+	if err != nil || !acquired {
+		return false, false
+	}
+
+	if state.State == globals.BreakerOpen {
+		c.setBreakerState(globals.BreakerHalfOpen, "cooldown is over, probing")
+	}
+	return true, true
+}
+
+// releaseProbe frees the probe lock if the probe request is not sent or its result says nothing about the upstream,
+// so the next request probes the channel
+func (c *Channel) releaseProbe() {
+	if isBreakerEnabled() {
+		connection.Cache.Del(context.Background(), getBreakerProbeKey(c.GetId()))
+	}
+}
+
+// RecordSuccess closes the breaker of the channel and resets the consecutive failures,
+// the windowed failures are kept until the window expires so the intermittent failures still open the breaker
+func (c *Channel) RecordSuccess() {
+	if !isBreakerEnabled() {
+		return
+	}
+
+	ctx := context.Background()
+	connection.Cache.Del(ctx, getBreakerFailuresKey(c.GetId()))
+
+	if c.GetBreakerState().State != globals.BreakerClosed {
+		connection.Cache.Del(ctx, getBreakerProbeKey(c.GetId()))
+		c.setBreakerState(globals.BreakerClosed, "probe request succeeded")
+	}
+}
+
+// RecordFailure counts the upstream failure of the channel, the breaker opens after the consecutive or windowed
+// failures and the failed probe request opens the breaker again
+func (c *Channel) RecordFailure(err error) {
+	if !isBreakerEnabled() || !isBreakerError(err) {
+		return
+	}
+
+	reason := strings.TrimSpace(strings.Split(err.Error(), "\n")[0])
+	if c.GetBreakerState().State == globals.BreakerHalfOpen {
+		connection.Cache.Del(context.Background(), getBreakerProbeKey(c.GetId()))
+		c.setBreakerState(globals.BreakerOpen, reason)
+		return
+	}
+
+	failures, _ := utils.Incr(connection.Cache, getBreakerFailuresKey(c.GetId()), 1)
+
+	window, _ := utils.Incr(connection.Cache, getBreakerWindowKey(c.GetId()), 1)
+	if window == 1 {
+		connection.Cache.Expire(context.Background(), getBreakerWindowKey(c.GetId()), time.Duration(SystemInstance.GetBreakerWindow())*time.Second)
+	}
+
+	if failures >= SystemInstance.GetBreakerFailures() || window >= SystemInstance.GetBreakerWindowFailures() {
+		connection.Cache.Del(context.Background(), getBreakerFailuresKey(c.GetId()), getBreakerWindowKey(c.GetId()))
+		c.setBreakerState(globals.BreakerOpen, reason)
+	}
+}
+
+// ResetBreaker closes the breaker of the channel by the admin
+func (c *Channel) ResetBreaker() {
+	if connection.Cache == nil {
+		return
+	}
+
+	connection.Cache.Del(context.Background(), getBreakerFailuresKey(c.GetId()), getBreakerWindowKey(c.GetId()), getBreakerProbeKey(c.GetId()))
+	c.setBreakerState(globals.BreakerClosed, "reset by admin")
+}
+
+// recordBreaker feeds the result of the request to the breaker, the signal error is the interruption of the client
+// and the errors caused by the request (e.g. the invalid parameters or the content filter) are not counted
+func (c *Channel) recordBreaker(err error) {
+	if err == nil || err.Error() == "signal" {
+		c.RecordSuccess()
+	} else if isBreakerError(err) {
+		c.RecordFailure(err)
+	} else if c.probing {
+		c.releaseProbe()
+	}
+}
+
+// GetBreakerEvents returns the recent state transitions of the circuit breakers
+func GetBreakerEvents() []BreakerEvent {
+	events := make([]BreakerEvent, 0)
+	if connection.Cache == nil {
+		return events
+	}
+
+	data, err := connection.Cache.LRange(context.Background(), breakerEventsKey, 0, maxBreakerEvents-1).Result()
+	if err != nil {
+		return events
+	}
+
+	for _, item := range data {
+		if event := utils.UnmarshalForm[BreakerEvent](item); event != nil {
+			events = append(events, *event)
+		}
+	}
+	return events
+}
@@ -0,0 +1,161 @@
+package channel
+
+import (
+	"chat/connection"
+	"chat/connection/redistest"
+	"chat/globals"
+	"chat/utils"
+	"errors"
+	"testing"
+	"time"
+)
+
+// useBreaker enables the circuit breaker with the config until the test is finished
+func useBreaker(t *testing.T, conf breakerState) *redistest.Server {
+	stub := useRedisStub(t)
+
+	system := SystemInstance
+	SystemInstance = &SystemConfig{Breaker: conf}
+	t.Cleanup(func() {
+		SystemInstance = system
+	})
+	return stub
+}
+
+// expireCooldown moves the transition of the breaker before the cooldown, as if the cooldown is over
+func expireCooldown(t *testing.T, c *Channel) {
+	state := c.GetBreakerState()
+	state.Since = time.Now().Unix() - SystemInstance.GetBreakerCooldown() - 1
+	if err := utils.SetJson(connection.Cache, getBreakerStateKey(c.GetId()), state, 0); err != nil {
+		t.Fatalf("cannot set breaker state: %s", err)
+	}
+}
+
+func assertBreaker(t *testing.T, c *Channel, state string, available bool) {
+	t.Helper()
+	if got := c.GetBreakerState().State; got != state {
+		t.Fatalf("breaker state = %s, want %s", got, state)
+	}
+	if got := c.IsBreakerAvailable(); got != available {
+		t.Fatalf("breaker available = %t, want %t", got, available)
+	}
+}
+
+func TestIsBreakerError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("signal"), false},
+		{errors.New("request failed with status: 502 Bad Gateway"), true},
+		{errors.New("chatgpt error: That model is currently overloaded with other requests. (type: server_error)"), true},
+		{errors.New("Post \"https://api.openai.com\": dial tcp: connection refused"), true},
+		{errors.New("context deadline exceeded (Client.Timeout exceeded while awaiting headers)"), true},
+		{errors.New("request failed with status: 400 Bad Request"), false},
+		{errors.New("request failed with status: 401 Unauthorized"), false},
+		{errors.New("This model's maximum context length is 4097 tokens"), false},
+		{errors.New("gemini error: the response was blocked by safety filters"), false},
+	}
+
+	for _, tc := range cases {
+		if got := isBreakerError(tc.err); got != tc.want {
+			t.Errorf("isBreakerError(%v) = %t, want %t", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestBreakerTransitions(t *testing.T) {
+	useBreaker(t, breakerState{Enabled: true, Failures: 3, WindowFailures: 10, Cooldown: 30})
+	c := &Channel{Id: 1, Name: "primary"}
+	failure := errors.New("request failed with status: 503 Service Unavailable")
+
+	// the client errors say nothing about the upstream
+	for i := 0; i < 5; i++ {
+		c.recordBreaker(errors.New("request failed with status: 400 Bad Request"))
+	}
+	assertBreaker(t, c, globals.BreakerClosed, true)
+
+	for i := 0; i < 3; i++ {
+		c.recordBreaker(failure)
+	}
+	assertBreaker(t, c, globals.BreakerOpen, false)
+
+	// the cooldown is over, the check does not take the probe lock
+	expireCooldown(t, c)
+	assertBreaker(t, c, globals.BreakerOpen, true)
+	assertBreaker(t, c, globals.BreakerOpen, true)
+
+	probing, ok := c.acquireProbe()
+	if !probing || !ok {
+		t.Fatalf("acquireProbe() = %t, %t, want the probe lock", probing, ok)
+	}
+	assertBreaker(t, c, globals.BreakerHalfOpen, false)
+
+	if _, ok := c.acquireProbe(); ok {
+		t.Fatal("acquireProbe() passes the second probe request")
+	}
+
+	// the failed probe opens the breaker again
+	c.recordBreaker(failure)
+	assertBreaker(t, c, globals.BreakerOpen, false)
+
+	expireCooldown(t, c)
+	if probing, ok := c.acquireProbe(); !probing || !ok {
+		t.Fatalf("acquireProbe() = %t, %t, want the probe lock", probing, ok)
+	}
+	c.recordBreaker(nil)
+	assertBreaker(t, c, globals.BreakerClosed, true)
+
+	var transitions []string
+	for _, event := range GetBreakerEvents() {
+		transitions = append([]string{event.From + ">" + event.To}, transitions...)
+	}
+	want := []string{"closed>open", "open>half-open", "half-open>open", "open>half-open", "half-open>closed"}
+	if utils.Marshal(transitions) != utils.Marshal(want) {
+		t.Errorf("transitions = %v, want %v", transitions, want)
+	}
+}
+
+func TestBreakerWindow(t *testing.T) {
+	useBreaker(t, breakerState{Enabled: true, Failures: 3, Window: 60, WindowFailures: 3, Cooldown: 30})
+	c := &Channel{Id: 1, Name: "flaky"}
+	failure := errors.New("read tcp: connection reset by peer")
+
+	// the success resets the consecutive failures but the intermittent failures still count in the window
+	for i := 0; i < 2; i++ {
+		c.recordBreaker(failure)
+		c.recordBreaker(nil)
+	}
+	assertBreaker(t, c, globals.BreakerClosed, true)
+
+	c.recordBreaker(failure)
+	assertBreaker(t, c, globals.BreakerOpen, false)
+}
+
+func TestBreakerReleaseProbe(t *testing.T) {
+	stub := useBreaker(t, breakerState{Enabled: true, Failures: 1, Cooldown: 30})
+	c := &Channel{Id: 1, Name: "probe"}
+
+	c.recordBreaker(errors.New("request failed with status: 500 Internal Server Error"))
+	expireCooldown(t, c)
+
+	probing, _ := c.acquireProbe()
+	instance := c.WithSecret("sk-test")
+	instance.probing = probing
+
+	// the probe request fails for the request itself, the next request probes the channel again
+	instance.recordBreaker(errors.New("request failed with status: 400 Bad Request"))
+	if value := stub.Get(getBreakerProbeKey(c.GetId())); value != "" {
+		t.Fatalf("probe lock = %q, want released", value)
+	}
+	assertBreaker(t, c, globals.BreakerHalfOpen, true)
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	useBreaker(t, breakerState{Enabled: false, Failures: 1})
+	c := &Channel{Id: 1, Name: "disabled"}
+
+	c.recordBreaker(errors.New("request failed with status: 500 Internal Server Error"))
+	assertBreaker(t, c, globals.BreakerClosed, true)
+}
@@ -1,6 +1,7 @@
 package channel
 
 import (
+	"chat/adapter"
 	"chat/utils"
 	"errors"
 	"fmt"
@@ -41,6 +42,14 @@ func (c *Channel) GetModels() []string {
 	return c.Models
 }
 
+// GetCost returns the relative cost of the upstream, 1 if it is not set
+func (c *Channel) GetCost() float32 {
+	if c.Cost <= 0 {
+		return 1
+	}
+	return c.Cost
+}
+
 func (c *Channel) GetRetry() int {
 	if c.Retry <= 0 {
 		return defaultMaxRetries
@@ -52,8 +61,12 @@ func (c *Channel) GetSecret() string {
 	return c.Secret
 }
 
-// GetRandomSecret returns a random secret from the secret list
+// GetRandomSecret returns a secret from the secret list, weighted toward the healthy secrets
 func (c *Channel) GetRandomSecret() string {
+	if secret, err := c.PickSecret(); err == nil {
+		return secret
+	}
+
 	arr := strings.Split(c.GetSecret(), "\n")
 	if len(arr) == 0 {
 		return ""
@@ -95,6 +108,26 @@ func (c *Channel) GetMapper() string {
 	return c.Mapper
 }
 
+// IsDynamicModels returns whether the models of the channel can be listed from the upstream (e.g. ollama)
+func (c *Channel) IsDynamicModels() bool {
+	return adapter.IsModelListable(c.GetType())
+}
+
+// SyncModels fills the models of the channel with the models served by the upstream
+func (c *Channel) SyncModels() error {
+	models, err := adapter.ListModels(c)
+	if err != nil {
+		return err
+	}
+
+	if len(models) == 0 {
+		return fmt.Errorf("no models are served by channel %s", c.GetName())
+	}
+
+	c.Models = models
+	return nil
+}
+
 func (c *Channel) Load() {
 	reflect := make(map[string]string)
 	exclude := make([]string, 0)
@@ -134,6 +167,27 @@ func (c *Channel) Load() {
 	}
 
 	c.HitModels = &hits
+
+	if c.Azure != nil {
+		c.Azure.Load()
+	}
+}
+
+// Validate returns the error of the channel settings, it is checked before the channel is saved
+func (c *Channel) Validate() error {
+	if len(strings.TrimSpace(c.GetName())) == 0 {
+		return fmt.Errorf("channel name is required")
+	}
+
+	if c.Cost < 0 {
+		return fmt.Errorf("cost of channel %s cannot be negative", c.GetName())
+	}
+
+	if err := c.validateLimit(); err != nil {
+		return err
+	}
+
+	return c.validateAzure()
 }
 
 func (c *Channel) GetReflect() map[string]string {
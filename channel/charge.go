@@ -77,6 +77,14 @@ func (m *ChargeManager) GetCharge(model string) *Charge {
 	}
 }
 
+// GetChargeOrDefault returns the charge of the model, or the charge of the fallback model if there is no rule for the model
+func (m *ChargeManager) GetChargeOrDefault(model string, fallback string) *Charge {
+	if charge, ok := m.Models[model]; ok {
+		return charge
+	}
+	return m.GetCharge(fallback)
+}
+
 func (m *ChargeManager) SaveConfig() error {
 	viper.Set("charge", m.Sequence)
 	m.Load()
@@ -282,6 +290,9 @@ func (c *Charge) GetLimit() float32 {
 	case globals.TokenBilling:
 		// 1k input tokens + 1k output tokens
 		return c.GetInput() + c.GetOutput()
+	case globals.AudioBilling:
+		// 1k characters + 1 minute of audio
+		return c.GetInput() + c.GetOutput()
 	default:
 		return 0
 	}
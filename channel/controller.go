@@ -99,6 +99,109 @@ func UpdateChannel(c *gin.Context) {
 	})
 }
 
+func GetBreakerList(c *gin.Context) {
+	states := make(map[int]BreakerState)
+	for _, channel := range ConduitInstance.Sequence {
+		states[channel.Id] = channel.GetBreakerState()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": true,
+		"data": gin.H{
+			"enabled": SystemInstance.IsBreakerEnabled(),
+			"states":  states,
+			"events":  GetBreakerEvents(),
+		},
+	})
+}
+
+func ResetBreaker(c *gin.Context) {
+	id := c.Param("id")
+	channel := ConduitInstance.Sequence.GetChannelById(utils.ParseInt(id))
+	if channel == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status": false,
+			"error":  "channel not found",
+		})
+		return
+	}
+
+	channel.ResetBreaker()
+	c.JSON(http.StatusOK, gin.H{
+		"status": true,
+	})
+}
+
+func GetHealthList(c *gin.Context) {
+	records := make(map[int][]HealthRecord)
+	for _, channel := range ConduitInstance.Sequence {
+		records[channel.Id] = channel.GetHealthRecords()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": true,
+		"data": gin.H{
+			"enabled":  SystemInstance.IsHealthEnabled(),
+			"interval": SystemInstance.GetHealthInterval(),
+			"records":  records,
+		},
+	})
+}
+
+func TestChannelHealth(c *gin.Context) {
+	id := c.Param("id")
+	channel := ConduitInstance.Sequence.GetChannelById(utils.ParseInt(id))
+	if channel == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status": false,
+			"error":  "channel not found",
+		})
+		return
+	}
+
+	records, err := channel.TestHealth()
+	c.JSON(http.StatusOK, gin.H{
+		"status": err == nil,
+		"error":  utils.GetError(err),
+		"data":   records,
+	})
+}
+
+func GetSecretList(c *gin.Context) {
+	id := c.Param("id")
+	channel := ConduitInstance.Sequence.GetChannelById(utils.ParseInt(id))
+	if channel == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status": false,
+			"error":  "channel not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": true,
+		"data":   channel.GetSecretStates(),
+	})
+}
+
+func EnableSecret(c *gin.Context) {
+	id := c.Param("id")
+	channel := ConduitInstance.Sequence.GetChannelById(utils.ParseInt(id))
+	if channel == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status": false,
+			"error":  "channel not found",
+		})
+		return
+	}
+
+	state := channel.EnableSecret(utils.ParseInt(c.Param("key")))
+	c.JSON(http.StatusOK, gin.H{
+		"status": state == nil,
+		"error":  utils.GetError(state),
+	})
+}
+
 func SetCharge(c *gin.Context) {
 	var charge Charge
 	if err := c.ShouldBindJSON(&charge); err != nil {
@@ -0,0 +1,222 @@
+package channel
+
+import (
+	"chat/adapter"
+	"chat/connection"
+	"chat/globals"
+	"chat/utils"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxHealthRecords is the max health check records kept for each channel
+const maxHealthRecords = 50
+
+// healthCheckTimeout is the max duration of the health check request
+const healthCheckTimeout = 30 * time.Second
+
+const healthLockKey = ":health:lock"
+
+// HealthRecord is the result of the health check request for one secret of the channel
+type HealthRecord struct {
+	Key     int    `json:"key"`    // index of the secret line
+	Secret  string `json:"secret"` // masked secret
+	Model   string `json:"model"`
+	Success bool   `json:"success"`
+	Latency int64  `json:"latency"` // milliseconds
+	Error   string `json:"error"`
+	Skipped bool   `json:"skipped"` // the channel does not serve any chat model to test with
+	Time    int64  `json:"time"`
+}
+
+func getHealthKey(id int) string {
+	return fmt.Sprintf(":health:%d", id)
+}
+
+// GetHealthModel returns the model to test the channel with, the first chat model of the channel is used
+// if the channel does not serve the test model, so no image or embedding request is sent on every interval
+func (c *Channel) GetHealthModel() string {
+	if model := SystemInstance.GetHealthModel(); c.IsHit(model) {
+		return model
+	}
+
+	for _, model := range c.GetHitModels() {
+		if globals.IsChatModel(model) {
+			return model
+		}
+	}
+	return ""
+}
+
+// testSecret sends the cheap chat request to the channel with one of its secrets
+func (c *Channel) testSecret(idx int, secret string, model string) HealthRecord {
+	record := HealthRecord{
+		Key:    idx,
+		Secret: maskSecret(secret),
+		Model:  model,
+		Time:   time.Now().Unix(),
+	}
+
+	// the copy of the channel only uses the secret under test
+	instance := c.WithSecret(secret)
+	instance.Retry = 1
+
+	message := []globals.Message{{Role: globals.User, Content: "hi"}}
+	props := &adapter.ChatProps{
+		Model:   model,
+		Message: message,
+		Token:   16,
+		Buffer:  utils.NewBuffer(model, message, ChargeInstance.GetCharge(model)),
+	}
+	props.MaxRetries = utils.ToPtr(1)
+
+	start := time.Now()
+	result := make(chan error, 1)
+	go func() {
+		// the scheduled check runs outside of the gin recovery, so the panic of the adapter must not kill the server
+		defer func() {
+			if r := recover(); r != nil {
+				result <- fmt.Errorf("health check panicked: %v", r)
+			}
+		}()
+
+		result <- adapter.NewChatRequest(instance, props, func(data string) error {
+			return nil
+		})
+	}()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-time.After(healthCheckTimeout):
+		err = fmt.Errorf("health check timed out after %s", healthCheckTimeout)
+	}
+
+	record.Latency = time.Since(start).Milliseconds()
+	record.Success = err == nil
+	if err != nil {
+		record.Error = strings.TrimSpace(strings.Split(err.Error(), "\n")[0])
+	}
+
+	// the passed check enables the secret again, e.g. the quota of the disabled secret is recharged
+	c.RecordSecret(secret, err)
+	if err == nil {
+		c.UpdateSecretBalance(secret)
+	}
+	return record
+}
+
+// TestHealth tests every secret of the channel and records the results, the skipped check is recorded
+// if the channel cannot be tested with the chat request
+func (c *Channel) TestHealth() ([]HealthRecord, error) {
+	model := c.GetHealthModel()
+	if len(model) == 0 {
+		return nil, c.skipHealth(model, fmt.Errorf("channel %s does not serve any chat model to test with", c.GetName()))
+	}
+
+	if !adapter.IsChatSupported(c, &adapter.ChatProps{Model: model}) {
+		return nil, c.skipHealth(model, fmt.Errorf("channel %s does not support the chat request for model %s", c.GetName(), model))
+	}
+
+	secrets := c.GetSecrets()
+	records := make([]HealthRecord, len(secrets))
+
+	var wg sync.WaitGroup
+	for idx, secret := range secrets {
+		wg.Add(1)
+		go func(idx int, secret string) {
+			defer wg.Done()
+			records[idx] = c.testSecret(idx, secret, model)
+		}(idx, secret)
+	}
+	wg.Wait()
+
+	c.saveHealthRecords(records)
+	return records, nil
+}
+
+// skipHealth records the skipped check of the channel and returns the reason
+func (c *Channel) skipHealth(model string, err error) error {
+	c.saveHealthRecords([]HealthRecord{{
+		Key:     -1,
+		Model:   model,
+		Error:   err.Error(),
+		Skipped: true,
+		Time:    time.Now().Unix(),
+	}})
+	return err
+}
+
+func (c *Channel) saveHealthRecords(records []HealthRecord) {
+	if connection.Cache == nil || len(records) == 0 {
+		return
+	}
+
+	values := make([]interface{}, 0, len(records))
+	for _, record := range records {
+		values = append(values, utils.Marshal(record))
+	}
+
+	ctx := context.Background()
+	connection.Cache.LPush(ctx, getHealthKey(c.GetId()), values...)
+	connection.Cache.LTrim(ctx, getHealthKey(c.GetId()), 0, maxHealthRecords-1)
+}
+
+// GetHealthRecords returns the recent health check records of the channel, the latest first
+func (c *Channel) GetHealthRecords() []HealthRecord {
+	records := make([]HealthRecord, 0)
+	if connection.Cache == nil {
+		return records
+	}
+
+	data, err := connection.Cache.LRange(context.Background(), getHealthKey(c.GetId()), 0, maxHealthRecords-1).Result()
+	if err != nil {
+		return records
+	}
+
+	for _, item := range data {
+		if record := utils.UnmarshalForm[HealthRecord](item); record != nil {
+			records = append(records, *record)
+		}
+	}
+	return records
+}
+
+// CheckHealth tests the active channels, the lock lets one node run the checks of each interval
+func (m *Manager) CheckHealth() {
+	interval := SystemInstance.GetHealthInterval()
+	if acquired, err := connection.Cache.SetNX(context.Background(), healthLockKey, 1, time.Duration(interval-1)*time.Second).Result(); err != nil || !acquired {
+		return
+	}
+
+	for _, channel := range m.GetActiveSequence() {
+		records, err := channel.TestHealth()
+		if err != nil {
+			globals.Debug(fmt.Sprintf("[health] skip channel %s: %s", channel.GetName(), err.Error()))
+			continue
+		}
+
+		failed := len(utils.Filter(records, func(record HealthRecord) bool {
+			return !record.Success
+		}))
+		if failed > 0 {
+			globals.Warn(fmt.Sprintf("[health] %d/%d keys of channel %s failed the health check", failed, len(records), channel.GetName()))
+		}
+	}
+}
+
+// HealthWorker runs the scheduled health checks in the background
+func HealthWorker() {
+	go func() {
+		for {
+			time.Sleep(time.Duration(SystemInstance.GetHealthInterval()) * time.Second)
+
+			if SystemInstance.IsHealthEnabled() && connection.Cache != nil {
+				ConduitInstance.CheckHealth()
+			}
+		}
+	}()
+}
@@ -0,0 +1,54 @@
+package channel
+
+import (
+	"chat/globals"
+	"testing"
+)
+
+func TestGetHealthModel(t *testing.T) {
+	system := SystemInstance
+	SystemInstance = &SystemConfig{Health: healthState{Model: globals.GPT3Turbo}}
+	t.Cleanup(func() {
+		SystemInstance = system
+	})
+
+	cases := []struct {
+		name   string
+		models []string
+		want   string
+	}{
+		{"test model", []string{globals.GPT4, globals.GPT3Turbo}, globals.GPT3Turbo},
+		{"first chat model", []string{"text-embedding-ada-002", globals.Dalle3, globals.GPT4}, globals.GPT4},
+		{"no chat model", []string{"text-embedding-3-small", globals.Dalle3, "tts-1", "whisper-1"}, ""},
+	}
+
+	for _, tc := range cases {
+		c := &Channel{Id: 1, Name: tc.name, Type: globals.OpenAIChannelType, Models: tc.models}
+		c.Load()
+
+		if got := c.GetHealthModel(); got != tc.want {
+			t.Errorf("%s: GetHealthModel() = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestHealthSkipped(t *testing.T) {
+	useRedisStub(t)
+	system := SystemInstance
+	SystemInstance = &SystemConfig{}
+	t.Cleanup(func() {
+		SystemInstance = system
+	})
+
+	c := &Channel{Id: 1, Name: "embedding", Type: globals.OpenAIChannelType, Models: []string{"text-embedding-ada-002"}, Secret: "sk-test"}
+	c.Load()
+
+	if _, err := c.TestHealth(); err == nil {
+		t.Fatal("TestHealth() tests the channel without any chat model")
+	}
+
+	records := c.GetHealthRecords()
+	if len(records) != 1 || !records[0].Skipped || records[0].Key != -1 {
+		t.Fatalf("health records = %+v, want one skipped record", records)
+	}
+}
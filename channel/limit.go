@@ -0,0 +1,202 @@
+package channel
+
+import (
+	"chat/connection"
+	"chat/globals"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"strconv"
+	"time"
+)
+
+// ErrChannelsSaturated is returned if every channel of the model is saturated by its rate limits until the queue timeout,
+// the relay responds it as the rate limited request instead of the unavailable upstream
+var ErrChannelsSaturated = errors.New("channels are saturated by the rate limits")
+
+// limitPollInterval is the interval to retry the saturated priority tier while the request is queued
+const limitPollInterval = 200 * time.Millisecond
+
+// maxLimitQueue is the max seconds the request waits for the saturated priority tier
+const maxLimitQueue = 60
+
+// limitConcurrencyExpiration is the max duration of the concurrency slot,
+// so the slots leaked by a crashed node are released
+const limitConcurrencyExpiration = 10 * time.Minute
+
+func getLimitRPMKey(id int, minute int64) string {
+	return fmt.Sprintf(":limit:rpm:%d:%d", id, minute)
+}
+
+func getLimitTPMKey(id int, minute int64) string {
+	return fmt.Sprintf(":limit:tpm:%d:%d", id, minute)
+}
+
+func getLimitConcurrencyKey(id int) string {
+	return fmt.Sprintf(":limit:concurrency:%d", id)
+}
+
+func (c *Channel) GetRPM() int {
+	return c.RPM
+}
+
+func (c *Channel) GetTPM() int {
+	return c.TPM
+}
+
+func (c *Channel) GetConcurrency() int {
+	return c.Concurrency
+}
+
+// GetQueue returns the seconds the request waits for the channel if it is saturated
+func (c *Channel) GetQueue() int {
+	if c.Queue > maxLimitQueue {
+		return maxLimitQueue
+	}
+	return c.Queue
+}
+
+// HasLimit returns whether the channel has any rate limit, the limits are not enforced without the cache
+func (c *Channel) HasLimit() bool {
+	return connection.Cache != nil && (c.GetRPM() > 0 || c.GetTPM() > 0 || c.GetConcurrency() > 0)
+}
+
+func (c *Channel) validateLimit() error {
+	if c.RPM < 0 || c.TPM < 0 || c.Concurrency < 0 || c.Queue < 0 {
+		return fmt.Errorf("rate limits of channel %s cannot be negative", c.GetName())
+	} else if c.Queue > maxLimitQueue {
+		return fmt.Errorf("queue timeout of channel %s cannot exceed %d seconds", c.GetName(), maxLimitQueue)
+	}
+	return nil
+}
+
+// Acquire takes the rate limit slot of the request with the estimated input tokens and counts it in flight,
+// false is returned if the channel is saturated, the slot is the member of the concurrency set to release
+func (c *Channel) Acquire(tokens int) (string, bool) {
+	slot, ok := c.acquireLimit(tokens)
+	if !ok {
+		return "", false
+	}
+
+	c.beginRequest()
+	return slot, true
+}
+
+// acquireLimit increases the rate limit counters, the counters taken so far are rolled back if the channel
+// is saturated or the cache fails, the request passes if the cache fails
+func (c *Channel) acquireLimit(tokens int) (string, bool) {
+	if !c.HasLimit() {
+		return "", true
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+	minute := now.Unix() / 60
+	rpm, tpm := getLimitRPMKey(c.GetId(), minute), getLimitTPMKey(c.GetId(), minute)
+
+	var rollbacks []func()
+	release := func() {
+		for idx := len(rollbacks) - 1; idx >= 0; idx-- {
+			rollbacks[idx]()
+		}
+	}
+	failOpen := func(err error) (string, bool) {
+		release()
+		globals.Warn(fmt.Sprintf("[channel] cannot check rate limits of channel %s: %s", c.GetName(), err.Error()))
+		return "", true
+	}
+
+	if c.GetRPM() > 0 {
+		count, err := connection.Cache.Incr(ctx, rpm).Result()
+		if err != nil {
+			return failOpen(err)
+		}
+
+		rollbacks = append(rollbacks, func() {
+			connection.Cache.Decr(ctx, rpm)
+		})
+		if count == 1 {
+			connection.Cache.Expire(ctx, rpm, 2*time.Minute)
+		}
+		if count > int64(c.GetRPM()) {
+			release()
+			return "", false
+		}
+	}
+
+	if c.GetTPM() > 0 {
+		used, err := connection.Cache.Get(ctx, tpm).Int64()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return failOpen(err)
+		}
+
+		// the request is allowed while the budget of the minute is not used up, so one long prompt is never blocked forever
+		if used >= int64(c.GetTPM()) {
+			release()
+			return "", false
+		}
+
+		if err := connection.Cache.IncrBy(ctx, tpm, int64(tokens)).Err(); err != nil {
+			return failOpen(err)
+		}
+
+		rollbacks = append(rollbacks, func() {
+			connection.Cache.DecrBy(ctx, tpm, int64(tokens))
+		})
+		connection.Cache.Expire(ctx, tpm, 2*time.Minute)
+	}
+
+	if c.GetConcurrency() > 0 {
+		key := getLimitConcurrencyKey(c.GetId())
+		slot := uuid.NewString()
+		rollbacks = append(rollbacks, func() {
+			connection.Cache.ZRem(ctx, key, slot)
+		})
+
+		// every request is the member scored by its start time, the slots of the crashed nodes are dropped
+		// once they are older than the expiration, no matter how busy the channel is
+		var count *redis.IntCmd
+		if _, err := connection.Cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Add(-limitConcurrencyExpiration).UnixMilli(), 10))
+			pipe.ZAdd(ctx, key, &redis.Z{Score: float64(now.UnixMilli()), Member: slot})
+			count = pipe.ZCard(ctx, key)
+			pipe.Expire(ctx, key, limitConcurrencyExpiration)
+			return nil
+		}); err != nil {
+			return failOpen(err)
+		}
+
+		if count.Val() > int64(c.GetConcurrency()) {
+			release()
+			return "", false
+		}
+		return slot, true
+	}
+
+	return "", true
+}
+
+// Release frees the concurrency slot of the finished request and counts its output tokens to the tpm limit
+func (c *Channel) Release(slot string, tokens int) {
+	c.endRequest()
+	c.releaseLimit(slot, tokens)
+}
+
+func (c *Channel) releaseLimit(slot string, tokens int) {
+	if !c.HasLimit() {
+		return
+	}
+
+	ctx := context.Background()
+	if c.GetConcurrency() > 0 && len(slot) > 0 {
+		connection.Cache.ZRem(ctx, getLimitConcurrencyKey(c.GetId()), slot)
+	}
+
+	if c.GetTPM() > 0 && tokens > 0 {
+		key := getLimitTPMKey(c.GetId(), time.Now().Unix()/60)
+		connection.Cache.IncrBy(ctx, key, int64(tokens))
+		connection.Cache.Expire(ctx, key, 2*time.Minute)
+	}
+}
@@ -0,0 +1,159 @@
+package channel
+
+import (
+	"chat/connection"
+	"context"
+	"github.com/go-redis/redis/v8"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func currentMinute() int64 {
+	return time.Now().Unix() / 60
+}
+
+// useMetrics resets the metrics and the round-robin cursors of the node until the test is finished
+func useMetrics(t *testing.T) {
+	metrics := metricsInstance
+	metricsInstance = &metricsCollector{
+		channels: map[int]*ChannelMetrics{},
+		cursors:  map[string]int{},
+	}
+	t.Cleanup(func() {
+		metricsInstance = metrics
+	})
+}
+
+func TestAcquireRPM(t *testing.T) {
+	useMetrics(t)
+	stub := useRedisStub(t)
+	c := &Channel{Id: 101, Name: "rpm", RPM: 2}
+
+	for i := 0; i < 2; i++ {
+		if _, ok := c.Acquire(0); !ok {
+			t.Fatalf("request %d is rejected under the rpm limit", i)
+		}
+	}
+	if _, ok := c.Acquire(0); ok {
+		t.Fatal("request over the rpm limit is accepted")
+	}
+
+	// the rejected request does not use up the budget of the minute
+	if count := stub.Get(getLimitRPMKey(c.GetId(), currentMinute())); count != "2" {
+		t.Errorf("rpm counter = %s, want 2", count)
+	}
+}
+
+func TestAcquireTPM(t *testing.T) {
+	useMetrics(t)
+	stub := useRedisStub(t)
+	c := &Channel{Id: 102, Name: "tpm", TPM: 100}
+	used := func() string {
+		return stub.Get(getLimitTPMKey(c.GetId(), currentMinute()))
+	}
+
+	slot, ok := c.Acquire(60)
+	if !ok {
+		t.Fatal("request is rejected under the tpm limit")
+	}
+	c.Release(slot, 30)
+	if used() != "90" {
+		t.Fatalf("tpm counter = %s, want the input and the output tokens 90", used())
+	}
+
+	// the request passes while the budget is not used up, even if its prompt exceeds the rest
+	if _, ok := c.Acquire(20); !ok {
+		t.Fatal("request is rejected before the tpm budget is used up")
+	}
+	if _, ok := c.Acquire(1); ok {
+		t.Fatal("request is accepted after the tpm budget is used up")
+	}
+	if used() != "110" {
+		t.Errorf("tpm counter = %s, want 110", used())
+	}
+}
+
+func TestAcquireConcurrency(t *testing.T) {
+	useMetrics(t)
+	stub := useRedisStub(t)
+	c := &Channel{Id: 103, Name: "concurrency", RPM: 100, TPM: 1000, Concurrency: 2}
+	key := getLimitConcurrencyKey(c.GetId())
+
+	first, ok := c.Acquire(10)
+	if !ok || len(first) == 0 {
+		t.Fatalf("Acquire() = %q, %t, want the concurrency slot", first, ok)
+	}
+	if _, ok := c.Acquire(10); !ok {
+		t.Fatal("request is rejected under the concurrency limit")
+	}
+
+	// the rejected request rolls back the counters it has taken
+	if _, ok := c.Acquire(10); ok {
+		t.Fatal("request over the concurrency limit is accepted")
+	}
+	if stub.Card(key) != 2 {
+		t.Errorf("concurrency slots = %d, want 2", stub.Card(key))
+	}
+	if rpm := stub.Get(getLimitRPMKey(c.GetId(), currentMinute())); rpm != "2" {
+		t.Errorf("rpm counter = %s, want 2", rpm)
+	}
+	if tpm := stub.Get(getLimitTPMKey(c.GetId(), currentMinute())); tpm != "20" {
+		t.Errorf("tpm counter = %s, want 20", tpm)
+	}
+	if inflight := c.GetMetrics().Inflight; inflight != 2 {
+		t.Errorf("inflight = %d, want 2", inflight)
+	}
+
+	c.Release(first, 0)
+	if stub.Card(key) != 1 || c.GetMetrics().Inflight != 1 {
+		t.Fatalf("slots = %d, inflight = %d after the release, want 1", stub.Card(key), c.GetMetrics().Inflight)
+	}
+	if _, ok := c.Acquire(10); !ok {
+		t.Fatal("request is rejected after the slot is released")
+	}
+}
+
+func TestAcquireExpiredSlot(t *testing.T) {
+	useMetrics(t)
+	stub := useRedisStub(t)
+	c := &Channel{Id: 104, Name: "leaked", Concurrency: 1}
+	key := getLimitConcurrencyKey(c.GetId())
+
+	// the slot leaked by the crashed node is dropped once it is older than the expiration
+	leaked := time.Now().Add(-limitConcurrencyExpiration - time.Minute).UnixMilli()
+	connection.Cache.ZAdd(context.Background(), key, &redis.Z{Score: float64(leaked), Member: "leaked"})
+
+	if _, ok := c.Acquire(0); !ok {
+		t.Fatal("request is rejected by the expired slot")
+	}
+	if stub.Card(key) != 1 {
+		t.Errorf("concurrency slots = %d, want 1", stub.Card(key))
+	}
+}
+
+func TestAcquireCacheFailure(t *testing.T) {
+	useMetrics(t)
+	stub := useRedisStub(t)
+	c := &Channel{Id: 105, Name: "failing", RPM: 10, TPM: 100, Concurrency: 1}
+
+	// the cache fails after the rpm counter is taken, the request passes and the counter is rolled back
+	stub.Fail("GET")
+	slot, ok := c.Acquire(10)
+	if !ok || len(slot) > 0 {
+		t.Fatalf("Acquire() = %q, %t, want the request to pass without the slot", slot, ok)
+	}
+
+	count, _ := strconv.Atoi(stub.Get(getLimitRPMKey(c.GetId(), currentMinute())))
+	if count != 0 {
+		t.Errorf("rpm counter = %d, want rolled back to 0", count)
+	}
+	if stub.Card(getLimitConcurrencyKey(c.GetId())) != 0 {
+		t.Errorf("concurrency slots = %d, want 0", stub.Card(getLimitConcurrencyKey(c.GetId())))
+	}
+
+	c.Release(slot, 0)
+	if inflight := c.GetMetrics().Inflight; inflight != 0 {
+		t.Errorf("inflight = %d, want 0", inflight)
+	}
+}
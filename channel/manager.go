@@ -1,8 +1,10 @@
 package channel
 
 import (
+	"chat/globals"
 	"chat/utils"
 	"errors"
+	"fmt"
 	"github.com/spf13/viper"
 )
 
@@ -106,7 +108,15 @@ func (m *Manager) GetTicker(model, group string) *Ticker {
 		return nil
 	}
 
-	return NewTicker(m.HitSequence(model), group)
+	ticker := NewTicker(m.HitSequence(model), group).Filter(func(channel *Channel) bool {
+		return channel.IsBreakerAvailable()
+	})
+
+	ticker.Model = model
+	if SystemInstance != nil {
+		ticker.Strategy = SystemInstance.GetRoutingStrategy(model, group)
+	}
+	return ticker
 }
 
 func (m *Manager) Len() int {
@@ -129,15 +139,36 @@ func (m *Manager) SaveConfig() error {
 	return viper.WriteConfig()
 }
 
+// FillModels fills the models of the channel from the upstream if the channel does not specify any models
+func (m *Manager) FillModels(channel *Channel) {
+	if len(channel.GetModels()) > 0 || !channel.IsDynamicModels() {
+		return
+	}
+
+	if err := channel.SyncModels(); err != nil {
+		globals.Warn(fmt.Sprintf("[channel] cannot list models of channel %s: %s", channel.GetName(), err.Error()))
+	}
+}
+
 func (m *Manager) CreateChannel(channel *Channel) error {
+	if err := channel.Validate(); err != nil {
+		return err
+	}
+
+	m.FillModels(channel)
 	channel.Id = m.GetMaxId() + 1
 	m.Sequence = append(m.Sequence, channel)
 	return m.SaveConfig()
 }
 
 func (m *Manager) UpdateChannel(id int, channel *Channel) error {
+	if err := channel.Validate(); err != nil {
+		return err
+	}
+
 	for i, item := range m.Sequence {
 		if item.Id == id {
+			m.FillModels(channel)
 			m.Sequence[i] = channel
 			return m.SaveConfig()
 		}
@@ -0,0 +1,143 @@
+package channel
+
+import (
+	"chat/adapter"
+	"chat/globals"
+	"math"
+	"sync"
+	"time"
+)
+
+// latencyAlpha is the smoothing factor of the ewma latency, the recent requests weigh more
+const latencyAlpha = 0.3
+
+// latencyFailurePenalty is the min latency of the failed request, so the failing upstream does not look fast
+const latencyFailurePenalty = 30 * time.Second
+
+// latencyDecay is the time constant the latency of the idle channel decays with,
+// so the channel left out for being slow is probed again
+const latencyDecay = 5 * time.Minute
+
+// firstTokenHook records the time of the first token of the streaming request, the latency is the time to
+// the first token so the channels with the long answers are not penalised
+func (c *Channel) firstTokenHook(hook globals.Hook) globals.Hook {
+	return func(data string) error {
+		if c.firstToken.IsZero() && len(data) > 0 {
+			c.firstToken = time.Now()
+		}
+		return hook(data)
+	}
+}
+
+// firstCompletionHook records the time of the first token of the streaming completion request
+func (c *Channel) firstCompletionHook(hook adapter.CompletionHook) adapter.CompletionHook {
+	return func(resp *adapter.CompletionResponse) error {
+		if c.firstToken.IsZero() {
+			c.firstToken = time.Now()
+		}
+		return hook(resp)
+	}
+}
+
+// getRequestLatency returns the time to the first token of the request, the whole duration if nothing is streamed
+func (c *Channel) getRequestLatency() time.Duration {
+	if !c.firstToken.IsZero() {
+		return c.firstToken.Sub(c.started)
+	}
+	return time.Since(c.started)
+}
+
+// ChannelMetrics is the live metrics of the channel collected by the node, they are not shared among the nodes
+type ChannelMetrics struct {
+	Inflight    int64   `json:"inflight"`
+	Requests    int64   `json:"requests"`
+	Latency     float64 `json:"latency"` // ewma milliseconds of the time to the first token, 0 if no request is observed
+	LatencyTime int64   `json:"latency_time"`
+}
+
+type metricsCollector struct {
+	mutex    sync.Mutex
+	channels map[int]*ChannelMetrics
+	cursors  map[string]int
+}
+
+var metricsInstance = &metricsCollector{
+	channels: map[int]*ChannelMetrics{},
+	cursors:  map[string]int{},
+}
+
+func (m *metricsCollector) get(id int) *ChannelMetrics {
+	if _, ok := m.channels[id]; !ok {
+		m.channels[id] = &ChannelMetrics{}
+	}
+	return m.channels[id]
+}
+
+// next returns the round-robin cursor of the key and moves it forward
+func (m *metricsCollector) next(key string) int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	cursor := m.cursors[key]
+	m.cursors[key] = cursor + 1
+	return cursor
+}
+
+// GetMetrics returns the live metrics of the channel on this node
+func (c *Channel) GetMetrics() ChannelMetrics {
+	metricsInstance.mutex.Lock()
+	defer metricsInstance.mutex.Unlock()
+
+	return *metricsInstance.get(c.GetId())
+}
+
+// GetLatency returns the ewma latency of the channel in milliseconds, decayed by the idle time since the last request
+func (c *Channel) GetLatency() float64 {
+	metrics := c.GetMetrics()
+	if metrics.Latency == 0 {
+		return 0
+	}
+
+	idle := time.Since(time.UnixMilli(metrics.LatencyTime))
+	return metrics.Latency * math.Exp(-float64(idle)/float64(latencyDecay))
+}
+
+func (c *Channel) beginRequest() {
+	metricsInstance.mutex.Lock()
+	defer metricsInstance.mutex.Unlock()
+
+	metrics := metricsInstance.get(c.GetId())
+	metrics.Inflight++
+	metrics.Requests++
+}
+
+func (c *Channel) endRequest() {
+	metricsInstance.mutex.Lock()
+	defer metricsInstance.mutex.Unlock()
+
+	if metrics := metricsInstance.get(c.GetId()); metrics.Inflight > 0 {
+		metrics.Inflight--
+	}
+}
+
+// observeLatency feeds the duration of the request to the ewma latency, the signal error is the interruption
+// of the client and is not observed
+func (c *Channel) observeLatency(duration time.Duration, err error) {
+	if err != nil && err.Error() == "signal" {
+		return
+	} else if err != nil && duration < latencyFailurePenalty {
+		duration = latencyFailurePenalty
+	}
+
+	metricsInstance.mutex.Lock()
+	defer metricsInstance.mutex.Unlock()
+
+	metrics := metricsInstance.get(c.GetId())
+	latency := float64(duration.Milliseconds())
+	if metrics.Latency == 0 {
+		metrics.Latency = latency
+	} else {
+		metrics.Latency = latencyAlpha*latency + (1-latencyAlpha)*metrics.Latency
+	}
+	metrics.LatencyTime = time.Now().UnixMilli()
+}
@@ -0,0 +1,22 @@
+package channel
+
+import (
+	"chat/connection/redistest"
+	"chat/globals"
+	"github.com/spf13/viper"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// keep the channel logs out of the console and the working directory
+	viper.Set("log.ignore_console", true)
+	globals.Logger.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// useRedisStub replaces the cache with the in-memory redis server until the test is finished
+func useRedisStub(t *testing.T) *redistest.Server {
+	return redistest.Use(t)
+}
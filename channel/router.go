@@ -12,6 +12,12 @@ func Register(app *gin.RouterGroup) {
 	app.GET("/admin/channel/delete/:id", DeleteChannel)
 	app.GET("/admin/channel/activate/:id", ActivateChannel)
 	app.GET("/admin/channel/deactivate/:id", DeactivateChannel)
+	app.GET("/admin/channel/breaker", GetBreakerList)
+	app.GET("/admin/channel/breaker/reset/:id", ResetBreaker)
+	app.GET("/admin/channel/health", GetHealthList)
+	app.GET("/admin/channel/health/test/:id", TestChannelHealth)
+	app.GET("/admin/channel/secret/:id", GetSecretList)
+	app.GET("/admin/channel/secret/:id/enable/:key", EnableSecret)
 
 	app.GET("/admin/charge/list", GetChargeList)
 	app.POST("/admin/charge/set", SetCharge)
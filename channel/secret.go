@@ -0,0 +1,320 @@
+package channel
+
+import (
+	"chat/adapter"
+	"chat/connection"
+	"chat/globals"
+	"chat/utils"
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// secretCooldown is the seconds the secret is skipped after it is rate limited
+const secretCooldown = 60
+
+const (
+	secretErrorAuth      = "auth"
+	secretErrorQuota     = "quota"
+	secretErrorRateLimit = "rate-limit"
+)
+
+var (
+	secretAuthErrors = []string{
+		"status: 401", "unauthorized", "invalid_api_key", "invalid api key", "incorrect api key",
+		"authentication_error", "api key not valid", "account_deactivated",
+	}
+	secretQuotaErrors = []string{
+		"insufficient_quota", "exceeded your current quota", "insufficient balance",
+		"credit balance is too low", "billing_not_active", "余额不足",
+	}
+	secretRateLimitErrors = []string{
+		"status: 429", "rate limit", "rate_limit", "too many requests", "appidqpsoverflowerror",
+	}
+)
+
+// SecretState is the health of one secret in the secret pool of the channel, shared by the nodes via redis
+type SecretState struct {
+	Key            int      `json:"key"`    // index of the secret line
+	Secret         string   `json:"secret"` // masked secret
+	Requests       int64    `json:"requests"`
+	Failures       int64    `json:"failures"` // consecutive failures
+	TotalFailures  int64    `json:"total_failures"`
+	LastError      string   `json:"last_error"`
+	LastErrorTime  int64    `json:"last_error_time"`
+	CooldownUntil  int64    `json:"cooldown_until"` // unix seconds until the rate limited secret is selectable again
+	Disabled       bool     `json:"disabled"`
+	DisabledReason string   `json:"disabled_reason"`
+	Balance        *float64 `json:"balance"` // usd, nil if the channel type cannot query the balance
+	BalanceTime    int64    `json:"balance_time"`
+}
+
+// getSecretKey returns the redis hash of the secret state, the fields are updated one by one with HINCRBY and HSET
+// so the concurrent requests of the nodes do not overwrite each other
+func getSecretKey(id int, secret string) string {
+	return fmt.Sprintf(":secret-state:%d:%s", id, utils.Md5Encrypt(secret))
+}
+
+// parseSecretState reads the fields of the secret state hash
+func parseSecretState(values map[string]string) SecretState {
+	integer := func(field string) int64 {
+		value, _ := strconv.ParseInt(values[field], 10, 64)
+		return value
+	}
+
+	state := SecretState{
+		Requests:       integer("requests"),
+		Failures:       integer("failures"),
+		TotalFailures:  integer("total_failures"),
+		LastError:      values["last_error"],
+		LastErrorTime:  integer("last_error_time"),
+		CooldownUntil:  integer("cooldown_until"),
+		Disabled:       values["disabled"] == "1",
+		DisabledReason: values["disabled_reason"],
+		BalanceTime:    integer("balance_time"),
+	}
+
+	if value, ok := values["balance"]; ok {
+		if balance, err := strconv.ParseFloat(value, 64); err == nil {
+			state.Balance = &balance
+		}
+	}
+	return state
+}
+
+// maskSecret hides the secret except the head and the tail, the endpoint of the `key|endpoint` secret is dropped
+func maskSecret(secret string) string {
+	secret = strings.TrimSpace(strings.Split(secret, "|")[0])
+	if len(secret) <= 8 {
+		return strings.Repeat("*", len(secret))
+	}
+
+	return fmt.Sprintf("%s...%s", secret[:3], secret[len(secret)-4:])
+}
+
+// GetSecrets returns the secret lines of the channel, the empty lines are ignored
+func (c *Channel) GetSecrets() []string {
+	return utils.Filter(strings.Split(c.GetSecret(), "\n"), func(secret string) bool {
+		return len(strings.TrimSpace(secret)) > 0
+	})
+}
+
+// classifySecretError returns the kind of the error which is caused by the secret, empty if it is not
+func classifySecretError(err error) string {
+	content := strings.ToLower(err.Error())
+	match := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			if strings.Contains(content, pattern) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case match(secretQuotaErrors):
+		return secretErrorQuota
+	case match(secretAuthErrors):
+		return secretErrorAuth
+	case match(secretRateLimitErrors):
+		return secretErrorRateLimit
+	default:
+		return ""
+	}
+}
+
+// GetSecretStates returns the states of the secrets of the channel in the order of the secret lines
+func (c *Channel) GetSecretStates() []SecretState {
+	secrets := c.GetSecrets()
+	states := make([]SecretState, len(secrets))
+	for idx, secret := range secrets {
+		states[idx] = SecretState{Key: idx, Secret: maskSecret(secret)}
+	}
+
+	if connection.Cache == nil || len(secrets) == 0 {
+		return states
+	}
+
+	ctx := context.Background()
+	results := make([]*redis.StringStringMapCmd, len(secrets))
+	if _, err := connection.Cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for idx, secret := range secrets {
+			results[idx] = pipe.HGetAll(ctx, getSecretKey(c.GetId(), secret))
+		}
+		return nil
+	}); err != nil {
+		return states
+	}
+
+	for idx, result := range results {
+		state := parseSecretState(result.Val())
+		state.Key, state.Secret = idx, maskSecret(secrets[idx])
+		states[idx] = state
+	}
+	return states
+}
+
+// updateSecretState increases and sets the fields of the secret state in one round trip
+func (c *Channel) updateSecretState(secret string, increments map[string]int64, fields map[string]interface{}) {
+	ctx := context.Background()
+	key := getSecretKey(c.GetId(), secret)
+
+	if _, err := connection.Cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for field, delta := range increments {
+			pipe.HIncrBy(ctx, key, field, delta)
+		}
+		if len(fields) > 0 {
+			pipe.HSet(ctx, key, fields)
+		}
+		return nil
+	}); err != nil {
+		globals.Warn(fmt.Sprintf("[channel] cannot update secret state of channel %s: %s", c.GetName(), err.Error()))
+	}
+}
+
+// resetSecretFields are the fields which enable the secret again
+var resetSecretFields = map[string]interface{}{
+	"failures":        0,
+	"cooldown_until":  0,
+	"disabled":        0,
+	"disabled_reason": "",
+}
+
+// isSecretAvailable returns whether the secret can be selected, the disabled and the rate limited secrets are skipped
+func (s SecretState) isSecretAvailable(now int64) bool {
+	return !s.Disabled && s.CooldownUntil <= now
+}
+
+// getSecretWeight returns the selection weight of the secret, the secret with more consecutive failures gets less traffic
+func (s SecretState) getSecretWeight() int {
+	return 1 + int(16/(1+s.Failures))
+}
+
+// PickSecret returns the secret of the request, weighted toward the healthy secrets of the pool
+func (c *Channel) PickSecret() (string, error) {
+	secrets := c.GetSecrets()
+	if len(secrets) == 0 {
+		return "", fmt.Errorf("channel %s has no secret", c.GetName())
+	} else if connection.Cache == nil || len(secrets) == 1 {
+		return secrets[utils.Intn(len(secrets))], nil
+	}
+
+	now := time.Now().Unix()
+	states := c.GetSecretStates()
+
+	candidates := make([]int, 0, len(secrets))
+	for idx, state := range states {
+		if state.isSecretAvailable(now) {
+			candidates = append(candidates, idx)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("all secrets of channel %s are disabled or rate limited", c.GetName())
+	}
+
+	weight := utils.Each(candidates, func(idx int) int {
+		return states[idx].getSecretWeight()
+	})
+
+	cursor := utils.Intn(utils.Sum(weight))
+	for i, idx := range candidates {
+		cursor -= weight[i]
+		if cursor < 0 {
+			return secrets[idx], nil
+		}
+	}
+	return secrets[candidates[0]], nil
+}
+
+// WithSecret returns the copy of the channel which only uses the secret
+func (c *Channel) WithSecret(secret string) *Channel {
+	instance := *c
+	instance.Secret = secret
+	return &instance
+}
+
+// Pick returns the copy of the channel with the picked secret, so the result of the request is recorded to the secret
+func (c *Channel) Pick() (*Channel, error) {
+	secret, err := c.PickSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	instance := c.WithSecret(secret)
+	instance.started = time.Now()
+	return instance, nil
+}
+
+// RecordSecret records the result of the request sent with the secret, the secret is disabled on the auth
+// or the insufficient quota errors and cooled down on the rate limit errors
+func (c *Channel) RecordSecret(secret string, err error) {
+	if connection.Cache == nil {
+		return
+	}
+
+	if err == nil || err.Error() == "signal" {
+		c.updateSecretState(secret, map[string]int64{"requests": 1}, resetSecretFields)
+		return
+	}
+
+	now := time.Now().Unix()
+	reason := strings.TrimSpace(strings.Split(err.Error(), "\n")[0])
+
+	fields := map[string]interface{}{
+		"last_error":      reason,
+		"last_error_time": now,
+	}
+
+	switch classifySecretError(err) {
+	case secretErrorAuth, secretErrorQuota:
+		globals.Warn(fmt.Sprintf("[channel] disable secret %s of channel %s (reason: %s)", maskSecret(secret), c.GetName(), reason))
+		fields["disabled"] = 1
+		fields["disabled_reason"] = reason
+	case secretErrorRateLimit:
+		fields["cooldown_until"] = now + secretCooldown
+	}
+
+	c.updateSecretState(secret, map[string]int64{"requests": 1, "failures": 1, "total_failures": 1}, fields)
+}
+
+// UpdateSecretBalance queries and records the remaining balance of the secret if the channel type supports it
+func (c *Channel) UpdateSecretBalance(secret string) {
+	if connection.Cache == nil || !adapter.IsBalanceSupported(c.GetType()) {
+		return
+	}
+
+	balance, err := adapter.GetBalance(c.WithSecret(secret))
+	if err != nil {
+		globals.Debug(fmt.Sprintf("[channel] cannot query balance of channel %s: %s", c.GetName(), err.Error()))
+		return
+	}
+
+	c.updateSecretState(secret, nil, map[string]interface{}{
+		"balance":      balance,
+		"balance_time": time.Now().Unix(),
+	})
+}
+
+// EnableSecret enables the secret of the index and resets its failures by the admin
+func (c *Channel) EnableSecret(idx int) error {
+	secrets := c.GetSecrets()
+	if idx < 0 || idx >= len(secrets) {
+		return fmt.Errorf("secret %d of channel %s not found", idx, c.GetName())
+	} else if connection.Cache == nil {
+		return fmt.Errorf("cache is not available")
+	}
+
+	c.updateSecretState(secrets[idx], nil, resetSecretFields)
+	return nil
+}
+
+// record feeds the result of the request sent by the picked channel to its secret, its breaker and its latency
+func (c *Channel) record(err error) {
+	c.RecordSecret(c.GetSecret(), err)
+	c.recordBreaker(err)
+	c.observeLatency(c.getRequestLatency(), err)
+}
@@ -0,0 +1,91 @@
+package channel
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClassifySecretError(t *testing.T) {
+	cases := []struct {
+		err  string
+		want string
+	}{
+		{"request failed with status: 401 Unauthorized", secretErrorAuth},
+		{"chatgpt error: Incorrect API key provided: sk-test. (type: invalid_request_error)", secretErrorAuth},
+		{"gemini error: API key not valid. Please pass a valid API key.", secretErrorAuth},
+		{"chatgpt error: You exceeded your current quota, please check your plan and billing details. (type: insufficient_quota)", secretErrorQuota},
+		{"claude error: Your credit balance is too low to access the Claude API.", secretErrorQuota},
+		{"request failed with status: 429 Too Many Requests", secretErrorRateLimit},
+		{"chatgpt error: Rate limit reached for gpt-4 in organization org-test on tokens per min.", secretErrorRateLimit},
+		{"request failed with status: 502 Bad Gateway", ""},
+		{"This model's maximum context length is 4097 tokens", ""},
+	}
+
+	for _, tc := range cases {
+		if got := classifySecretError(errors.New(tc.err)); got != tc.want {
+			t.Errorf("classifySecretError(%q) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestRecordSecret(t *testing.T) {
+	useRedisStub(t)
+	c := &Channel{Id: 1, Name: "pool", Secret: "sk-auth-00000000\nsk-limit-0000000\nsk-healthy-00000"}
+
+	c.RecordSecret("sk-auth-00000000", errors.New("request failed with status: 401 Unauthorized\n```json\n{}\n```"))
+	c.RecordSecret("sk-limit-0000000", errors.New("request failed with status: 429 Too Many Requests"))
+	c.RecordSecret("sk-healthy-00000", errors.New("request failed with status: 502 Bad Gateway"))
+	c.RecordSecret("sk-healthy-00000", nil)
+
+	states := c.GetSecretStates()
+	now := time.Now().Unix()
+
+	if auth := states[0]; !auth.Disabled || auth.DisabledReason != "request failed with status: 401 Unauthorized" {
+		t.Errorf("auth secret state = %+v, want disabled with the first line of the error", auth)
+	}
+	if limit := states[1]; limit.Disabled || limit.CooldownUntil <= now || limit.isSecretAvailable(now) {
+		t.Errorf("rate limited secret state = %+v, want cooled down", limit)
+	}
+	if healthy := states[2]; healthy.Requests != 2 || healthy.Failures != 0 || healthy.TotalFailures != 1 || !healthy.isSecretAvailable(now) {
+		t.Errorf("healthy secret state = %+v, want 2 requests and the consecutive failures reset", healthy)
+	}
+
+	for i := 0; i < 20; i++ {
+		if secret, err := c.PickSecret(); err != nil || secret != "sk-healthy-00000" {
+			t.Fatalf("PickSecret() = %q, %v, want the healthy secret", secret, err)
+		}
+	}
+
+	if err := c.EnableSecret(0); err != nil {
+		t.Fatalf("EnableSecret() = %s", err)
+	}
+	if auth := c.GetSecretStates()[0]; auth.Disabled || auth.Failures != 0 || auth.TotalFailures != 1 {
+		t.Errorf("enabled secret state = %+v, want enabled with the total failures kept", auth)
+	}
+}
+
+func TestRecordSecretConcurrently(t *testing.T) {
+	useRedisStub(t)
+	c := &Channel{Id: 1, Name: "busy", Secret: "sk-busy-00000000"}
+
+	// the counters are increased in redis, so the concurrent requests of the nodes are not lost
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%5 == 0 {
+				c.RecordSecret("sk-busy-00000000", errors.New("request failed with status: 500 Internal Server Error"))
+			} else {
+				c.RecordSecret("sk-busy-00000000", nil)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if state := c.GetSecretStates()[0]; state.Requests != 50 || state.TotalFailures != 10 {
+		t.Errorf("secret state = %+v, want 50 requests and 10 failures", state)
+	}
+}
@@ -11,6 +11,9 @@ import (
 	"github.com/spf13/viper"
 )
 
+// maxContextReserve is the max tokens of the context length reserved for the reply
+const maxContextReserve = 4096
+
 type ApiInfo struct {
 	Title        string `json:"title"`
 	Logo         string `json:"logo"`
@@ -59,12 +62,68 @@ type searchState struct {
 	Query    int    `json:"query" mapstructure:"query"`
 }
 
+type midjourneyState struct {
+	Timeout int `json:"timeout" mapstructure:"timeout"` // seconds
+}
+
+type moderationState struct {
+	Enabled bool              `json:"enabled" mapstructure:"enabled"`
+	Model   string            `json:"model" mapstructure:"model"`
+	Action  string            `json:"action" mapstructure:"action"` // default action of the flagged input: block, log or allow
+	Groups  map[string]string `json:"groups" mapstructure:"groups"` // action of the flagged input per group
+}
+
+// contextLength is the context length of the model, the lengths are stored as the list like the charge rules,
+// since viper splits the dotted model names (e.g. gpt-3.5-turbo) of the map keys into the nested keys
+type contextLength struct {
+	Model  string `json:"model" mapstructure:"model"`
+	Length int    `json:"length" mapstructure:"length"`
+}
+
+type contextState struct {
+	Summary bool            `json:"summary" mapstructure:"summary"` // replace the overflowed turns with the running summary
+	Model   string          `json:"model" mapstructure:"model"`     // model to summarize with, the model of the conversation by default
+	Length  []contextLength `json:"length" mapstructure:"length"`   // context length per model, overrides the builtin context length
+}
+
+type breakerState struct {
+	Enabled        bool `json:"enabled" mapstructure:"enabled"`
+	Failures       int  `json:"failures" mapstructure:"failures"`              // consecutive failures to open the breaker
+	Window         int  `json:"window" mapstructure:"window"`                  // seconds
+	WindowFailures int  `json:"window_failures" mapstructure:"windowfailures"` // failures in the window to open the breaker
+	Cooldown       int  `json:"cooldown" mapstructure:"cooldown"`              // seconds before the probe request
+}
+
+type healthState struct {
+	Enabled  bool   `json:"enabled" mapstructure:"enabled"`
+	Interval int    `json:"interval" mapstructure:"interval"` // seconds
+	Model    string `json:"model" mapstructure:"model"`       // model to test the channels with
+}
+
+// routingRule is the routing strategy of the model, stored as the list for the dotted model names like the context lengths
+type routingRule struct {
+	Model    string `json:"model" mapstructure:"model"`
+	Strategy string `json:"strategy" mapstructure:"strategy"`
+}
+
+type routingState struct {
+	Strategy string            `json:"strategy" mapstructure:"strategy"` // default strategy to select the channel in the priority tier
+	Models   []routingRule     `json:"models" mapstructure:"models"`     // strategy per model, overrides the group strategy
+	Groups   map[string]string `json:"groups" mapstructure:"groups"`     // strategy per group
+}
+
 type SystemConfig struct {
-	General generalState `json:"general" mapstructure:"general"`
-	Site    siteState    `json:"site" mapstructure:"site"`
-	Phone   phoneState   `json:"phone" mapstructure:"phone"`
-	Mail    mailState    `json:"mail" mapstructure:"mail"`
-	Search  searchState  `json:"search" mapstructure:"search"`
+	General    generalState    `json:"general" mapstructure:"general"`
+	Site       siteState       `json:"site" mapstructure:"site"`
+	Phone      phoneState      `json:"phone" mapstructure:"phone"`
+	Mail       mailState       `json:"mail" mapstructure:"mail"`
+	Search     searchState     `json:"search" mapstructure:"search"`
+	Midjourney midjourneyState `json:"midjourney" mapstructure:"midjourney"`
+	Moderation moderationState `json:"moderation" mapstructure:"moderation"`
+	Context    contextState    `json:"context" mapstructure:"context"`
+	Breaker    breakerState    `json:"breaker" mapstructure:"breaker"`
+	Health     healthState     `json:"health" mapstructure:"health"`
+	Routing    routingState    `json:"routing" mapstructure:"routing"`
 }
 
 func NewSystemConfig() *SystemConfig {
@@ -79,6 +138,7 @@ func NewSystemConfig() *SystemConfig {
 
 func (c *SystemConfig) Load() {
 	globals.NotifyUrl = c.GetBackend()
+	globals.MidjourneyTimeout = c.Midjourney.Timeout
 }
 
 func (c *SystemConfig) SaveConfig() error {
@@ -105,6 +165,12 @@ func (c *SystemConfig) UpdateConfig(data *SystemConfig) error {
 	c.Phone = data.Phone
 	c.Mail = data.Mail
 	c.Search = data.Search
+	c.Midjourney = data.Midjourney
+	c.Moderation = data.Moderation
+	c.Context = data.Context
+	c.Breaker = data.Breaker
+	c.Health = data.Health
+	c.Routing = data.Routing
 
 	return c.SaveConfig()
 }
@@ -234,3 +300,147 @@ func (c *SystemConfig) GetAppLogo() string {
 
 	return logo
 }
+
+func (c *SystemConfig) IsModerationEnabled() bool {
+	return c.Moderation.Enabled
+}
+
+func (c *SystemConfig) GetModerationModel() string {
+	if model := strings.TrimSpace(c.Moderation.Model); len(model) > 0 {
+		return model
+	}
+
+	return globals.TextModerationLatest
+}
+
+// GetModerationAction returns the action of the flagged input for the group, blocked by default
+func (c *SystemConfig) GetModerationAction(group string) string {
+	if action, ok := c.Moderation.Groups[group]; ok && isModerationAction(action) {
+		return action
+	} else if isModerationAction(c.Moderation.Action) {
+		return c.Moderation.Action
+	}
+
+	return globals.ModerationBlock
+}
+
+func isModerationAction(action string) bool {
+	return utils.Contains(action, []string{globals.ModerationBlock, globals.ModerationLog, globals.ModerationAllow})
+}
+
+// GetContextLength returns the context length of the model in tokens
+func (c *SystemConfig) GetContextLength(model string) int {
+	for _, item := range c.Context.Length {
+		if item.Model == model && item.Length > 0 {
+			return item.Length
+		}
+	}
+
+	return utils.GetContextLengthByModel(model)
+}
+
+// GetContextBudget returns the tokens budget of the chat context, the rest of the context length is reserved for the reply
+func (c *SystemConfig) GetContextBudget(model string) int {
+	length := c.GetContextLength(model)
+
+	reserve := length / 4
+	if reserve > maxContextReserve {
+		reserve = maxContextReserve
+	}
+	return length - reserve
+}
+
+func (c *SystemConfig) IsContextSummaryEnabled() bool {
+	return c.Context.Summary
+}
+
+// GetContextSummaryModel returns the model to summarize the context of the conversation with
+func (c *SystemConfig) GetContextSummaryModel(model string) string {
+	if summary := strings.TrimSpace(c.Context.Model); len(summary) > 0 {
+		return summary
+	}
+
+	return model
+}
+
+func (c *SystemConfig) IsBreakerEnabled() bool {
+	return c.Breaker.Enabled
+}
+
+// GetBreakerFailures returns the consecutive failures to open the breaker, 5 by default
+func (c *SystemConfig) GetBreakerFailures() int64 {
+	if c.Breaker.Failures > 0 {
+		return int64(c.Breaker.Failures)
+	}
+	return 5
+}
+
+// GetBreakerWindow returns the window of the failures in seconds, 60 by default
+func (c *SystemConfig) GetBreakerWindow() int64 {
+	if c.Breaker.Window > 0 {
+		return int64(c.Breaker.Window)
+	}
+	return 60
+}
+
+// GetBreakerWindowFailures returns the failures in the window to open the breaker, 10 by default
+func (c *SystemConfig) GetBreakerWindowFailures() int64 {
+	if c.Breaker.WindowFailures > 0 {
+		return int64(c.Breaker.WindowFailures)
+	}
+	return 10
+}
+
+// GetBreakerCooldown returns the seconds before the open breaker sends the probe request, 60 by default
+func (c *SystemConfig) GetBreakerCooldown() int64 {
+	if c.Breaker.Cooldown > 0 {
+		return int64(c.Breaker.Cooldown)
+	}
+	return 60
+}
+
+func (c *SystemConfig) IsHealthEnabled() bool {
+	return c.Health.Enabled
+}
+
+// GetHealthInterval returns the interval of the health checks in seconds, 300 by default and 60 at least
+func (c *SystemConfig) GetHealthInterval() int64 {
+	if c.Health.Interval <= 0 {
+		return 300
+	} else if c.Health.Interval < 60 {
+		return 60
+	}
+	return int64(c.Health.Interval)
+}
+
+// GetHealthModel returns the model to test the channels with
+func (c *SystemConfig) GetHealthModel() string {
+	if model := strings.TrimSpace(c.Health.Model); len(model) > 0 {
+		return model
+	}
+
+	return globals.GPT3Turbo
+}
+
+// GetRoutingStrategy returns the strategy to select the channel for the model in the group, weighted random by default
+func (c *SystemConfig) GetRoutingStrategy(model string, group string) string {
+	for _, rule := range c.Routing.Models {
+		if rule.Model == model && isRoutingStrategy(rule.Strategy) {
+			return rule.Strategy
+		}
+	}
+
+	if strategy, ok := c.Routing.Groups[group]; ok && isRoutingStrategy(strategy) {
+		return strategy
+	} else if isRoutingStrategy(c.Routing.Strategy) {
+		return c.Routing.Strategy
+	}
+
+	return globals.RoutingWeighted
+}
+
+func isRoutingStrategy(strategy string) bool {
+	return utils.Contains(strategy, []string{
+		globals.RoutingWeighted, globals.RoutingRoundRobin, globals.RoutingLeastInflight, globals.RoutingLatency, globals.RoutingCost,
+	})
+}
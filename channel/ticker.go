@@ -1,6 +1,13 @@
 package channel
 
-import "chat/utils"
+import (
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
 
 func NewTicker(seq Sequence, group string) *Ticker {
 	stack := make(Sequence, 0)
@@ -17,33 +24,26 @@ func NewTicker(seq Sequence, group string) *Ticker {
 	}
 }
 
-func (t *Ticker) GetChannelByPriority(priority int) *Channel {
-	var stack Sequence
-
-	for idx, channel := range t.Sequence {
-		if channel.GetPriority() == priority {
-			// get if the next channel has the same priority
-			if idx+1 < len(t.Sequence) && t.Sequence[idx+1].GetPriority() == priority {
-				stack = append(stack, channel)
-				continue
-			}
-
-			if len(stack) == 0 {
-				return channel
-			}
+// Filter removes the channels which cannot serve the request from the ticker
+func (t *Ticker) Filter(fn func(channel *Channel) bool) *Ticker {
+	t.Sequence = utils.Filter(t.Sequence, fn)
+	return t
+}
 
-			// stack is not empty
-			stack = append(stack, channel)
+// GetTier returns the channels of the priority
+func (t *Ticker) GetTier(priority int) Sequence {
+	return utils.Filter(t.Sequence, func(channel *Channel) bool {
+		return channel.GetPriority() == priority
+	})
+}
 
-			// sort by weight and break the loop
-			if idx+1 >= len(t.Sequence) || t.Sequence[idx+1].GetPriority() != priority {
-				stack.Sort()
-				break
-			}
-		}
+// GetChannelByWeight returns the random channel of the sequence, weighted by the channel weight
+func (s Sequence) GetChannelByWeight() *Channel {
+	if len(s) == 0 {
+		return nil
 	}
 
-	weight := utils.Each(stack, func(channel *Channel) int {
+	weight := utils.Each(s, func(channel *Channel) int {
 		return channel.GetWeight()
 	})
 	total := utils.Sum(weight)
@@ -52,16 +52,146 @@ func (t *Ticker) GetChannelByPriority(priority int) *Channel {
 	cursor := utils.Intn(total)
 
 	// get channel by weight
-	for _, channel := range stack {
+	for _, channel := range s {
 		cursor -= channel.GetWeight()
 		if cursor < 0 {
 			return channel
 		}
 	}
 
-	return stack[0]
+	return s[0]
+}
+
+// GetChannelByScore returns the weighted random channel among the channels with the lowest score
+func (s Sequence) GetChannelByScore(score func(channel *Channel) float64) *Channel {
+	var stack Sequence
+	lowest := math.MaxFloat64
+	for _, channel := range s {
+		if value := score(channel); value < lowest {
+			lowest = value
+			stack = Sequence{channel}
+		} else if value == lowest {
+			stack = append(stack, channel)
+		}
+	}
+
+	return stack.GetChannelByWeight()
+}
+
+// GetQueue returns the max seconds the request waits for the saturated sequence
+func (s Sequence) GetQueue() int {
+	queue := 0
+	for _, channel := range s {
+		if channel.GetQueue() > queue {
+			queue = channel.GetQueue()
+		}
+	}
+	return queue
+}
+
+// GetChannelByPriority returns the channel of the priority picked by the routing strategy
+func (t *Ticker) GetChannelByPriority(priority int) *Channel {
+	if tier := t.GetTier(priority); len(tier) > 0 {
+		return t.pick(tier)
+	}
+	return nil
+}
+
+// pick returns the channel of the priority tier by the routing strategy of the ticker
+func (t *Ticker) pick(seq Sequence) *Channel {
+	switch t.Strategy {
+	case globals.RoutingRoundRobin:
+		stack := seq.sortById()
+		return stack[t.nextTurn(seq)%len(stack)]
+	case globals.RoutingLeastInflight:
+		return seq.GetChannelByScore(func(channel *Channel) float64 {
+			return float64(channel.GetMetrics().Inflight)
+		})
+	case globals.RoutingLatency:
+		return seq.GetChannelByScore(func(channel *Channel) float64 {
+			return channel.GetLatency()
+		})
+	case globals.RoutingCost:
+		return seq.GetChannelByScore(func(channel *Channel) float64 {
+			return float64(channel.GetCost())
+		})
+	default:
+		return seq.GetChannelByWeight()
+	}
+}
+
+// sortById returns the copy of the sequence ordered by the id, so the round-robin turn is stable among the requests
+func (s Sequence) sortById() Sequence {
+	stack := append(Sequence{}, s...)
+	sort.Slice(stack, func(i, j int) bool {
+		return stack[i].GetId() < stack[j].GetId()
+	})
+	return stack
+}
+
+// nextTurn returns the round-robin cursor of the priority tier and moves it forward
+func (t *Ticker) nextTurn(tier Sequence) int {
+	return metricsInstance.next(fmt.Sprintf("%s:%d", t.Model, tier[0].GetPriority()))
+}
+
+// take takes the probe lock of the breaker and the rate limit slot of the request for the picked channel,
+// limited is whether the channel is rejected by its rate limits rather than by its open breaker
+func (t *Ticker) take(channel *Channel) (ok bool, limited bool) {
+	probing, ok := channel.acquireProbe()
+	if !ok {
+		return false, false
+	}
+
+	if slot, ok := channel.Acquire(t.Tokens); ok {
+		t.probing, t.slot = probing, slot
+		return true, false
+	}
+
+	if probing {
+		channel.releaseProbe()
+	}
+	return false, true
+}
+
+// acquire returns the channel of the tier picked by the routing strategy which takes the probe lock of
+// its breaker and the rate limit slot of the request, nil if every channel of the tier is unavailable,
+// limited is whether every channel of the tier is rejected by its rate limits, which may free up in the queue
+func (t *Ticker) acquire(tier Sequence) (*Channel, bool) {
+	limited := true
+	if t.Strategy == globals.RoutingRoundRobin {
+		// the turn goes through the whole tier and skips the unavailable channels, so the rotation is not skewed
+		stack := tier.sortById()
+		cursor := t.nextTurn(tier)
+		for idx := range stack {
+			channel := stack[(cursor+idx)%len(stack)]
+			ok, rejected := t.take(channel)
+			if ok {
+				return channel, false
+			}
+			limited = limited && rejected
+		}
+		return nil, limited
+	}
+
+	seq := tier
+	for len(seq) > 0 {
+		channel := t.pick(seq)
+		ok, rejected := t.take(channel)
+		if ok {
+			return channel, false
+		}
+		limited = limited && rejected
+
+		seq = utils.Filter(seq, func(c *Channel) bool {
+			return c != channel
+		})
+	}
+	return nil, limited
 }
 
+// Next returns the channel of the next priority tier, the saturated channels are skipped and the request waits
+// for the saturated tier until the queue timeout of the tier before it falls through to the next tier,
+// the tier is only saturated if every channel is rejected by its rate limits, not by its open breaker
 func (t *Ticker) Next() *Channel {
 	if t.Cursor >= len(t.Sequence) {
 		// out of sequence
@@ -69,10 +199,30 @@ func (t *Ticker) Next() *Channel {
 	}
 
 	priority := t.Sequence[t.Cursor].GetPriority()
-	channel := t.GetChannelByPriority(priority)
+	tier := t.GetTier(priority)
 	t.SkipPriority(priority)
 
-	return channel
+	deadline := time.Now().Add(time.Duration(tier.GetQueue()) * time.Second)
+	for {
+		channel, limited := t.acquire(tier)
+		if channel != nil {
+			return channel
+		}
+
+		if !limited {
+			// the open breaker does not close in the queue, the unavailable tier is not reported as saturated
+			globals.Info(fmt.Sprintf("[channel] channels of priority %d are unavailable, skip to the next priority", priority))
+			t.Saturated, t.broken = false, true
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			globals.Info(fmt.Sprintf("[channel] channels of priority %d are saturated, skip to the next priority", priority))
+			t.Saturated = !t.broken
+			return nil
+		}
+		time.Sleep(limitPollInterval)
+	}
 }
 
 func (t *Ticker) SkipPriority(priority int) {
@@ -91,6 +241,77 @@ func (t *Ticker) IsDone() bool {
 	return t.Cursor >= len(t.Sequence)
 }
 
+// EstimateTokens sets the estimated input tokens of the request, it is only counted if any channel has the tpm limit
+func (t *Ticker) EstimateTokens(fn func() int) {
+	for _, channel := range t.Sequence {
+		if channel.HasLimit() && channel.GetTPM() > 0 {
+			t.Tokens = fn()
+			return
+		}
+	}
+}
+
+// GetError returns the error of the ticker which is exhausted without sending any request
+func (t *Ticker) GetError(err error) error {
+	if err == nil && t.Saturated {
+		return ErrChannelsSaturated
+	}
+	return err
+}
+
 func (t *Ticker) IsEmpty() bool {
 	return len(t.Sequence) == 0
 }
+
+// Run sends the request to the channels tier by tier until one of them succeeds, fn is called with the copy of
+// the channel with the picked secret, the signal error is the interruption of the client and ends the run
+func (t *Ticker) Run(fn func(instance *Channel) error) error {
+	var err error
+	for !t.IsDone() {
+		channel := t.Next()
+		if channel == nil {
+			continue
+		}
+
+		if err = t.send(channel, fn); err == nil || err.Error() == "signal" {
+			return nil
+		}
+
+		globals.Warn(fmt.Sprintf("[channel] caught error %s for model %s at channel %s", err.Error(), t.Model, channel.GetName()))
+	}
+
+	globals.Info(fmt.Sprintf("[channel] channels are exhausted for model %s", t.Model))
+	return t.GetError(err)
+}
+
+// send sends the request with the channel taken by Next, its result is recorded to the picked secret
+func (t *Ticker) send(channel *Channel, fn func(instance *Channel) error) error {
+	// the slot is released even if the adapter panics
+	slot := t.slot
+	defer func() {
+		channel.Release(slot, t.getOutputTokens())
+	}()
+
+	instance, err := channel.Pick()
+	if err != nil {
+		if t.probing {
+			channel.releaseProbe()
+		}
+		return err
+	}
+
+	instance.probing = t.probing
+	err = fn(instance)
+	instance.record(err)
+	return err
+}
+
+// getOutputTokens returns the output tokens of the finished request, counted by the tpm limits
+func (t *Ticker) getOutputTokens() int {
+	if t.Buffer == nil {
+		return 0
+	}
+
+	t.Buffer.Finish()
+	return t.Buffer.CountOutputToken()
+}
@@ -0,0 +1,225 @@
+package channel
+
+import (
+	"chat/globals"
+	"errors"
+	"testing"
+	"time"
+)
+
+// pickIds returns the ids of the channels picked by the ticker one request after another
+func pickIds(t *Ticker, tier Sequence, count int) []int {
+	ids := make([]int, count)
+	for idx := range ids {
+		channel, _ := t.acquire(tier)
+		if channel == nil {
+			ids[idx] = -1
+			continue
+		}
+
+		ids[idx] = channel.GetId()
+		channel.Release(t.slot, 0)
+	}
+	return ids
+}
+
+func assertIds(t *testing.T, got []int, want []int) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("picked channels = %v, want %v", got, want)
+	}
+	for idx := range got {
+		if got[idx] != want[idx] {
+			t.Fatalf("picked channels = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestPickWeighted(t *testing.T) {
+	light, heavy := &Channel{Id: 201, Weight: 1}, &Channel{Id: 202, Weight: 99}
+	ticker := &Ticker{Model: "weighted", Strategy: globals.RoutingWeighted}
+
+	count := 0
+	for i := 0; i < 1000; i++ {
+		if ticker.pick(Sequence{light, heavy}) == heavy {
+			count++
+		}
+	}
+	if count < 900 {
+		t.Errorf("heavy channel is picked %d times of 1000, want about 990", count)
+	}
+}
+
+func TestPickRoundRobin(t *testing.T) {
+	useMetrics(t)
+	tier := Sequence{{Id: 213}, {Id: 211}, {Id: 212}}
+	ticker := &Ticker{Model: "round-robin", Strategy: globals.RoutingRoundRobin}
+
+	// the channels are in turn of the id, no matter how the tier is ordered
+	assertIds(t, pickIds(ticker, tier, 6), []int{211, 212, 213, 211, 212, 213})
+}
+
+func TestPickRoundRobinSaturated(t *testing.T) {
+	useMetrics(t)
+	useRedisStub(t)
+	saturated := &Channel{Id: 222, Concurrency: 1}
+	tier := Sequence{{Id: 221}, saturated, {Id: 223}}
+	ticker := &Ticker{Model: "round-robin-saturated", Strategy: globals.RoutingRoundRobin}
+
+	if _, ok := saturated.Acquire(0); !ok {
+		t.Fatal("cannot take the slot of the channel")
+	}
+
+	// the turn of the saturated channel goes to the next one, the rotation keeps going through the whole tier
+	assertIds(t, pickIds(ticker, tier, 6), []int{221, 223, 223, 221, 223, 223})
+}
+
+func TestPickLeastInflight(t *testing.T) {
+	useMetrics(t)
+	busy, idle := &Channel{Id: 231}, &Channel{Id: 232}
+	ticker := &Ticker{Model: "least-inflight", Strategy: globals.RoutingLeastInflight}
+
+	busy.beginRequest()
+	defer busy.endRequest()
+
+	for i := 0; i < 10; i++ {
+		if channel := ticker.pick(Sequence{busy, idle}); channel != idle {
+			t.Fatalf("pick() = %d, want the idle channel", channel.GetId())
+		}
+	}
+}
+
+func TestPickLatency(t *testing.T) {
+	useMetrics(t)
+	slow, fast, fresh := &Channel{Id: 241}, &Channel{Id: 242}, &Channel{Id: 243}
+	ticker := &Ticker{Model: "latency", Strategy: globals.RoutingLatency}
+
+	slow.observeLatency(2*time.Second, nil)
+	fast.observeLatency(200*time.Millisecond, nil)
+	if channel := ticker.pick(Sequence{slow, fast}); channel != fast {
+		t.Fatalf("pick() = %d, want the fast channel", channel.GetId())
+	}
+
+	// the failed request counts as slow, the channel without any observation is tried first
+	fast.observeLatency(time.Millisecond, errors.New("request failed with status: 502 Bad Gateway"))
+	if channel := ticker.pick(Sequence{slow, fast}); channel != slow {
+		t.Fatalf("pick() = %d, want the slow channel after the fast one fails", channel.GetId())
+	}
+	if channel := ticker.pick(Sequence{slow, fast, fresh}); channel != fresh {
+		t.Fatalf("pick() = %d, want the channel without any observation", channel.GetId())
+	}
+}
+
+func TestPickCost(t *testing.T) {
+	expensive, cheap := &Channel{Id: 251, Cost: 2}, &Channel{Id: 252, Cost: 0.5}
+	ticker := &Ticker{Model: "cost", Strategy: globals.RoutingCost}
+
+	for i := 0; i < 10; i++ {
+		if channel := ticker.pick(Sequence{expensive, cheap}); channel != cheap {
+			t.Fatalf("pick() = %d, want the cheap channel", channel.GetId())
+		}
+	}
+}
+
+func TestRequestLatency(t *testing.T) {
+	c := &Channel{Id: 261, started: time.Now().Add(-time.Second)}
+	hook := c.firstTokenHook(func(data string) error {
+		return nil
+	})
+
+	// the latency of the request is the time to the first token, not the duration of the whole answer
+	_ = hook("")
+	if !c.firstToken.IsZero() {
+		t.Fatal("the empty chunk is counted as the first token")
+	}
+	_ = hook("Hello")
+	first := c.firstToken
+	_ = hook(", world!")
+
+	if c.firstToken != first {
+		t.Fatal("the first token is overwritten by the later chunk")
+	}
+	if latency := c.getRequestLatency(); latency != first.Sub(c.started) {
+		t.Errorf("latency = %s, want the time to the first token %s", latency, first.Sub(c.started))
+	}
+
+	// nothing is streamed, e.g. the failed request, the whole duration is the latency
+	c = &Channel{Id: 262, started: time.Now().Add(-time.Second)}
+	if latency := c.getRequestLatency(); latency < time.Second {
+		t.Errorf("latency = %s, want the whole duration", latency)
+	}
+}
+
+func TestTickerRun(t *testing.T) {
+	useMetrics(t)
+	primary := &Channel{Id: 271, Name: "primary", Priority: 2, Secret: "sk-primary"}
+	secondary := &Channel{Id: 272, Name: "secondary", Priority: 1, Secret: "sk-secondary"}
+	ticker := &Ticker{Sequence: Sequence{primary, secondary}, Model: "run", Strategy: globals.RoutingWeighted}
+
+	// the failed tier falls through to the next priority with the secret of the picked channel
+	var secrets []string
+	err := ticker.Run(func(instance *Channel) error {
+		secrets = append(secrets, instance.GetSecret())
+		if instance.GetId() == primary.GetId() {
+			return errors.New("request failed with status: 502 Bad Gateway")
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("Run() = %s, want the secondary channel to succeed", err)
+	}
+	if len(secrets) != 2 || secrets[0] != "sk-primary" || secrets[1] != "sk-secondary" {
+		t.Errorf("secrets = %v, want the primary then the secondary", secrets)
+	}
+	if primary.GetMetrics().Inflight != 0 || secondary.GetMetrics().Inflight != 0 {
+		t.Error("the channels are still in flight after the run")
+	}
+}
+
+func TestTickerNextUnavailable(t *testing.T) {
+	useMetrics(t)
+	useBreaker(t, breakerState{Enabled: true, Failures: 1, Cooldown: 30})
+	broken := &Channel{Id: 281, Name: "broken", Priority: 2, Queue: 5}
+	limited := &Channel{Id: 282, Name: "limited", Priority: 1, Concurrency: 1}
+	ticker := &Ticker{Sequence: Sequence{broken, limited}, Model: "unavailable", Strategy: globals.RoutingWeighted}
+
+	broken.recordBreaker(errors.New("request failed with status: 503 Service Unavailable"))
+	if _, ok := limited.Acquire(0); !ok {
+		t.Fatal("cannot take the slot of the channel")
+	}
+
+	// the open breaker does not close in the queue, the tier falls through at once
+	started := time.Now()
+	if channel := ticker.Next(); channel != nil || ticker.Saturated {
+		t.Fatalf("Next() = %v, saturated = %t, want the unavailable tier", channel, ticker.Saturated)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("Next() waits %s for the open breaker", elapsed)
+	}
+
+	// the rate limited tier is not reported as saturated since the breaker rejects the earlier tier
+	if channel := ticker.Next(); channel != nil || ticker.Saturated {
+		t.Fatalf("Next() = %v, saturated = %t, want not saturated", channel, ticker.Saturated)
+	}
+	if err := ticker.GetError(nil); err != nil {
+		t.Errorf("GetError() = %s, want nil", err)
+	}
+}
+
+func TestTickerNextSaturated(t *testing.T) {
+	useMetrics(t)
+	useRedisStub(t)
+	limited := &Channel{Id: 291, Name: "limited", Concurrency: 1}
+	ticker := &Ticker{Sequence: Sequence{limited}, Model: "saturated", Strategy: globals.RoutingWeighted}
+
+	if _, ok := limited.Acquire(0); !ok {
+		t.Fatal("cannot take the slot of the channel")
+	}
+	if channel := ticker.Next(); channel != nil || !ticker.Saturated {
+		t.Fatalf("Next() = %v, saturated = %t, want the saturated tier", channel, ticker.Saturated)
+	}
+	if err := ticker.GetError(nil); err != ErrChannelsSaturated {
+		t.Errorf("GetError() = %v, want %s", err, ErrChannelsSaturated)
+	}
+}
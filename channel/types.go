@@ -1,5 +1,10 @@
 package channel
 
+import (
+	"chat/utils"
+	"time"
+)
+
 type Channel struct {
 	Id            int                `json:"id" mapstructure:"id"`
 	Name          string             `json:"name" mapstructure:"name"`
@@ -13,9 +18,30 @@ type Channel struct {
 	Mapper        string             `json:"mapper" mapstructure:"mapper"`
 	State         bool               `json:"state" mapstructure:"state"`
 	Group         []string           `json:"group" mapstructure:"group"`
+	Azure         *AzureConfig       `json:"azure,omitempty" mapstructure:"azure"`   // only azure channels
+	RPM           int                `json:"rpm" mapstructure:"rpm"`                 // requests per minute, 0 is unlimited
+	TPM           int                `json:"tpm" mapstructure:"tpm"`                 // tokens per minute, 0 is unlimited
+	Concurrency   int                `json:"concurrency" mapstructure:"concurrency"` // in-flight requests, 0 is unlimited
+	Queue         int                `json:"queue" mapstructure:"queue"`             // seconds to wait for the saturated channel
+	Cost          float32            `json:"cost" mapstructure:"cost"`               // relative cost of the upstream, used by the cost routing strategy
 	Reflect       *map[string]string `json:"-"`
 	HitModels     *[]string          `json:"-"`
 	ExcludeModels *[]string          `json:"-"`
+	started       time.Time          // start time of the request sent by the picked channel
+	firstToken    time.Time          // time of the first token of the request sent by the picked channel
+	probing       bool               // the request sent by the picked channel holds the probe lock of the breaker
+}
+
+// AzureConfig is the per-channel azure openai settings, the deployments are the `model>deployment` lines like the mapper
+// and the deployment of the kind is used for the models which are not listed
+type AzureConfig struct {
+	ApiVersion  string             `json:"api_version" mapstructure:"api_version"`
+	Deployments string             `json:"deployments" mapstructure:"deployments"`
+	Chat        string             `json:"chat" mapstructure:"chat"`
+	Image       string             `json:"image" mapstructure:"image"`
+	Embedding   string             `json:"embedding" mapstructure:"embedding"`
+	Audio       string             `json:"audio" mapstructure:"audio"`
+	Deployment  *map[string]string `json:"-"`
 }
 
 type Sequence []*Channel
@@ -27,8 +53,16 @@ type Manager struct {
 }
 
 type Ticker struct {
-	Sequence Sequence `json:"sequence"`
-	Cursor   int      `json:"cursor"`
+	Sequence  Sequence      `json:"sequence"`
+	Cursor    int           `json:"cursor"`
+	Model     string        `json:"model"`
+	Strategy  string        `json:"strategy"` // routing strategy to select the channel in the priority tier
+	Tokens    int           `json:"tokens"`   // estimated input tokens of the request, counted by the tpm limits
+	Buffer    *utils.Buffer `json:"-"`        // buffer of the request, its output tokens are counted by the tpm limits
+	Saturated bool          `json:"saturated"`
+	broken    bool          // any channel tried is rejected by its open breaker, so the channels are not only saturated
+	probing   bool          // the channel returned by Next holds the probe lock of the breaker
+	slot      string        // concurrency slot taken by the channel returned by Next
 }
 
 type Charge struct {
@@ -4,27 +4,199 @@ import (
 	"chat/adapter"
 	"chat/globals"
 	"chat/utils"
+	"errors"
 	"fmt"
+	"strings"
 )
 
+// ErrUnsupportedParams is returned if no channel of the model can honor the parameters of the request,
+// the relay responds it as the invalid request instead of the upstream error
+var ErrUnsupportedParams = errors.New("cannot find channel which supports parameters")
+
 func NewChatRequest(group string, props *adapter.ChatProps, hook globals.Hook) error {
 	ticker := ConduitInstance.GetTicker(props.Model, group)
 	if ticker == nil || ticker.IsEmpty() {
 		return fmt.Errorf("cannot find channel for model %s", props.Model)
 	}
 
-	var err error
-	for !ticker.IsDone() {
-		if channel := ticker.Next(); channel != nil {
-			props.MaxRetries = utils.ToPtr(channel.GetRetry())
-			if err = adapter.NewChatRequest(channel, props, hook); err == nil || err.Error() == "signal" {
-				return nil
-			}
+	if ticker.Filter(func(channel *Channel) bool {
+		return adapter.IsChatSupported(channel, props)
+	}).IsEmpty() {
+		if params := props.GetParams(); len(params) > 0 {
+			return fmt.Errorf("%w %s for model %s", ErrUnsupportedParams, strings.Join(params, ", "), props.Model)
+		}
+		return fmt.Errorf("cannot find available channel for model %s", props.Model)
+	}
+
+	ticker.EstimateTokens(func() int {
+		return utils.NumTokensFromMessages(props.Message, props.Model)
+	})
+
+	ticker.Buffer = props.Buffer
+	return ticker.Run(func(instance *Channel) error {
+		props.MaxRetries = utils.ToPtr(instance.GetRetry())
+		return adapter.NewChatRequest(instance, props, instance.firstTokenHook(hook))
+	})
+}
+
+func NewEmbeddingRequest(group string, props *adapter.EmbeddingProps) (*adapter.EmbeddingResponse, error) {
+	ticker := ConduitInstance.GetTicker(props.Model, group)
+	if ticker == nil || ticker.IsEmpty() {
+		return nil, fmt.Errorf("cannot find channel for model %s", props.Model)
+	}
+
+	if ticker.Filter(func(channel *Channel) bool {
+		return adapter.IsEmbeddingSupported(channel.GetType())
+	}).IsEmpty() {
+		return nil, fmt.Errorf("cannot find channel which supports embeddings for model %s", props.Model)
+	}
+
+	var resp *adapter.EmbeddingResponse
+	err := ticker.Run(func(instance *Channel) (e error) {
+		props.MaxRetries = utils.ToPtr(instance.GetRetry())
+		resp, e = adapter.NewEmbeddingRequest(instance, props)
+		return e
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// IsImageSupported returns whether the model has channels which support the images api in the group
+func IsImageSupported(group string, model string) bool {
+	ticker := ConduitInstance.GetTicker(model, group)
+	if ticker == nil {
+		return false
+	}
+
+	return !ticker.Filter(func(channel *Channel) bool {
+		return adapter.IsImageSupported(channel.GetType())
+	}).IsEmpty()
+}
+
+func NewImageRequest(group string, props *adapter.ImageProps) (*adapter.ImageResponse, error) {
+	ticker := ConduitInstance.GetTicker(props.Model, group)
+	if ticker == nil || ticker.IsEmpty() {
+		return nil, fmt.Errorf("cannot find channel for model %s", props.Model)
+	}
+
+	if ticker.Filter(func(channel *Channel) bool {
+		return adapter.IsImageSupported(channel.GetType())
+	}).IsEmpty() {
+		return nil, fmt.Errorf("cannot find channel which supports images api for model %s", props.Model)
+	}
+
+	var resp *adapter.ImageResponse
+	err := ticker.Run(func(instance *Channel) (e error) {
+		props.MaxRetries = utils.ToPtr(instance.GetRetry())
+		resp, e = adapter.NewImageRequest(instance, props)
+		return e
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// getAudioTicker returns the ticker of the channels which support the audio api
+func getAudioTicker(group string, model string) (*Ticker, error) {
+	ticker := ConduitInstance.GetTicker(model, group)
+	if ticker == nil || ticker.IsEmpty() {
+		return nil, fmt.Errorf("cannot find channel for model %s", model)
+	}
+
+	if ticker.Filter(func(channel *Channel) bool {
+		return adapter.IsAudioSupported(channel.GetType())
+	}).IsEmpty() {
+		return nil, fmt.Errorf("cannot find channel which supports audio api for model %s", model)
+	}
+
+	return ticker, nil
+}
+
+func NewTranscriptionRequest(group string, props *adapter.AudioProps) (*adapter.AudioResponse, error) {
+	ticker, err := getAudioTicker(group, props.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *adapter.AudioResponse
+	err = ticker.Run(func(instance *Channel) (e error) {
+		props.MaxRetries = utils.ToPtr(instance.GetRetry())
+		resp, e = adapter.NewTranscriptionRequest(instance, props)
+		return e
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func NewSpeechRequest(group string, props *adapter.SpeechProps) (*adapter.SpeechResponse, error) {
+	ticker, err := getAudioTicker(group, props.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *adapter.SpeechResponse
+	err = ticker.Run(func(instance *Channel) (e error) {
+		props.MaxRetries = utils.ToPtr(instance.GetRetry())
+		resp, e = adapter.NewSpeechRequest(instance, props)
+		return e
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func NewCompletionRequest(group string, props *adapter.CompletionProps, hook adapter.CompletionHook) error {
+	ticker := ConduitInstance.GetTicker(props.Model, group)
+	if ticker == nil || ticker.IsEmpty() {
+		return fmt.Errorf("cannot find channel for model %s", props.Model)
+	}
 
-			globals.Warn(fmt.Sprintf("[channel] caught error %s for model %s at channel %s", err.Error(), props.Model, channel.GetName()))
+	if ticker.Filter(func(channel *Channel) bool {
+		return adapter.IsCompletionSupported(channel, props)
+	}).IsEmpty() {
+		if params := props.GetNativeParams(); len(params) > 0 {
+			return fmt.Errorf("%w %s for model %s", ErrUnsupportedParams, strings.Join(params, ", "), props.Model)
 		}
+		return fmt.Errorf("cannot find available channel for model %s", props.Model)
 	}
 
-	globals.Info(fmt.Sprintf("[channel] channels are exhausted for model %s", props.Model))
-	return err
+	ticker.EstimateTokens(func() int {
+		return utils.NumTokensFromTexts([]string{props.Prompt}, props.Model)
+	})
+
+	ticker.Buffer = props.Buffer
+	return ticker.Run(func(instance *Channel) error {
+		props.MaxRetries = utils.ToPtr(instance.GetRetry())
+		return adapter.NewCompletionRequest(instance, props, instance.firstCompletionHook(hook))
+	})
+}
+
+func NewModerationRequest(group string, props *adapter.ModerationProps) (*adapter.ModerationResponse, error) {
+	ticker := ConduitInstance.GetTicker(props.Model, group)
+	if ticker == nil || ticker.IsEmpty() {
+		return nil, fmt.Errorf("cannot find channel for model %s", props.Model)
+	}
+
+	if ticker.Filter(func(channel *Channel) bool {
+		return adapter.IsModerationSupported(channel.GetType())
+	}).IsEmpty() {
+		return nil, fmt.Errorf("cannot find channel which supports moderations api for model %s", props.Model)
+	}
+
+	var resp *adapter.ModerationResponse
+	err := ticker.Run(func(instance *Channel) (e error) {
+		props.MaxRetries = utils.ToPtr(instance.GetRetry())
+		resp, e = adapter.NewModerationRequest(instance, props)
+		return e
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
 }
@@ -149,6 +149,9 @@ func CreateConversationTable(db *sql.DB) {
 		  conversation_name VARCHAR(255),
 		  data MEDIUMTEXT,
 		  model VARCHAR(255) NOT NULL DEFAULT 'gpt-3.5-turbo-0613',
+		  summary MEDIUMTEXT,
+		  summarized INT NOT NULL DEFAULT 0,
+		  mask INT NOT NULL DEFAULT 0,
 		  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		  UNIQUE KEY (user_id, conversation_id)
 		);
@@ -156,6 +159,26 @@ func CreateConversationTable(db *sql.DB) {
 	if err != nil {
 		fmt.Println(err)
 	}
+
+	// the context columns are added after the table is created by the earlier versions
+	AddColumn(db, "conversation", "summary", "MEDIUMTEXT")
+	AddColumn(db, "conversation", "summarized", "INT NOT NULL DEFAULT 0")
+	AddColumn(db, "conversation", "mask", "INT NOT NULL DEFAULT 0")
+}
+
+// AddColumn adds the column to the existing table if the column does not exist
+func AddColumn(db *sql.DB, table string, column string, definition string) {
+	var count int
+	if err := db.QueryRow(`
+		SELECT COUNT(*) FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?
+	`, table, column).Scan(&count); err != nil || count > 0 {
+		return
+	}
+
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		fmt.Println(err)
+	}
 }
 
 func CreateSharingTable(db *sql.DB) {
@@ -0,0 +1,306 @@
+// Package redistest serves the commands of the redis protocol used by the tests in memory, since the module has no redis server to test with
+package redistest
+
+import (
+	"bufio"
+	"chat/connection"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Server serves the strings, hashes, sorted sets and lists of the redis protocol in memory, the expiration is ignored
+type Server struct {
+	mutex   sync.Mutex
+	strings map[string]string
+	hashes  map[string]map[string]string
+	zsets   map[string]map[string]float64
+	lists   map[string][]string
+	failing map[string]bool // commands which reply the error, e.g. to test the fallback on the cache failure
+}
+
+// Use replaces the cache with the client of the in-memory server until the test is finished
+func Use(t testing.TB) *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen the redis stub: %s", err)
+	}
+
+	server := &Server{
+		strings: map[string]string{},
+		hashes:  map[string]map[string]string{},
+		zsets:   map[string]map[string]float64{},
+		lists:   map[string][]string{},
+		failing: map[string]bool{},
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+
+	cache := connection.Cache
+	connection.Cache = redis.NewClient(&redis.Options{Addr: listener.Addr().String(), MaxRetries: -1})
+	t.Cleanup(func() {
+		_ = connection.Cache.Close()
+		_ = listener.Close()
+		connection.Cache = cache
+	})
+	return server
+}
+
+// Fail makes the command reply the error
+func (s *Server) Fail(command string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.failing[command] = true
+}
+
+// Get returns the string of the key, empty if the key does not exist
+func (s *Server) Get(key string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.strings[key]
+}
+
+// Hash returns the fields of the hash
+func (s *Server) Hash(key string) map[string]string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.hashes[key]
+}
+
+// Card returns the number of the members of the sorted set
+func (s *Server) Card(key string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.zsets[key])
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+
+		if _, err := conn.Write([]byte(s.exec(args))); err != nil {
+			return
+		}
+	}
+}
+
+func integer(value int) string {
+	return fmt.Sprintf(":%d\r\n", value)
+}
+
+func bulk(value string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+}
+
+func array(values []string) string {
+	reply := fmt.Sprintf("*%d\r\n", len(values))
+	for _, value := range values {
+		reply += bulk(value)
+	}
+	return reply
+}
+
+// listRange returns the bounds of the inclusive range, the negative index counts from the tail
+func listRange(size int, start string, stop string) (int, int) {
+	from, _ := strconv.Atoi(start)
+	to, _ := strconv.Atoi(stop)
+	if from < 0 {
+		from += size
+	}
+	if to < 0 {
+		to += size
+	}
+
+	if from < 0 {
+		from = 0
+	}
+	if to >= size {
+		to = size - 1
+	}
+	if from > to {
+		return 0, 0
+	}
+	return from, to + 1
+}
+
+func (s *Server) exec(args []string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	command := strings.ToUpper(args[0])
+	if s.failing[command] {
+		return fmt.Sprintf("-ERR %s is failing\r\n", command)
+	}
+
+	switch command {
+	case "PING":
+		return "+PONG\r\n"
+	case "GET":
+		if value, ok := s.strings[args[1]]; ok {
+			return bulk(value)
+		}
+		return "$-1\r\n"
+	case "SET":
+		_, exist := s.strings[args[1]]
+		for _, option := range args[3:] {
+			if strings.ToUpper(option) == "NX" && exist {
+				return "$-1\r\n"
+			}
+		}
+		s.strings[args[1]] = args[2]
+		return "+OK\r\n"
+	case "INCR", "INCRBY", "DECR", "DECRBY":
+		delta := 1
+		if len(args) > 2 {
+			delta, _ = strconv.Atoi(args[2])
+		}
+		if strings.HasPrefix(command, "DECR") {
+			delta = -delta
+		}
+
+		value, _ := strconv.Atoi(s.strings[args[1]])
+		s.strings[args[1]] = strconv.Itoa(value + delta)
+		return integer(value + delta)
+	case "EXPIRE":
+		return integer(1)
+	case "DEL", "EXISTS":
+		count := 0
+		for _, key := range args[1:] {
+			_, str := s.strings[key]
+			_, hash := s.hashes[key]
+			_, zset := s.zsets[key]
+			_, list := s.lists[key]
+			if str || hash || zset || list {
+				count++
+			}
+			if command == "DEL" {
+				delete(s.strings, key)
+				delete(s.hashes, key)
+				delete(s.zsets, key)
+				delete(s.lists, key)
+			}
+		}
+		return integer(count)
+	case "HINCRBY":
+		if s.hashes[args[1]] == nil {
+			s.hashes[args[1]] = map[string]string{}
+		}
+		delta, _ := strconv.Atoi(args[3])
+		value, _ := strconv.Atoi(s.hashes[args[1]][args[2]])
+		s.hashes[args[1]][args[2]] = strconv.Itoa(value + delta)
+		return integer(value + delta)
+	case "HSET":
+		if s.hashes[args[1]] == nil {
+			s.hashes[args[1]] = map[string]string{}
+		}
+		for idx := 2; idx+1 < len(args); idx += 2 {
+			s.hashes[args[1]][args[idx]] = args[idx+1]
+		}
+		return integer((len(args) - 2) / 2)
+	case "HGETALL":
+		var values []string
+		for field, value := range s.hashes[args[1]] {
+			values = append(values, field, value)
+		}
+		return array(values)
+	case "ZADD":
+		if s.zsets[args[1]] == nil {
+			s.zsets[args[1]] = map[string]float64{}
+		}
+		for idx := 2; idx+1 < len(args); idx += 2 {
+			score, _ := strconv.ParseFloat(args[idx], 64)
+			s.zsets[args[1]][args[idx+1]] = score
+		}
+		return integer((len(args) - 2) / 2)
+	case "ZREM":
+		count := 0
+		for _, member := range args[2:] {
+			if _, ok := s.zsets[args[1]][member]; ok {
+				delete(s.zsets[args[1]], member)
+				count++
+			}
+		}
+		return integer(count)
+	case "ZCARD":
+		return integer(len(s.zsets[args[1]]))
+	case "ZREMRANGEBYSCORE":
+		min, _ := strconv.ParseFloat(args[2], 64)
+		max, _ := strconv.ParseFloat(args[3], 64)
+		count := 0
+		for member, score := range s.zsets[args[1]] {
+			if score >= min && score <= max {
+				delete(s.zsets[args[1]], member)
+				count++
+			}
+		}
+		return integer(count)
+	case "LPUSH":
+		for _, value := range args[2:] {
+			s.lists[args[1]] = append([]string{value}, s.lists[args[1]]...)
+		}
+		return integer(len(s.lists[args[1]]))
+	case "LTRIM":
+		from, to := listRange(len(s.lists[args[1]]), args[2], args[3])
+		s.lists[args[1]] = s.lists[args[1]][from:to]
+		return "+OK\r\n"
+	case "LRANGE":
+		from, to := listRange(len(s.lists[args[1]]), args[2], args[3])
+		return array(s.lists[args[1]][from:to])
+	default:
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+	}
+}
+
+// readCommand reads the command sent as the array of bulk strings
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	} else if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("unexpected command line %q", line)
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil || count == 0 {
+		return nil, fmt.Errorf("unexpected command line %q", line)
+	}
+
+	args := make([]string, count)
+	for i := range args {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "$")))
+		if err != nil {
+			return nil, fmt.Errorf("unexpected bulk string header %q", header)
+		}
+
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
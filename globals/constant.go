@@ -11,6 +11,7 @@ const (
 	OpenAIChannelType      = "openai"
 	AzureOpenAIChannelType = "azure"
 	ClaudeChannelType      = "claude"
+	AnthropicChannelType   = "anthropic"
 	SlackChannelType       = "slack"
 	SparkdeskChannelType   = "sparkdesk"
 	ChatGLMChannelType     = "chatglm"
@@ -23,12 +24,43 @@ const (
 	PalmChannelType        = "palm"
 	MidjourneyChannelType  = "midjourney"
 	OneAPIChannelType      = "oneapi"
+	OllamaChannelType      = "ollama"
+	LlamaCppChannelType    = "llamacpp"
+)
+
+// kinds of the azure openai deployments, a resource may serve the chat, image, embedding and audio models by different deployments
+const (
+	AzureChatDeployment      = "chat"
+	AzureImageDeployment     = "image"
+	AzureEmbeddingDeployment = "embedding"
+	AzureAudioDeployment     = "audio"
 )
 
 const (
 	NonBilling   = "non-billing"
 	TimesBilling = "times-billing"
 	TokenBilling = "token-billing"
+	AudioBilling = "audio-billing" // input: per 1k characters of speech, output: per minute of transcribed audio
+)
+
+const (
+	ModerationBlock = "block" // reject the flagged request
+	ModerationLog   = "log"   // log the flagged request and send it upstream
+	ModerationAllow = "allow" // skip the moderation
+)
+
+const (
+	BreakerClosed   = "closed"    // the channel serves the requests
+	BreakerOpen     = "open"      // the channel is left out of the selection until the cooldown is over
+	BreakerHalfOpen = "half-open" // a probe request is sent to the channel
+)
+
+const (
+	RoutingWeighted      = "weighted"       // random channel weighted by the channel weight
+	RoutingRoundRobin    = "round-robin"    // channels in turn
+	RoutingLeastInflight = "least-inflight" // channel with the least in-flight requests of the node
+	RoutingLatency       = "latency"        // channel with the lowest latency observed by the node
+	RoutingCost          = "cost"           // channel with the lowest cost
 )
 
 const (
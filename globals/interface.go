@@ -12,6 +12,13 @@ type ChannelConfig interface {
 	ProcessError(err error) error
 }
 
+// AzureChannelConfig is implemented by the channels with the azure openai settings (api version and deployments),
+// the azure adapter falls back to the endpoint as the api version and the model as the deployment without it
+type AzureChannelConfig interface {
+	GetAzureApiVersion() string
+	GetAzureDeployment(model string, kind string) string // empty if the deployment of the model is not configured
+}
+
 type AuthLike interface {
 	GetID(db *sql.DB) int64
 	HitID() int64
@@ -0,0 +1,166 @@
+package globals
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	TextContentType     = "text"
+	ImageUrlContentType = "image_url"
+	ImageContentType    = "image" // base64 encoded image
+	FileContentType     = "file"
+)
+
+type ImageUrl struct {
+	Url    string  `json:"url"`
+	Detail *string `json:"detail,omitempty"`
+}
+
+type ImageData struct {
+	MimeType string `json:"mime_type"`
+	Data     string `json:"data"` // base64 encoded data without the data url prefix
+}
+
+type FileData struct {
+	Name     string `json:"name"`
+	MimeType string `json:"mime_type,omitempty"`
+	Content  string `json:"content"` // parsed text content of the file
+}
+
+type MessageContent struct {
+	Type     string     `json:"type"`
+	Text     *string    `json:"text,omitempty"`
+	ImageUrl *ImageUrl  `json:"image_url,omitempty"`
+	Image    *ImageData `json:"image,omitempty"`
+	File     *FileData  `json:"file,omitempty"`
+}
+
+type MessageContents []MessageContent
+
+func NewTextContent(text string) MessageContent {
+	return MessageContent{
+		Type: TextContentType,
+		Text: &text,
+	}
+}
+
+func NewImageUrlContent(url string) MessageContent {
+	return MessageContent{
+		Type:     ImageUrlContentType,
+		ImageUrl: &ImageUrl{Url: url},
+	}
+}
+
+func NewImageContent(mimeType string, data string) MessageContent {
+	return MessageContent{
+		Type:  ImageContentType,
+		Image: &ImageData{MimeType: mimeType, Data: data},
+	}
+}
+
+func (c MessageContent) IsImage() bool {
+	return (c.Type == ImageUrlContentType && c.ImageUrl != nil) || (c.Type == ImageContentType && c.Image != nil)
+}
+
+// GetText returns the text of the text and file parts, the file is formatted as the file block of the web client
+func (c MessageContent) GetText() string {
+	switch c.Type {
+	case TextContentType:
+		if c.Text != nil {
+			return *c.Text
+		}
+	case FileContentType:
+		if c.File != nil {
+			return fmt.Sprintf("```file\n[[%s]]\n%s\n```", c.File.Name, c.File.Content)
+		}
+	}
+	return ""
+}
+
+// GetImageUrl returns the url of the image part, the base64 image is returned as the data url
+func (c MessageContent) GetImageUrl() string {
+	switch c.Type {
+	case ImageUrlContentType:
+		if c.ImageUrl != nil {
+			return c.ImageUrl.Url
+		}
+	case ImageContentType:
+		if c.Image != nil {
+			return fmt.Sprintf("data:%s;base64,%s", c.Image.MimeType, c.Image.Data)
+		}
+	}
+	return ""
+}
+
+// GetText returns the text view of the parts, image urls are kept inline as the fallback of the text only models
+func (c MessageContents) GetText() string {
+	segments := make([]string, 0)
+	for _, content := range c {
+		text := content.GetText()
+		if content.Type == ImageUrlContentType && content.ImageUrl != nil {
+			text = content.ImageUrl.Url
+		}
+
+		if len(strings.TrimSpace(text)) > 0 {
+			segments = append(segments, text)
+		}
+	}
+	return strings.Join(segments, "\n")
+}
+
+func (c MessageContents) HasImages() bool {
+	for _, content := range c {
+		if content.IsImage() {
+			return true
+		}
+	}
+	return false
+}
+
+// HasInlineImages returns whether the parts contain base64 images, which cannot fall back to the text view
+func (c MessageContents) HasInlineImages() bool {
+	for _, content := range c {
+		if content.Type == ImageContentType && content.Image != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// GetContents returns the typed parts of the message, the plain message is returned as a single text part
+func (m Message) GetContents() MessageContents {
+	if len(m.Contents) > 0 {
+		return m.Contents
+	}
+
+	if len(m.Content) == 0 {
+		return MessageContents{}
+	}
+	return MessageContents{NewTextContent(m.Content)}
+}
+
+// IsMultimodal returns whether the parts contain the non text parts
+func (c MessageContents) IsMultimodal() bool {
+	for _, content := range c {
+		if content.Type != TextContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// IsMultimodal returns whether the message carries the non text parts
+func (m Message) IsMultimodal() bool {
+	return m.Contents.IsMultimodal()
+}
+
+// GetTextMessages returns the messages without typed parts, for the models which only accept the text view
+func GetTextMessages(messages []Message) []Message {
+	result := make([]Message, len(messages))
+	for i, message := range messages {
+		message.Contents = nil
+		result[i] = message
+	}
+	return result
+}
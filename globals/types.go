@@ -2,10 +2,11 @@ package globals
 
 type Hook func(data string) error
 type Message struct {
-	Role       string     `json:"role"`
-	Content    string     `json:"content"`
-	ToolCallId *string    `json:"tool_call_id,omitempty"` // only `tool` role
-	ToolCalls  *ToolCalls `json:"tool_calls,omitempty"`   // only `assistant` role
+	Role       string          `json:"role"`
+	Content    string          `json:"content"`                // text view of the message, used by the text only models
+	Contents   MessageContents `json:"contents,omitempty"`     // typed parts of the multimodal message
+	ToolCallId *string         `json:"tool_call_id,omitempty"` // only `tool` role
+	ToolCalls  *ToolCalls      `json:"tool_calls,omitempty"`   // only `assistant` role
 }
 
 type ChatSegmentResponse struct {
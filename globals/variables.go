@@ -17,6 +17,7 @@ var AllowedOrigins = []string{
 }
 
 var NotifyUrl = ""
+var MidjourneyTimeout = 0 // seconds, 0 means the default timeout
 
 func OriginIsAllowed(uri string) bool {
 	instance, _ := url.Parse(uri)
@@ -62,6 +63,7 @@ const (
 	Dalle                 = "dalle"
 	Dalle2                = "dall-e-2"
 	Dalle3                = "dall-e-3"
+	TextModerationLatest  = "text-moderation-latest"
 	Claude1               = "claude-1"
 	Claude1100k           = "claude-1.3"
 	Claude2               = "claude-1-100k"
@@ -81,6 +83,9 @@ const (
 	ZhiPuChatGLMPro       = "zhipu-chatglm-pro"
 	ZhiPuChatGLMStd       = "zhipu-chatglm-std"
 	ZhiPuChatGLMLite      = "zhipu-chatglm-lite"
+	GLM4                  = "glm-4"
+	GLM4Vision            = "glm-4v"
+	GLM3Turbo             = "glm-3-turbo"
 	QwenTurbo             = "qwen-turbo"
 	QwenPlus              = "qwen-plus"
 	QwenTurboNet          = "qwen-turbo-net"
@@ -101,6 +106,10 @@ var DalleModels = []string{
 	Dalle, Dalle2, Dalle3,
 }
 
+var MidjourneyModels = []string{
+	Midjourney, MidjourneyFast, MidjourneyTurbo,
+}
+
 func in(value string, slice []string) bool {
 	for _, item := range slice {
 		if item == value {
@@ -115,6 +124,49 @@ func IsDalleModel(model string) bool {
 	return in(model, DalleModels)
 }
 
+func IsMidjourneyModel(model string) bool {
+	// midjourney models accept the follow-up action commands
+	return in(model, MidjourneyModels)
+}
+
+var CompletionModelPrefixes = []string{
+	"text-davinci", "text-curie", "text-babbage", "text-ada", "davinci", "curie", "babbage", "ada",
+}
+
+func IsCompletionModel(model string) bool {
+	// legacy completion models (e.g. gpt-3.5-turbo-instruct, davinci-002) are served by the completions api
+	if model == GPT3TurboInstruct || strings.HasSuffix(model, "-instruct") {
+		return true
+	}
+
+	for _, prefix := range CompletionModelPrefixes {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// nonChatModelKeywords are the keywords of the models served by the embeddings, images, audio and moderations api
+var nonChatModelKeywords = []string{
+	"embedding", "moderation", "whisper", "tts", "dall-e", "dalle", "midjourney", "stable-diffusion",
+}
+
+// IsChatModel returns whether the model is served by the chat api, it is used to pick the model of the health check
+func IsChatModel(model string) bool {
+	if IsDalleModel(model) || IsMidjourneyModel(model) || IsCompletionModel(model) || model == GPT4Dalle {
+		return false
+	}
+
+	model = strings.ToLower(model)
+	for _, keyword := range nonChatModelKeywords {
+		if strings.Contains(model, keyword) {
+			return false
+		}
+	}
+	return true
+}
+
 func IsGPT41106VisionPreview(model string) bool {
 	// enable openai image format for gpt-4-vision-preview model
 	return model == GPT41106VisionPreview ||
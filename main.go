@@ -47,6 +47,8 @@ func main() {
 	worker := middleware.RegisterMiddleware(app)
 	defer worker()
 
+	channel.HealthWorker()
+
 	utils.RegisterStaticRoute(app)
 	registerApiRouter(app)
 
@@ -0,0 +1,195 @@
+package manager
+
+import (
+	"chat/adapter"
+	adaptercommon "chat/adapter/common"
+	"chat/admin"
+	"chat/auth"
+	"chat/channel"
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"math"
+	"net/http"
+	"strings"
+)
+
+const audioFileLimit = 25 << 20 // 25MB limit of the audio file of the openai audio api
+
+var transcriptionFormats = []string{"json", "text", "srt", "verbose_json", "vtt"}
+
+func AudioTranscriptionsRelayAPI(c *gin.Context) {
+	relayTranscriptionAPI(c, adaptercommon.AudioTranscription)
+}
+
+func AudioTranslationsRelayAPI(c *gin.Context) {
+	relayTranscriptionAPI(c, adaptercommon.AudioTranslation)
+}
+
+// checkAudioModel strips the official suffix of the model and checks the permission of the user
+func checkAudioModel(c *gin.Context, user *auth.User, model string) (string, bool) {
+	model = strings.TrimSuffix(model, "-official")
+
+	if !auth.CanEnableModel(utils.GetDBFromContext(c), user, model) {
+		sendErrorResponse(c, fmt.Errorf("quota exceeded"), "quota_exceeded_error")
+		return model, false
+	}
+	return model, true
+}
+
+// collectAudioQuota analyses the audio request and collects the quota of the user
+func collectAudioQuota(c *gin.Context, user *auth.User, buffer *utils.Buffer, err error) bool {
+	db := utils.GetDBFromContext(c)
+	cache := utils.GetCacheFromContext(c)
+
+	admin.AnalysisRequest(buffer.GetModel(), buffer, err)
+	if err != nil {
+		auth.RevertSubscriptionUsage(db, cache, user, buffer.GetModel())
+		globals.Warn(fmt.Sprintf("error from audio request api: %s (instance: %s, client: %s)", err, buffer.GetModel(), c.ClientIP()))
+
+		sendErrorResponse(c, err)
+		return false
+	}
+
+	if quota := buffer.GetQuota(); quota > 0 {
+		user.UseQuota(db, quota)
+	}
+	return true
+}
+
+func relayTranscriptionAPI(c *gin.Context, t string) {
+	user := getRelayUser(c)
+	if user == nil {
+		return
+	}
+
+	// the body is limited before the multipart form is parsed, the fields take the rest of the megabyte
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, audioFileLimit+1<<20)
+
+	var form RelayTranscriptionForm
+	if err := c.ShouldBind(&form); err != nil {
+		abortWithErrorResponse(c, fmt.Errorf("invalid request body: %s", err.Error()), "invalid_request_error")
+		return
+	}
+
+	if form.ResponseFormat == "" {
+		form.ResponseFormat = "json"
+	} else if !utils.Contains(form.ResponseFormat, transcriptionFormats) {
+		abortWithErrorResponse(c, fmt.Errorf("invalid response format %s", form.ResponseFormat), "invalid_request_error")
+		return
+	}
+
+	model, ok := checkAudioModel(c, user, form.Model)
+	if !ok {
+		return
+	}
+
+	file, err := getMultipartFile(c, "file", audioFileLimit)
+	if err != nil {
+		abortWithErrorResponse(c, fmt.Errorf("invalid file: %s", err.Error()), "invalid_request_error")
+		return
+	} else if file == nil {
+		abortWithErrorResponse(c, fmt.Errorf("file is required"), "invalid_request_error")
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	resp, err := channel.NewTranscriptionRequest(auth.GetGroup(db, user), &adapter.AudioProps{
+		Type:        t,
+		Model:       model,
+		File:        file,
+		Language:    form.Language,
+		Prompt:      form.Prompt,
+		Temperature: form.Temperature,
+	})
+
+	// transcriptions are billed per second of the audio
+	charge := channel.ChargeInstance.GetCharge(model)
+	var quota float32
+	if resp != nil {
+		quota = utils.CountTranscriptionQuota(charge, model, resp.Duration, resp.Text)
+	}
+
+	if !collectAudioQuota(c, user, utils.NewAudioBuffer(model, quota, charge), err) {
+		return
+	}
+
+	body, contentType := getTranscriptionBody(resp, form.ResponseFormat)
+	c.Data(http.StatusOK, contentType, []byte(body))
+}
+
+func SpeechRelayAPI(c *gin.Context) {
+	user := getRelayUser(c)
+	if user == nil {
+		return
+	}
+
+	var form RelaySpeechForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		abortWithErrorResponse(c, fmt.Errorf("invalid request body: %s", err.Error()), "invalid_request_error")
+		return
+	}
+
+	model, ok := checkAudioModel(c, user, form.Model)
+	if !ok {
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	resp, err := channel.NewSpeechRequest(auth.GetGroup(db, user), &adapter.SpeechProps{
+		Model:          model,
+		Input:          form.Input,
+		Voice:          form.Voice,
+		ResponseFormat: form.ResponseFormat,
+		Speed:          form.Speed,
+	})
+
+	// speech is billed per character of the input
+	charge := channel.ChargeInstance.GetCharge(model)
+	buffer := utils.NewAudioBuffer(model, utils.CountSpeechQuota(charge, model, form.Input), charge)
+	if !collectAudioQuota(c, user, buffer, err) {
+		return
+	}
+
+	c.Data(http.StatusOK, utils.Multi(resp.ContentType != "", resp.ContentType, "audio/mpeg"), resp.Data)
+}
+
+// getSubtitleTime formats the seconds as the subtitle timestamp (e.g. 00:01:02,500 for srt, 00:01:02.500 for vtt)
+func getSubtitleTime(seconds float64, separator string) string {
+	ms := int(math.Round(seconds * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, separator, ms%1000)
+}
+
+func getSubtitles(resp *adapter.AudioResponse, format string) string {
+	var builder strings.Builder
+	if format == "vtt" {
+		builder.WriteString("WEBVTT\n\n")
+	}
+
+	for idx, segment := range resp.Segments {
+		if format == "srt" {
+			builder.WriteString(fmt.Sprintf("%d\n%s --> %s\n", idx+1, getSubtitleTime(segment.Start, ","), getSubtitleTime(segment.End, ",")))
+		} else {
+			builder.WriteString(fmt.Sprintf("%s --> %s\n", getSubtitleTime(segment.Start, "."), getSubtitleTime(segment.End, ".")))
+		}
+		builder.WriteString(strings.TrimSpace(segment.Text) + "\n\n")
+	}
+	return builder.String()
+}
+
+// getTranscriptionBody renders the verbose json response of the upstream in the requested response format
+func getTranscriptionBody(resp *adapter.AudioResponse, format string) (string, string) {
+	switch format {
+	case "text":
+		return resp.Text + "\n", "text/plain; charset=utf-8"
+	case "srt":
+		return getSubtitles(resp, format), "text/plain; charset=utf-8"
+	case "vtt":
+		return getSubtitles(resp, format), "text/vtt; charset=utf-8"
+	case "verbose_json":
+		return utils.Marshal(resp), "application/json; charset=utf-8"
+	default:
+		return utils.Marshal(gin.H{"text": resp.Text}), "application/json; charset=utf-8"
+	}
+}
@@ -19,6 +19,7 @@ const defaultQuotaMessage = "You don't have enough quota or you don't have permi
 
 func CollectQuota(c *gin.Context, user *auth.User, buffer *utils.Buffer, uncountable bool, err error) {
 	db := utils.GetDBFromContext(c)
+	buffer.Finish()
 	quota := buffer.GetQuota()
 	if buffer.IsEmpty() {
 		return
@@ -63,8 +64,6 @@ func ChatHandler(conn *Connection, user *auth.User, instance *conversation.Conve
 		}
 	}()
 
-	segment := web.UsingWebSegment(instance)
-
 	model := instance.GetModel()
 	db := conn.GetDB()
 	cache := conn.GetCache()
@@ -82,6 +81,21 @@ func ChatHandler(conn *Connection, user *auth.User, instance *conversation.Conve
 		return defaultQuotaMessage
 	}
 
+	if err := moderateInput(conn.GetCtx(), user, model, instance.GetChatMessage()); err != nil {
+		if plan {
+			auth.RevertSubscriptionUsage(db, cache, user, model)
+		}
+
+		conn.Send(globals.ChatSegmentResponse{
+			Message: err.Error(),
+			End:     true,
+		})
+		return err.Error()
+	}
+
+	summarizeContext(conn.GetCtx(), user, instance)
+	segment := web.UsingWebSegment(instance)
+
 	if form := ExtractCacheData(conn.GetCtx(), &CacheProps{
 		Message:    segment,
 		Model:      model,
@@ -91,19 +105,22 @@ func ChatHandler(conn *Connection, user *auth.User, instance *conversation.Conve
 		return form.Message
 	}
 
-	buffer := utils.NewBuffer(model, segment, channel.ChargeInstance.GetCharge(model))
+	buffer := utils.NewBuffer(model, segment, getChatCharge(model, segment))
 	err := channel.NewChatRequest(
 		auth.GetGroup(db, user),
 		&adapter.ChatProps{
 			Model:   model,
 			Message: segment,
 			Plan:    plan,
-			Buffer:  *buffer,
+			Buffer:  buffer,
 		},
 		func(data string) error {
 			if signal := conn.PeekWithType(StopType); signal != nil {
 				// stop signal from client
 				return fmt.Errorf("signal")
+			} else if data == "" {
+				// heartbeat of the long-running task (e.g. midjourney), nothing to send
+				return nil
 			}
 			return conn.SendClient(globals.ChatSegmentResponse{
 				Message: buffer.Write(data),
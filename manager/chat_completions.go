@@ -13,6 +13,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -34,6 +35,11 @@ func ChatRelayAPI(c *gin.Context) {
 		return
 	}
 
+	if err := checkRelayForm(form); err != nil {
+		abortWithErrorResponse(c, err, "invalid_request_error")
+		return
+	}
+
 	db := utils.GetDBFromContext(c)
 	user := &auth.User{
 		Username: username,
@@ -42,6 +48,7 @@ func ChatRelayAPI(c *gin.Context) {
 	created := time.Now().Unix()
 
 	messages := transform(form.Messages)
+	input := messages // the user input before the web search segment is moderated
 	if strings.HasPrefix(form.Model, "web-") {
 		suffix := strings.TrimPrefix(form.Model, "web-")
 
@@ -60,6 +67,11 @@ func ChatRelayAPI(c *gin.Context) {
 		return
 	}
 
+	if err := moderateInput(c, user, form.Model, input); err != nil {
+		sendErrorResponse(c, err, "moderation_error")
+		return
+	}
+
 	if form.Stream {
 		sendStreamTranshipmentResponse(c, form, messages, id, created, user, false)
 	} else {
@@ -67,7 +79,34 @@ func ChatRelayAPI(c *gin.Context) {
 	}
 }
 
+// maxRelayChoices is the max `n` of the request, each choice is generated by a separate upstream request
+const maxRelayChoices = 8
+
+// checkRelayForm returns the error of the parameters which cannot be relayed, the parameters which the channels
+// of the model cannot honor are rejected by the channel selection instead
+func checkRelayForm(form RelayForm) error {
+	if _, err := getStopSequences(form.Stop); err != nil {
+		return err
+	}
+
+	if form.N != nil {
+		if *form.N < 1 || *form.N > maxRelayChoices {
+			return fmt.Errorf("n must be between 1 and %d", maxRelayChoices)
+		} else if *form.N > 1 && form.Stream {
+			return fmt.Errorf("n greater than 1 is not supported with stream")
+		}
+	}
+
+	if form.StreamOptions != nil && !form.Stream {
+		return fmt.Errorf("stream_options is only allowed when stream is true")
+	}
+
+	return nil
+}
+
 func getChatProps(form RelayForm, messages []globals.Message, buffer *utils.Buffer, plan bool) *adapter.ChatProps {
+	stop, _ := getStringArray(form.Stop) // validated by checkRelayForm
+
 	return &adapter.ChatProps{
 		Model:             form.Model,
 		Message:           messages,
@@ -81,51 +120,99 @@ func getChatProps(form RelayForm, messages []globals.Message, buffer *utils.Buff
 		TopK:              form.TopK,
 		Tools:             form.Tools,
 		ToolChoice:        form.ToolChoice,
-		Buffer:            *buffer,
+		Stop:              stop,
+		Seed:              form.Seed,
+		LogitBias:         form.LogitBias,
+		ResponseFormat:    form.ResponseFormat,
+		User:              form.User,
+		Buffer:            buffer,
 	}
 }
 
-func sendTranshipmentResponse(c *gin.Context, form RelayForm, messages []globals.Message, id string, created int64, user *auth.User, plan bool) {
-	db := utils.GetDBFromContext(c)
-	cache := utils.GetCacheFromContext(c)
-
-	buffer := utils.NewBuffer(form.Model, messages, channel.ChargeInstance.GetCharge(form.Model))
-	err := channel.NewChatRequest(auth.GetGroup(db, user), getChatProps(form, messages, buffer, plan), func(data string) error {
+// createTranshipmentRequest requests the choice of the chat completion, the result is written to the buffer
+func createTranshipmentRequest(group string, form RelayForm, messages []globals.Message, plan bool) (*utils.Buffer, error) {
+	buffer := utils.NewBuffer(form.Model, messages, getChatCharge(form.Model, messages))
+	err := channel.NewChatRequest(group, getChatProps(form, messages, buffer, plan), func(data string) error {
 		buffer.Write(data)
 		return nil
 	})
 
 	admin.AnalysisRequest(form.Model, buffer, err)
-	if err != nil {
-		auth.RevertSubscriptionUsage(db, cache, user, form.Model)
-		globals.Warn(fmt.Sprintf("error from chat request api: %s (instance: %s, client: %s)", err, form.Model, c.ClientIP()))
+	return buffer, err
+}
 
-		sendErrorResponse(c, err)
-		return
+func sendTranshipmentResponse(c *gin.Context, form RelayForm, messages []globals.Message, id string, created int64, user *auth.User, plan bool) {
+	db := utils.GetDBFromContext(c)
+	cache := utils.GetCacheFromContext(c)
+
+	// the choices (n > 1) are requested concurrently, the upstreams are asked for one choice each
+	group := auth.GetGroup(db, user)
+	buffers := make([]*utils.Buffer, utils.GetPtrVal(form.N, 1))
+	errs := make([]error, len(buffers))
+
+	var wg sync.WaitGroup
+	for i := range buffers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			buffers[i], errs[i] = createTranshipmentRequest(group, form, messages, plan)
+		}(i)
+	}
+	wg.Wait()
+
+	// the response fails as a whole like the single choice, so none of the choices is charged if any of them fails
+	for _, err := range errs {
+		if err != nil {
+			auth.RevertSubscriptionUsage(db, cache, user, form.Model)
+			globals.Warn(fmt.Sprintf("error from chat request api: %s (instance: %s, client: %s)", err, form.Model, c.ClientIP()))
+
+			sendErrorResponse(c, err)
+			return
+		}
+	}
+
+	var (
+		choices []Choice
+		usage   Usage
+		quota   float32
+	)
+	for i, buffer := range buffers {
+		CollectQuota(c, user, buffer, plan, nil)
+
+		choices = append(choices, Choice{
+			Index: i,
+			Message: globals.Message{
+				Role:      globals.Assistant,
+				Content:   buffer.ReadWithDefault(defaultMessage),
+				ToolCalls: buffer.GetToolCalls(),
+			},
+			FinishReason: getFinishReason(buffer),
+		})
+		usage.PromptTokens += buffer.CountInputToken()
+		usage.CompletionTokens += buffer.CountOutputToken()
+		usage.TotalTokens += buffer.CountToken()
+		quota += buffer.GetQuota()
 	}
 
-	CollectQuota(c, user, buffer, plan, err)
 	c.JSON(http.StatusOK, RelayResponse{
 		Id:      fmt.Sprintf("chatcmpl-%s", id),
 		Object:  "chat.completion",
 		Created: created,
 		Model:   form.Model,
-		Choices: []Choice{
-			{
-				Index:        0,
-				Message:      globals.Message{Role: globals.Assistant, Content: buffer.ReadWithDefault(defaultMessage)},
-				FinishReason: "stop",
-			},
-		},
-		Usage: Usage{
-			PromptTokens:     buffer.CountInputToken(),
-			CompletionTokens: buffer.CountOutputToken(),
-			TotalTokens:      buffer.CountToken(),
-		},
-		Quota: utils.Multi[*float32](form.Official, nil, utils.ToPtr(buffer.GetQuota())),
+		Choices: choices,
+		Usage:   usage,
+		Quota:   utils.Multi[*float32](form.Official, nil, utils.ToPtr(quota)),
 	})
 }
 
+// getFinishReason returns the finish reason of the choice generated into the buffer
+func getFinishReason(buffer *utils.Buffer) string {
+	if buffer.IsFunctionCalling() {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
 func getStreamTranshipmentForm(id string, created int64, form RelayForm, data string, buffer *utils.Buffer, end bool, err error) RelayStreamResponse {
 	return RelayStreamResponse{
 		Id:      fmt.Sprintf("chatcmpl-%s", id),
@@ -152,14 +239,35 @@ func getStreamTranshipmentForm(id string, created int64, form RelayForm, data st
 	}
 }
 
+// getStreamUsageForm returns the usage chunk of `stream_options.include_usage`, which is sent after the last choice chunk
+func getStreamUsageForm(id string, created int64, form RelayForm, buffer *utils.Buffer) RelayStreamResponse {
+	return RelayStreamResponse{
+		Id:      fmt.Sprintf("chatcmpl-%s", id),
+		Object:  "chat.completion.chunk",
+		Created: created,
+		Model:   form.Model,
+		Choices: []ChoiceDelta{},
+		Usage: Usage{
+			PromptTokens:     buffer.CountInputToken(),
+			CompletionTokens: buffer.CountOutputToken(),
+			TotalTokens:      buffer.CountToken(),
+		},
+		Quota: utils.Multi[*float32](form.Official, nil, utils.ToPtr(buffer.GetQuota())),
+	}
+}
+
 func sendStreamTranshipmentResponse(c *gin.Context, form RelayForm, messages []globals.Message, id string, created int64, user *auth.User, plan bool) {
 	partial := make(chan RelayStreamResponse)
 	db := utils.GetDBFromContext(c)
 	cache := utils.GetCacheFromContext(c)
 
 	go func() {
-		buffer := utils.NewBuffer(form.Model, messages, channel.ChargeInstance.GetCharge(form.Model))
+		buffer := utils.NewBuffer(form.Model, messages, getChatCharge(form.Model, messages))
 		err := channel.NewChatRequest(auth.GetGroup(db, user), getChatProps(form, messages, buffer, plan), func(data string) error {
+			if data == "" {
+				// heartbeat of the long-running task (e.g. midjourney)
+				return nil
+			}
 			partial <- getStreamTranshipmentForm(id, created, form, buffer.Write(data), buffer, false, nil)
 			return nil
 		})
@@ -174,6 +282,9 @@ func sendStreamTranshipmentResponse(c *gin.Context, form RelayForm, messages []g
 		}
 
 		partial <- getStreamTranshipmentForm(id, created, form, "", buffer, true, nil)
+		if form.StreamOptions != nil && form.StreamOptions.IncludeUsage {
+			partial <- getStreamUsageForm(id, created, form, buffer)
+		}
 		CollectQuota(c, user, buffer, plan, err)
 		close(partial)
 		return
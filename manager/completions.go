@@ -39,14 +39,14 @@ func NativeChatHandler(c *gin.Context, user *auth.User, model string, message []
 		return form.Message, 0
 	}
 
-	buffer := utils.NewBuffer(model, segment, channel.ChargeInstance.GetCharge(model))
+	buffer := utils.NewBuffer(model, segment, getChatCharge(model, segment))
 	err := channel.NewChatRequest(
 		auth.GetGroup(db, user),
 		&adapter.ChatProps{
 			Model:   model,
 			Plan:    plan,
 			Message: segment,
-			Buffer:  *buffer,
+			Buffer:  buffer,
 		},
 		func(resp string) error {
 			buffer.Write(resp)
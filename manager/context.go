@@ -0,0 +1,81 @@
+package manager
+
+import (
+	"chat/adapter"
+	"chat/admin"
+	"chat/auth"
+	"chat/channel"
+	"chat/globals"
+	"chat/manager/conversation"
+	"chat/utils"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"strings"
+)
+
+const summaryInstruction = "You are a helpful assistant that summarizes conversations. " +
+	"Summarize the conversation below in the language of the conversation, keep the facts, decisions, names and open questions " +
+	"which are useful to continue the conversation, and reply with the summary only."
+
+// getSummaryMessages returns the prompt to fold the overflowed messages into the running summary
+func getSummaryMessages(summary string, messages []globals.Message) []globals.Message {
+	var transcript []string
+	if len(summary) > 0 {
+		transcript = append(transcript, fmt.Sprintf("summary of the earlier conversation: %s", summary))
+	}
+	for _, message := range messages {
+		transcript = append(transcript, fmt.Sprintf("%s: %s", message.Role, message.Content))
+	}
+
+	return []globals.Message{
+		{Role: globals.System, Content: summaryInstruction},
+		{Role: globals.User, Content: strings.Join(transcript, "\n\n")},
+	}
+}
+
+// summarizeContext replaces the turns which overflow the context window with the running summary of the conversation,
+// the context is left as it is (the overflowed turns are dropped) if the summary is disabled or failed
+func summarizeContext(c *gin.Context, user *auth.User, instance *conversation.Conversation) {
+	conf := channel.SystemInstance
+	if !conf.IsContextSummaryEnabled() || globals.IsMidjourneyModel(instance.GetModel()) {
+		return
+	}
+
+	messages, summarized := instance.GetOverflowMessages()
+	if len(messages) == 0 {
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	model := conf.GetContextSummaryModel(instance.GetModel())
+	segment := getSummaryMessages(instance.GetSummary(), messages)
+
+	buffer := utils.NewBuffer(model, segment, channel.ChargeInstance.GetCharge(model))
+	err := channel.NewChatRequest(
+		auth.GetGroup(db, user),
+		&adapter.ChatProps{
+			Model:   model,
+			Message: segment,
+			Buffer:  buffer,
+		},
+		func(data string) error {
+			buffer.Write(data)
+			return nil
+		},
+	)
+
+	admin.AnalysisRequest(model, buffer, err)
+	CollectQuota(c, user, buffer, false, err)
+	if err != nil {
+		globals.Warn(fmt.Sprintf("[context] cannot summarize the context: %s (instance: %s, client: %s)", err.Error(), model, c.ClientIP()))
+		return
+	}
+
+	summary := strings.TrimSpace(buffer.Read())
+	if len(summary) == 0 {
+		return
+	}
+
+	instance.SetSummary(summary, summarized)
+	instance.SaveConversation(db)
+}
@@ -0,0 +1,126 @@
+package conversation
+
+import (
+	"chat/channel"
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+)
+
+const summaryPrompt = "The following is the summary of the earlier conversation:\n%s"
+
+// isPinned returns whether the message is always sent with the context, the mask and the system messages are pinned
+func (c *Conversation) isPinned(index int) bool {
+	return index < c.Mask || c.Message[index].Role == globals.System
+}
+
+// getSummarized returns the number of the messages folded into the summary, 0 if the summary is not used
+func (c *Conversation) getSummarized() int {
+	if c.IsIgnoreContext() || !channel.SystemInstance.IsContextSummaryEnabled() || len(c.Summary) == 0 {
+		return 0
+	}
+
+	if c.Summarized > len(c.Message) {
+		return len(c.Message)
+	}
+	return c.Summarized
+}
+
+func (c *Conversation) getSummaryMessage() *globals.Message {
+	if c.getSummarized() == 0 {
+		return nil
+	}
+
+	return &globals.Message{
+		Role:    globals.System,
+		Content: fmt.Sprintf(summaryPrompt, c.Summary),
+	}
+}
+
+// packContext returns the index of the oldest message which fits the tokens budget of the model,
+// the messages before the index (except the pinned ones) overflow the context window
+func (c *Conversation) packContext() int {
+	model := c.GetModel()
+	budget := channel.SystemInstance.GetContextBudget(model)
+	limit := c.GetContextLength()
+
+	tokens := 0
+	for i := range c.Message {
+		if c.isPinned(i) {
+			tokens += utils.NumTokensFromMessage(c.Message[i], model)
+		}
+	}
+	if summary := c.getSummaryMessage(); summary != nil {
+		tokens += utils.NumTokensFromMessage(*summary, model)
+	}
+
+	start, count := len(c.Message), 0
+	for i, summarized := len(c.Message)-1, c.getSummarized(); i >= summarized; i-- {
+		if c.isPinned(i) {
+			continue
+		}
+
+		// the latest message is always sent, even if it overflows the budget
+		cost := utils.NumTokensFromMessage(c.Message[i], model)
+		if count > 0 && (tokens+cost > budget || (limit > 0 && count >= limit)) {
+			break
+		}
+
+		tokens += cost
+		count++
+		start = i
+	}
+
+	return start
+}
+
+// GetChatMessage returns the context of the conversation, the pinned messages and the running summary
+// are followed by as many recent messages as fit the tokens budget of the model
+func (c *Conversation) GetChatMessage() []globals.Message {
+	start := c.packContext()
+
+	var messages []globals.Message
+	for i := 0; i < start; i++ {
+		if c.isPinned(i) {
+			messages = append(messages, c.Message[i])
+		}
+	}
+	if summary := c.getSummaryMessage(); summary != nil {
+		messages = append(messages, *summary)
+	}
+
+	return append(messages, c.Message[start:]...)
+}
+
+// GetOverflowMessages returns the messages which overflow the context window and are not summarized yet,
+// the index is the number of the messages folded into the summary after they are summarized,
+// nothing overflows if the context is ignored since the earlier turns are dropped on purpose
+func (c *Conversation) GetOverflowMessages() ([]globals.Message, int) {
+	if c.IsIgnoreContext() {
+		return nil, c.Summarized
+	}
+
+	start := c.packContext()
+
+	var messages []globals.Message
+	for i := c.getSummarized(); i < start; i++ {
+		if !c.isPinned(i) {
+			messages = append(messages, c.Message[i])
+		}
+	}
+
+	return messages, start
+}
+
+func (c *Conversation) GetSummary() string {
+	if c.getSummarized() == 0 {
+		return ""
+	}
+	return c.Summary
+}
+
+// SetSummary replaces the first summarized messages with the summary in the context
+func (c *Conversation) SetSummary(summary string, summarized int) {
+	c.Summary = summary
+	c.Summarized = summarized
+}
@@ -13,24 +13,29 @@ const defaultConversationName = "new chat"
 const defaultConversationContext = 8
 
 type Conversation struct {
-	Auth      bool              `json:"auth"`
-	UserID    int64             `json:"user_id"`
-	Id        int64             `json:"id"`
-	Name      string            `json:"name"`
-	Message   []globals.Message `json:"message"`
-	Model     string            `json:"model"`
-	EnableWeb bool              `json:"enable_web"`
-	Shared    bool              `json:"shared"`
-	Context   int               `json:"context"`
+	Auth       bool              `json:"auth"`
+	UserID     int64             `json:"user_id"`
+	Id         int64             `json:"id"`
+	Name       string            `json:"name"`
+	Message    []globals.Message `json:"message"`
+	Model      string            `json:"model"`
+	EnableWeb  bool              `json:"enable_web"`
+	Shared     bool              `json:"shared"`
+	Context    int               `json:"context"`    // max messages of the context, the context is also limited by the tokens budget
+	Mask       int               `json:"mask"`       // number of the mask messages at the beginning, which are always sent
+	Summary    string            `json:"summary"`    // running summary of the turns which overflow the context window
+	Summarized int               `json:"summarized"` // number of the messages folded into the summary
+	ignore     bool              // only the latest message is sent, the context is neither packed nor summarized
 }
 
 type FormMessage struct {
-	Type          string `json:"type"`
-	Message       string `json:"message"`
-	Web           bool   `json:"web"`
-	Model         string `json:"model"`
-	IgnoreContext bool   `json:"ignore_context"`
-	Context       int    `json:"context"`
+	Type          string                  `json:"type"`
+	Message       string                  `json:"message"`
+	Contents      globals.MessageContents `json:"contents,omitempty"` // typed parts of the message (e.g. images, files)
+	Web           bool                    `json:"web"`
+	Model         string                  `json:"model"`
+	IgnoreContext bool                    `json:"ignore_context"`
+	Context       int                     `json:"context"`
 }
 
 func NewAnonymousConversation() *Conversation {
@@ -53,6 +58,7 @@ func NewConversation(db *sql.DB, id int64) *Conversation {
 		Name:    defaultConversationName,
 		Message: []globals.Message{},
 		Model:   globals.GPT3Turbo,
+		Context: defaultConversationContext,
 	}
 }
 
@@ -110,6 +116,14 @@ func (c *Conversation) SetContextLength(context int) {
 	c.Context = context
 }
 
+func (c *Conversation) IsIgnoreContext() bool {
+	return c.ignore
+}
+
+func (c *Conversation) SetIgnoreContext(ignore bool) {
+	c.ignore = ignore
+}
+
 func (c *Conversation) GetName() string {
 	return c.Name
 }
@@ -150,10 +164,6 @@ func (c *Conversation) GetMessageSegment(length int) []globals.Message {
 	return c.Message[len(c.Message)-length:]
 }
 
-func (c *Conversation) GetChatMessage() []globals.Message {
-	return c.GetMessageSegment(c.GetContextLength())
-}
-
 func CopyMessage(message []globals.Message) []globals.Message {
 	return utils.DeepCopy[[]globals.Message](message) // deep copy
 }
@@ -171,11 +181,14 @@ func (c *Conversation) AddMessages(messages []globals.Message) {
 }
 
 func (c *Conversation) InsertMessage(message globals.Message, index int) {
-	c.Message = append(c.Message[:index], append([]globals.Message{message}, c.Message[index:]...)...)
+	c.InsertMessages([]globals.Message{message}, index)
 }
 
 func (c *Conversation) InsertMessages(messages []globals.Message, index int) {
 	c.Message = append(c.Message[:index], append(messages, c.Message[index:]...)...)
+	if index < c.Summarized {
+		c.Summarized += len(messages)
+	}
 }
 
 func (c *Conversation) AddMessageFromUser(message string) {
@@ -185,6 +198,14 @@ func (c *Conversation) AddMessageFromUser(message string) {
 	})
 }
 
+func (c *Conversation) AddMessageFromUserWithContents(message string, contents globals.MessageContents) {
+	c.AddMessage(globals.Message{
+		Role:     globals.User,
+		Content:  message,
+		Contents: contents,
+	})
+}
+
 func (c *Conversation) AddMessageFromAssistant(message string) {
 	c.AddMessage(globals.Message{
 		Role:    globals.Assistant,
@@ -199,6 +220,29 @@ func (c *Conversation) AddMessageFromSystem(message string) {
 	})
 }
 
+func (f *FormMessage) IsEmpty() bool {
+	return len(f.Message) == 0 && len(f.Contents) == 0
+}
+
+// GetUserMessage returns the text view and the typed parts of the form,
+// the image urls of the plain message (e.g. uploaded by the legacy client) are extracted as the image parts
+func (f *FormMessage) GetUserMessage() (string, globals.MessageContents) {
+	if len(f.Contents) == 0 {
+		return f.Message, utils.ExtractImageContents(f.Message)
+	}
+
+	contents := utils.NormalizeImageContents(f.Contents)
+	message := f.Message
+	if len(message) == 0 {
+		message = contents.GetText()
+	}
+
+	if !contents.IsMultimodal() {
+		return message, nil
+	}
+	return message, contents
+}
+
 func GetMessage(data []byte) (string, error) {
 	form, err := utils.Unmarshal[FormMessage](data)
 	form.Message = strings.TrimSpace(form.Message)
@@ -215,11 +259,12 @@ func (c *Conversation) AddMessageFromByte(data []byte) (string, error) {
 	form, err := utils.Unmarshal[FormMessage](data)
 	if err != nil {
 		return "", err
-	} else if len(form.Message) == 0 {
+	} else if form.IsEmpty() {
 		return "", errors.New("message is empty")
 	}
 
-	c.AddMessageFromUser(form.Message)
+	message, contents := form.GetUserMessage()
+	c.AddMessageFromUserWithContents(message, contents)
 	c.SetModel(form.Model)
 	c.SetEnableWeb(form.Web)
 
@@ -230,15 +275,16 @@ func (c *Conversation) AddMessageFromByte(data []byte) (string, error) {
 	}
 
 	c.SetContextLength(form.Context)
-	return form.Message, nil
+	c.SetIgnoreContext(form.IgnoreContext)
+	return message, nil
 }
 
 func (c *Conversation) AddMessageFromForm(form *FormMessage) error {
-	if len(form.Message) == 0 {
+	if form.IsEmpty() {
 		return errors.New("message is empty")
 	}
 
-	c.AddMessageFromUser(form.Message)
+	c.AddMessageFromUserWithContents(form.GetUserMessage())
 	c.SetModel(form.Model)
 	c.SetEnableWeb(form.Web)
 	if form.IgnoreContext {
@@ -248,6 +294,7 @@ func (c *Conversation) AddMessageFromForm(form *FormMessage) error {
 	}
 
 	c.SetContextLength(form.Context)
+	c.SetIgnoreContext(form.IgnoreContext)
 	return nil
 }
 
@@ -257,7 +304,7 @@ func (c *Conversation) HandleMessage(db *sql.DB, form *FormMessage) bool {
 		return false
 	}
 	if head {
-		c.SetName(db, form.Message)
+		c.SetName(db, c.GetLatestMessage())
 	}
 	c.SaveConversation(db)
 	return true
@@ -291,6 +338,12 @@ func (c *Conversation) RemoveMessage(index int) globals.Message {
 	}
 	message := c.Message[index]
 	c.Message = append(c.Message[:index], c.Message[index+1:]...)
+	if index < c.Summarized {
+		c.Summarized--
+	}
+	if index < c.Mask {
+		c.Mask--
+	}
 	return message
 }
 
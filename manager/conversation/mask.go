@@ -9,5 +9,6 @@ func (c *Conversation) LoadMask(data string) {
 	message := utils.UnmarshalForm[[]globals.Message](data)
 	if message != nil && len(*message) > 0 {
 		c.InsertMessages(*message, 0)
+		c.Mask += len(*message)
 	}
 }
@@ -16,8 +16,10 @@ func (c *Conversation) SaveConversation(db *sql.DB) bool {
 
 	data := utils.ToJson(c.GetMessage())
 	query := `
-		INSERT INTO conversation (user_id, conversation_id, conversation_name, data, model) VALUES (?, ?, ?, ?, ?)
-		ON DUPLICATE KEY UPDATE conversation_name = VALUES(conversation_name), data = VALUES(data)
+		INSERT INTO conversation (user_id, conversation_id, conversation_name, data, model, summary, summarized, mask)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE conversation_name = VALUES(conversation_name), data = VALUES(data),
+			summary = VALUES(summary), summarized = VALUES(summarized), mask = VALUES(mask)
 	`
 
 	stmt, err := db.Prepare(query)
@@ -31,7 +33,7 @@ func (c *Conversation) SaveConversation(db *sql.DB) bool {
 		}
 	}(stmt)
 
-	_, err = stmt.Exec(c.UserID, c.Id, c.Name, data, c.Model)
+	_, err = stmt.Exec(c.UserID, c.Id, c.Name, data, c.Model, c.Summary, c.Summarized, c.Mask)
 	if err != nil {
 		globals.Info(fmt.Sprintf("execute error during save conversation: %s", err.Error()))
 		return false
@@ -54,13 +56,14 @@ func LoadConversation(db *sql.DB, userId int64, conversationId int64) *Conversat
 	}
 
 	var (
-		data  string
-		model interface{}
+		data    string
+		model   interface{}
+		summary sql.NullString
 	)
 	err := db.QueryRow(`
-		SELECT conversation_name, model, data FROM conversation
+		SELECT conversation_name, model, data, summary, summarized, mask FROM conversation
 		WHERE user_id = ? AND conversation_id = ?
-		`, userId, conversationId).Scan(&conversation.Name, &model, &data)
+		`, userId, conversationId).Scan(&conversation.Name, &model, &data, &summary, &conversation.Summarized, &conversation.Mask)
 	if value, ok := model.([]byte); ok {
 		conversation.Model = string(value)
 	} else {
@@ -71,6 +74,7 @@ func LoadConversation(db *sql.DB, userId int64, conversationId int64) *Conversat
 		return nil
 	}
 
+	conversation.Summary = summary.String
 	conversation.Message, err = utils.Unmarshal[[]globals.Message]([]byte(data))
 	if err != nil {
 		return nil
@@ -0,0 +1,107 @@
+package manager
+
+import (
+	"chat/adapter"
+	adaptercommon "chat/adapter/common"
+	"chat/admin"
+	"chat/auth"
+	"chat/channel"
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+func EmbeddingRelayAPI(c *gin.Context) {
+	username := utils.GetUserFromContext(c)
+	if username == "" {
+		abortWithErrorResponse(c, fmt.Errorf("access denied for invalid api key"), "authentication_error")
+		return
+	}
+
+	if utils.GetAgentFromContext(c) != "api" {
+		abortWithErrorResponse(c, fmt.Errorf("access denied for invalid agent"), "authentication_error")
+		return
+	}
+
+	var form RelayEmbeddingForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		abortWithErrorResponse(c, fmt.Errorf("invalid request body: %s", err.Error()), "invalid_request_error")
+		return
+	}
+
+	input, err := adaptercommon.ParseEmbeddingInput(form.Input)
+	if err != nil {
+		abortWithErrorResponse(c, fmt.Errorf("invalid input: %s", err.Error()), "invalid_request_error")
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	user := &auth.User{
+		Username: username,
+	}
+
+	if strings.HasSuffix(form.Model, "-official") {
+		form.Model = strings.TrimSuffix(form.Model, "-official")
+		form.Official = true
+	}
+
+	check := auth.CanEnableModel(db, user, form.Model)
+	if !check {
+		sendErrorResponse(c, fmt.Errorf("quota exceeded"), "quota_exceeded_error")
+		return
+	}
+
+	createRelayEmbeddingObject(c, form, input, user)
+}
+
+func getEmbeddingProps(form RelayEmbeddingForm, input *adaptercommon.EmbeddingInput) *adapter.EmbeddingProps {
+	return &adapter.EmbeddingProps{
+		Model:          form.Model,
+		Input:          input,
+		EncodingFormat: form.EncodingFormat,
+		Dimensions:     form.Dimensions,
+		User:           form.User,
+	}
+}
+
+func createRelayEmbeddingObject(c *gin.Context, form RelayEmbeddingForm, input *adaptercommon.EmbeddingInput, user *auth.User) {
+	db := utils.GetDBFromContext(c)
+	cache := utils.GetCacheFromContext(c)
+
+	resp, err := channel.NewEmbeddingRequest(auth.GetGroup(db, user), getEmbeddingProps(form, input))
+
+	// embeddings are billed on input tokens only, prefer the usage reported by the upstream
+	tokens := input.CountTokens(form.Model)
+	if resp != nil && resp.Usage.PromptTokens > 0 {
+		tokens = resp.Usage.PromptTokens
+	}
+
+	buffer := utils.NewEmbeddingBuffer(form.Model, tokens, channel.ChargeInstance.GetCharge(form.Model))
+	admin.AnalysisRequest(form.Model, buffer, err)
+	if err != nil {
+		auth.RevertSubscriptionUsage(db, cache, user, form.Model)
+		globals.Warn(fmt.Sprintf("error from embedding request api: %s (instance: %s, client: %s)", err, form.Model, c.ClientIP()))
+
+		sendErrorResponse(c, err)
+		return
+	}
+
+	quota := buffer.GetQuota()
+	if quota > 0 {
+		user.UseQuota(db, quota)
+	}
+
+	c.JSON(http.StatusOK, RelayEmbeddingResponse{
+		Object: "list",
+		Data:   resp.Data,
+		Model:  form.Model,
+		Usage: adaptercommon.EmbeddingUsage{
+			PromptTokens: tokens,
+			TotalTokens:  tokens,
+		},
+		Quota: utils.Multi[*float32](form.Official, nil, utils.ToPtr(quota)),
+	})
+}
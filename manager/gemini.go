@@ -0,0 +1,437 @@
+package manager
+
+import (
+	"chat/adapter"
+	"chat/addition/web"
+	"chat/admin"
+	"chat/auth"
+	"chat/channel"
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// the gemini generateContent api (/v1beta/models/{model}:generateContent) is converted to the chat request,
+// so that the google sdks can be relayed to any channel
+
+const (
+	geminiGenerateAction       = "generateContent"
+	geminiStreamGenerateAction = "streamGenerateContent"
+)
+
+func getGeminiStatus(code int) string {
+	switch code {
+	case http.StatusBadRequest:
+		return "INVALID_ARGUMENT"
+	case http.StatusUnauthorized:
+		return "UNAUTHENTICATED"
+	case http.StatusForbidden:
+		return "PERMISSION_DENIED"
+	case http.StatusNotFound:
+		return "NOT_FOUND"
+	case http.StatusTooManyRequests:
+		return "RESOURCE_EXHAUSTED"
+	default:
+		return "UNAVAILABLE"
+	}
+}
+
+func getGeminiError(code int, err error) *GeminiError {
+	return &GeminiError{
+		Code:    code,
+		Message: err.Error(),
+		Status:  getGeminiStatus(code),
+	}
+}
+
+func sendGeminiErrorResponse(c *gin.Context, code int, err error) {
+	c.JSON(code, RelayGeminiErrorResponse{
+		Error: *getGeminiError(code, err),
+	})
+}
+
+// getGeminiAction splits the path param (e.g. gemini-pro:generateContent) to the model and the action
+func getGeminiAction(param string) (string, string) {
+	param = strings.TrimPrefix(param, "models/")
+	index := strings.LastIndex(param, ":")
+	if index == -1 {
+		return param, ""
+	}
+
+	return param[:index], param[index+1:]
+}
+
+func getGeminiText(content *GeminiContent) string {
+	if content == nil {
+		return ""
+	}
+
+	var texts []string
+	for _, part := range content.Parts {
+		if part.Text != nil && len(*part.Text) > 0 {
+			texts = append(texts, *part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// transformGeminiForm converts the gemini contents to the chat messages,
+// the function calls of gemini have no id, so the function responses are matched with the calls by the name
+func transformGeminiForm(form RelayGeminiForm) ([]globals.Message, error) {
+	var messages []globals.Message
+	if system := getGeminiText(form.SystemInstruction); len(system) > 0 {
+		messages = append(messages, globals.Message{Role: globals.System, Content: system})
+	}
+
+	pending := map[string][]string{}
+	for _, content := range form.Contents {
+		role := globals.User
+		switch content.Role {
+		case "", "user", "function":
+		case "model":
+			role = globals.Assistant
+		default:
+			return nil, fmt.Errorf("content role must be user or model, got %s", content.Role)
+		}
+
+		var (
+			contents globals.MessageContents
+			calls    globals.ToolCalls
+		)
+		for _, part := range content.Parts {
+			inline := utils.Multi(part.InlineData != nil, part.InlineData, part.InlineDataAlias)
+
+			switch {
+			case part.Text != nil:
+				contents = append(contents, globals.NewTextContent(*part.Text))
+			case inline != nil:
+				contents = append(contents, globals.NewImageContent(inline.MimeType, inline.Data))
+			case part.FileData != nil:
+				contents = append(contents, globals.NewImageUrlContent(part.FileData.FileUri))
+			case part.FunctionCall != nil:
+				id := fmt.Sprintf("call_%d_%d", len(messages), len(calls))
+				pending[part.FunctionCall.Name] = append(pending[part.FunctionCall.Name], id)
+
+				calls = append(calls, globals.ToolCall{
+					Type: "function",
+					Id:   globals.ToolCallId(id),
+					Function: globals.ToolCallFunction{
+						Name:      part.FunctionCall.Name,
+						Arguments: utils.Marshal(utils.Multi[interface{}](part.FunctionCall.Args == nil, map[string]interface{}{}, part.FunctionCall.Args)),
+					},
+				})
+			case part.FunctionResponse != nil:
+				name := part.FunctionResponse.Name
+				ids := pending[name]
+				if len(ids) == 0 {
+					return nil, fmt.Errorf("function response %s has no matching function call", name)
+				}
+				id := ids[0]
+				pending[name] = ids[1:]
+
+				messages = append(messages, globals.Message{
+					Role:       globals.Tool,
+					Content:    utils.Marshal(part.FunctionResponse.Response),
+					ToolCallId: &id,
+				})
+			default:
+				return nil, fmt.Errorf("content part is empty or not supported")
+			}
+		}
+
+		if len(contents) == 0 && len(calls) == 0 {
+			continue
+		}
+
+		message := globals.Message{Role: role}
+		if len(contents) > 0 {
+			contents = utils.NormalizeImageContents(contents)
+			message.Content = contents.GetText()
+			if contents.IsMultimodal() {
+				message.Contents = contents
+			}
+		}
+		if len(calls) > 0 {
+			message.ToolCalls = &calls
+		}
+		messages = append(messages, message)
+	}
+
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("contents must not be empty")
+	}
+	return messages, nil
+}
+
+func getGeminiTools(form RelayGeminiForm) *globals.FunctionTools {
+	var tools globals.FunctionTools
+	for _, tool := range form.Tools {
+		for _, declaration := range tool.FunctionDeclarations {
+			parameters := utils.MapToStruct[globals.ToolParameters](declaration.Parameters)
+			tools = append(tools, globals.ToolObject{
+				Type: "function",
+				Function: globals.ToolFunction{
+					Name:        declaration.Name,
+					Description: declaration.Description,
+					Parameters:  utils.GetPtrVal(parameters, globals.ToolParameters{Type: "object"}),
+				},
+			})
+		}
+	}
+
+	if len(tools) == 0 {
+		return nil
+	}
+	return &tools
+}
+
+// getGeminiToolChoice converts the function calling config of gemini to the openai tool choice
+func getGeminiToolChoice(form RelayGeminiForm) *interface{} {
+	if form.ToolConfig == nil {
+		return nil
+	}
+
+	conf := form.ToolConfig.FunctionCallingConfig
+	var choice interface{}
+	switch strings.ToUpper(conf.Mode) {
+	case "ANY":
+		if len(conf.AllowedFunctionNames) == 1 {
+			choice = map[string]interface{}{
+				"type":     "function",
+				"function": map[string]string{"name": conf.AllowedFunctionNames[0]},
+			}
+		} else {
+			choice = "required"
+		}
+	case "NONE":
+		choice = "none"
+	default:
+		choice = "auto"
+	}
+	return &choice
+}
+
+func getGeminiProps(model string, form RelayGeminiForm, messages []globals.Message, buffer *utils.Buffer) *adapter.ChatProps {
+	conf := form.GenerationConfig
+	return &adapter.ChatProps{
+		Model:       model,
+		Message:     messages,
+		Token:       utils.Multi(conf.MaxOutputTokens == 0, 2500, conf.MaxOutputTokens),
+		Temperature: conf.Temperature,
+		TopP:        conf.TopP,
+		TopK:        conf.TopK,
+		Tools:       getGeminiTools(form),
+		ToolChoice:  getGeminiToolChoice(form),
+		Stop:        conf.StopSequences,
+		Buffer:      buffer,
+	}
+}
+
+// getGeminiFunctionCalls returns the tool calls of the buffer as the function call parts
+func getGeminiFunctionCalls(buffer *utils.Buffer) []GeminiPart {
+	calls := buffer.GetToolCalls()
+	if calls == nil {
+		return nil
+	}
+
+	return utils.Each[globals.ToolCall, GeminiPart](*calls, func(call globals.ToolCall) GeminiPart {
+		args, err := utils.Unmarshal[interface{}]([]byte(call.Function.Arguments))
+		if err != nil || args == nil {
+			args = map[string]interface{}{}
+		}
+
+		return GeminiPart{
+			FunctionCall: &GeminiFunctionCall{Name: call.Function.Name, Args: args},
+		}
+	})
+}
+
+func getGeminiUsage(buffer *utils.Buffer) *GeminiUsageMetadata {
+	return &GeminiUsageMetadata{
+		PromptTokenCount:     buffer.CountInputToken(),
+		CandidatesTokenCount: buffer.CountOutputToken(),
+		TotalTokenCount:      buffer.CountToken(),
+	}
+}
+
+func getGeminiResponse(model string, form RelayGeminiForm, parts []GeminiPart, buffer *utils.Buffer, end bool) RelayGeminiResponse {
+	return RelayGeminiResponse{
+		Candidates: []GeminiCandidate{
+			{
+				Index:        0,
+				Content:      GeminiContent{Role: "model", Parts: parts},
+				FinishReason: utils.Multi(end, "STOP", ""),
+			},
+		},
+		UsageMetadata: utils.MultiF(end, func() *GeminiUsageMetadata { return getGeminiUsage(buffer) }, nil),
+		ModelVersion:  model,
+		Quota:         utils.MultiF(end && !form.Official, func() *float32 { return utils.ToPtr(buffer.GetQuota()) }, nil),
+	}
+}
+
+func GeminiRelayAPI(c *gin.Context) {
+	username := utils.GetUserFromContext(c)
+	if username == "" {
+		sendGeminiErrorResponse(c, http.StatusUnauthorized, fmt.Errorf("access denied for invalid api key"))
+		return
+	}
+
+	if utils.GetAgentFromContext(c) != "api" {
+		sendGeminiErrorResponse(c, http.StatusUnauthorized, fmt.Errorf("access denied for invalid agent"))
+		return
+	}
+
+	model, action := getGeminiAction(c.Param("model"))
+	if action != geminiGenerateAction && action != geminiStreamGenerateAction {
+		sendGeminiErrorResponse(c, http.StatusNotFound, fmt.Errorf("action %s is not supported", action))
+		return
+	}
+
+	var form RelayGeminiForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		sendGeminiErrorResponse(c, http.StatusBadRequest, fmt.Errorf("invalid request body: %s", err.Error()))
+		return
+	}
+
+	if form.GenerationConfig.CandidateCount > 1 {
+		sendGeminiErrorResponse(c, http.StatusBadRequest, fmt.Errorf("candidateCount greater than 1 is not supported"))
+		return
+	}
+
+	messages, err := transformGeminiForm(form)
+	if err != nil {
+		sendGeminiErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	input := messages // the user input before the web search segment is moderated
+	if strings.HasPrefix(model, "web-") {
+		model = strings.TrimPrefix(model, "web-")
+		messages = web.UsingWebNativeSegment(true, messages)
+	}
+
+	if strings.HasSuffix(model, "-official") {
+		model = strings.TrimSuffix(model, "-official")
+		form.Official = true
+	}
+
+	user := &auth.User{
+		Username: username,
+	}
+	if !auth.CanEnableModel(utils.GetDBFromContext(c), user, model) {
+		sendGeminiErrorResponse(c, http.StatusForbidden, fmt.Errorf("quota exceeded"))
+		return
+	}
+
+	if err := moderateInput(c, user, model, input); err != nil {
+		sendGeminiErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if action == geminiStreamGenerateAction {
+		// google sdks request the sse format with alt=sse, otherwise the chunks are streamed as a json array
+		sendStreamGeminiResponse(c, model, form, messages, user, c.Query("alt") == "sse")
+	} else {
+		sendGeminiResponse(c, model, form, messages, user)
+	}
+}
+
+func sendGeminiResponse(c *gin.Context, model string, form RelayGeminiForm, messages []globals.Message, user *auth.User) {
+	db := utils.GetDBFromContext(c)
+	cache := utils.GetCacheFromContext(c)
+
+	buffer := utils.NewBuffer(model, messages, getChatCharge(model, messages))
+	err := channel.NewChatRequest(auth.GetGroup(db, user), getGeminiProps(model, form, messages, buffer), func(data string) error {
+		buffer.Write(data)
+		return nil
+	})
+
+	admin.AnalysisRequest(model, buffer, err)
+	if err != nil {
+		auth.RevertSubscriptionUsage(db, cache, user, model)
+		globals.Warn(fmt.Sprintf("error from gemini request api: %s (instance: %s, client: %s)", err, model, c.ClientIP()))
+
+		sendGeminiErrorResponse(c, getRelayErrorStatus(err), err)
+		return
+	}
+
+	CollectQuota(c, user, buffer, false, err)
+
+	var parts []GeminiPart
+	if text := buffer.Read(); len(text) > 0 || !buffer.IsFunctionCalling() {
+		parts = append(parts, GeminiPart{Text: utils.ToPtr(buffer.ReadWithDefault(defaultMessage))})
+	}
+	parts = append(parts, getGeminiFunctionCalls(buffer)...)
+
+	c.JSON(http.StatusOK, getGeminiResponse(model, form, parts, buffer, true))
+}
+
+func sendStreamGeminiResponse(c *gin.Context, model string, form RelayGeminiForm, messages []globals.Message, user *auth.User, sse bool) {
+	partial := make(chan RelayGeminiResponse)
+	db := utils.GetDBFromContext(c)
+	cache := utils.GetCacheFromContext(c)
+
+	go func() {
+		buffer := utils.NewBuffer(model, messages, getChatCharge(model, messages))
+		err := channel.NewChatRequest(auth.GetGroup(db, user), getGeminiProps(model, form, messages, buffer), func(data string) error {
+			if data == "" {
+				// heartbeat of the long-running task (e.g. midjourney)
+				return nil
+			}
+
+			chunk := buffer.Write(data)
+			partial <- getGeminiResponse(model, form, []GeminiPart{{Text: &chunk}}, buffer, false)
+			return nil
+		})
+
+		admin.AnalysisRequest(model, buffer, err)
+		if err != nil {
+			auth.RevertSubscriptionUsage(db, cache, user, model)
+			globals.Warn(fmt.Sprintf("error from gemini request api: %s (instance: %s, client: %s)", err.Error(), model, c.ClientIP()))
+			partial <- RelayGeminiResponse{Error: getGeminiError(getRelayErrorStatus(err), err)}
+			close(partial)
+			return
+		}
+
+		parts := getGeminiFunctionCalls(buffer)
+		if len(parts) == 0 {
+			parts = []GeminiPart{{Text: utils.ToPtr("")}}
+		}
+		partial <- getGeminiResponse(model, form, parts, buffer, true)
+
+		CollectQuota(c, user, buffer, false, err)
+		close(partial)
+	}()
+
+	rendered := false
+	c.Stream(func(w io.Writer) bool {
+		if resp, ok := <-partial; ok {
+			if resp.Error != nil && !rendered {
+				c.JSON(resp.Error.Code, RelayGeminiErrorResponse{Error: *resp.Error})
+				return false
+			}
+
+			if sse {
+				c.Render(-1, utils.NewEvent(resp))
+			} else {
+				if !rendered {
+					c.Header("Content-Type", "application/json")
+				}
+				_, _ = c.Writer.WriteString(utils.Multi(rendered, ",\r\n", "[") + utils.Marshal(resp))
+			}
+
+			rendered = true
+			return true
+		}
+
+		return false
+	})
+
+	if rendered && !sse {
+		_, _ = c.Writer.WriteString("]")
+	}
+}
@@ -2,6 +2,7 @@ package manager
 
 import (
 	"chat/adapter"
+	adaptercommon "chat/adapter/common"
 	"chat/admin"
 	"chat/auth"
 	"chat/channel"
@@ -9,20 +10,18 @@ import (
 	"chat/utils"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"io"
 	"net/http"
 	"strings"
 	"time"
 )
 
-func ImagesRelayAPI(c *gin.Context) {
-	username := utils.GetUserFromContext(c)
-	if username == "" {
-		abortWithErrorResponse(c, fmt.Errorf("access denied for invalid api key"), "authentication_error")
-		return
-	}
+const imageB64JsonFormat = "b64_json"
+const imageFileLimit = 4 << 20 // 4MB limit of the image and the mask of the openai images api
 
-	if utils.GetAgentFromContext(c) != "api" {
-		abortWithErrorResponse(c, fmt.Errorf("access denied for invalid agent"), "authentication_error")
+func ImagesRelayAPI(c *gin.Context) {
+	user := getRelayUser(c)
+	if user == nil {
 		return
 	}
 
@@ -35,26 +34,139 @@ func ImagesRelayAPI(c *gin.Context) {
 	prompt := strings.TrimSpace(form.Prompt)
 	if prompt == "" {
 		sendErrorResponse(c, fmt.Errorf("prompt is required"), "invalid_request_error")
+		return
 	}
 
-	db := utils.GetDBFromContext(c)
-	user := &auth.User{
-		Username: username,
+	if !checkImageModel(c, user, &form) {
+		return
 	}
 
-	created := time.Now().Unix()
+	createRelayImageRequest(c, form, &adapter.ImageProps{
+		Type:   adaptercommon.ImageGeneration,
+		Prompt: prompt,
+	}, user)
+}
+
+func ImageEditsRelayAPI(c *gin.Context) {
+	relayImageFileAPI(c, adaptercommon.ImageEdit)
+}
 
-	if strings.HasSuffix(form.Model, "-official") {
-		form.Model = strings.TrimSuffix(form.Model, "-official")
+func ImageVariationsRelayAPI(c *gin.Context) {
+	relayImageFileAPI(c, adaptercommon.ImageVariation)
+}
+
+// getMultipartFile reads the file of the multipart form up to the limit, returns nil if the file is not uploaded
+func getMultipartFile(c *gin.Context, name string, limit int64) (*utils.MultipartFile, error) {
+	header, err := c.FormFile(name)
+	if err != nil {
+		if err == http.ErrMissingFile {
+			return nil, nil
+		}
+		return nil, err
+	} else if header.Size > limit {
+		return nil, fmt.Errorf("file %s exceeds the limit of %dMB", header.Filename, limit>>20)
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
 	}
+	defer file.Close()
 
-	check := auth.CanEnableModel(db, user, form.Model)
-	if !check {
+	data, err := io.ReadAll(io.LimitReader(file, limit))
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.MultipartFile{
+		Name: header.Filename,
+		Data: data,
+	}, nil
+}
+
+// checkImageModel fills the default model of the form and checks the permission and the quota of the user
+func checkImageModel(c *gin.Context, user *auth.User, form *RelayImageForm) bool {
+	if form.Model == "" {
+		// default model of the openai images api
+		form.Model = globals.Dalle2
+	}
+	form.Model = strings.TrimSuffix(form.Model, "-official")
+
+	if !auth.CanEnableModel(utils.GetDBFromContext(c), user, form.Model) {
 		sendErrorResponse(c, fmt.Errorf("quota exceeded"), "quota_exceeded_error")
+		return false
+	}
+	return true
+}
+
+// relayImageFileAPI relays the edit and variation requests, which are uploaded as the multipart form
+func relayImageFileAPI(c *gin.Context, t string) {
+	user := getRelayUser(c)
+	if user == nil {
+		return
+	}
+
+	// the body holds the image, the mask and the fields, it is limited before the multipart form is parsed
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, 2*imageFileLimit+1<<20)
+
+	var form RelayImageForm
+	if err := c.ShouldBind(&form); err != nil {
+		abortWithErrorResponse(c, fmt.Errorf("invalid request body: %s", err.Error()), "invalid_request_error")
+		return
+	}
+
+	if !checkImageModel(c, user, &form) {
+		return
+	}
+
+	image, err := getMultipartFile(c, "image", imageFileLimit)
+	if err != nil {
+		abortWithErrorResponse(c, fmt.Errorf("invalid image: %s", err.Error()), "invalid_request_error")
+		return
+	} else if image == nil {
+		abortWithErrorResponse(c, fmt.Errorf("image is required"), "invalid_request_error")
 		return
 	}
 
-	createRelayImageObject(c, form, prompt, created, user, false)
+	props := &adapter.ImageProps{
+		Type:  t,
+		Image: image,
+	}
+
+	if t == adaptercommon.ImageEdit {
+		if props.Prompt = strings.TrimSpace(form.Prompt); props.Prompt == "" {
+			sendErrorResponse(c, fmt.Errorf("prompt is required"), "invalid_request_error")
+			return
+		}
+
+		if props.Mask, err = getMultipartFile(c, "mask", imageFileLimit); err != nil {
+			abortWithErrorResponse(c, fmt.Errorf("invalid mask: %s", err.Error()), "invalid_request_error")
+			return
+		}
+	}
+
+	createRelayImageRequest(c, form, props, user)
+}
+
+func createRelayImageRequest(c *gin.Context, form RelayImageForm, props *adapter.ImageProps, user *auth.User) {
+	db := utils.GetDBFromContext(c)
+	created := time.Now().Unix()
+
+	if props.Type == adaptercommon.ImageGeneration && !channel.IsImageSupported(auth.GetGroup(db, user), form.Model) {
+		// the model is served by the chat channels (e.g. midjourney), the images are scraped from the markdown
+		createRelayImageObject(c, form, props.Prompt, created, user, false)
+		return
+	}
+
+	props.Model = form.Model
+	props.N = form.N
+	props.Size = form.Size
+	props.Quality = form.Quality
+	props.Style = form.Style
+	props.ResponseFormat = form.ResponseFormat
+	props.User = form.User
+
+	createRelayImagesObject(c, form, props, created, user)
 }
 
 func getImageProps(form RelayImageForm, messages []globals.Message, buffer *utils.Buffer, plan bool) *adapter.ChatProps {
@@ -63,19 +175,35 @@ func getImageProps(form RelayImageForm, messages []globals.Message, buffer *util
 		Message: messages,
 		Plan:    plan,
 		Token:   2500,
-		Buffer:  *buffer,
+		Buffer:  buffer,
 	}
 }
 
-func getUrlFromBuffer(buffer *utils.Buffer) string {
+// getImagesFromBuffer returns all the images of the chat response, which are encoded as base64 for the b64_json format
+func getImagesFromBuffer(buffer *utils.Buffer, format *string) []adaptercommon.ImageData {
 	content := buffer.Read()
 
-	urls := utils.ExtractImageUrls(content)
-	if len(urls) > 0 {
-		return urls[len(urls)-1]
+	urls := utils.ExtractMarkdownImages(content)
+	if len(urls) == 0 {
+		urls = utils.ExtractImageUrls(content)
+	}
+
+	images := make([]adaptercommon.ImageData, 0, len(urls))
+	for _, url := range urls {
+		if format == nil || *format != imageB64JsonFormat {
+			images = append(images, adaptercommon.ImageData{Url: url})
+			continue
+		}
+
+		_, data, err := utils.GetImageData(globals.NewImageUrlContent(url))
+		if err != nil {
+			globals.Warn(fmt.Sprintf("cannot encode image %s as base64: %s", url, err.Error()))
+			continue
+		}
+		images = append(images, adaptercommon.ImageData{B64Json: data})
 	}
 
-	return ""
+	return images
 }
 
 func createRelayImageObject(c *gin.Context, form RelayImageForm, prompt string, created int64, user *auth.User, plan bool) {
@@ -106,18 +234,52 @@ func createRelayImageObject(c *gin.Context, form RelayImageForm, prompt string,
 
 	CollectQuota(c, user, buffer, plan, err)
 
-	image := getUrlFromBuffer(buffer)
-	if image == "" {
+	images := getImagesFromBuffer(buffer, form.ResponseFormat)
+	if len(images) == 0 {
 		sendErrorResponse(c, fmt.Errorf("no image generated"), "image_generation_error")
 		return
 	}
 
 	c.JSON(http.StatusOK, RelayImageResponse{
 		Created: created,
-		Data: []RelayImageData{
-			{
-				Url: image,
-			},
-		},
+		Data:    images,
+	})
+}
+
+// createRelayImagesObject relays the request to the images api of the channel, all the images of the upstream are returned
+func createRelayImagesObject(c *gin.Context, form RelayImageForm, props *adapter.ImageProps, created int64, user *auth.User) {
+	db := utils.GetDBFromContext(c)
+	cache := utils.GetCacheFromContext(c)
+
+	var messages []globals.Message
+	if props.Prompt != "" {
+		messages = append(messages, globals.Message{Role: globals.User, Content: props.Prompt})
+	}
+
+	buffer := utils.NewBuffer(form.Model, messages, channel.ChargeInstance.GetCharge(form.Model))
+	resp, err := channel.NewImageRequest(auth.GetGroup(db, user), props)
+	if resp != nil {
+		for _, image := range resp.Data {
+			buffer.Write(utils.GetImageMarkdown(utils.Multi(image.Url != "", image.Url, imageB64JsonFormat)))
+		}
+
+		// times billing is charged per image
+		buffer.SetCount(len(resp.Data))
+	}
+
+	admin.AnalysisRequest(form.Model, buffer, err)
+	if err != nil {
+		auth.RevertSubscriptionUsage(db, cache, user, form.Model)
+		globals.Warn(fmt.Sprintf("error from image request api: %s (instance: %s, type: %s, client: %s)", err, form.Model, props.Type, c.ClientIP()))
+
+		sendErrorResponse(c, err)
+		return
+	}
+
+	CollectQuota(c, user, buffer, false, err)
+
+	c.JSON(http.StatusOK, RelayImageResponse{
+		Created: utils.Multi(resp.Created > 0, resp.Created, created),
+		Data:    resp.Data,
 	})
 }
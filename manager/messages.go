@@ -0,0 +1,512 @@
+package manager
+
+import (
+	"chat/adapter"
+	"chat/addition/web"
+	"chat/admin"
+	"chat/auth"
+	"chat/channel"
+	"chat/globals"
+	"chat/utils"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// the anthropic messages api (/v1/messages) is converted to the chat request, so that it can be relayed to any channel
+
+// statusOverloaded is the status of the anthropic `overloaded_error`, it is not defined by net/http
+const statusOverloaded = 529
+
+// getMessagesErrorType returns the anthropic error type of the relay error status
+func getMessagesErrorType(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "invalid_request_error"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case statusOverloaded:
+		return "overloaded_error"
+	default:
+		return "api_error"
+	}
+}
+
+// getMessagesErrorStatus returns the relay error status of the anthropic error type
+func getMessagesErrorStatus(errType string) int {
+	switch errType {
+	case "invalid_request_error":
+		return http.StatusBadRequest
+	case "rate_limit_error":
+		return http.StatusTooManyRequests
+	case "overloaded_error":
+		return statusOverloaded
+	default:
+		return http.StatusServiceUnavailable
+	}
+}
+
+func sendMessagesErrorResponse(c *gin.Context, status int, err error, errType string) {
+	c.JSON(status, RelayMessagesErrorResponse{
+		Type: "error",
+		Error: TranshipmentError{
+			Message: err.Error(),
+			Type:    errType,
+		},
+	})
+}
+
+// getMessagesText returns the text of the string content or the text blocks (e.g. system prompt, tool result)
+func getMessagesText(content interface{}) string {
+	switch v := content.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		blocks := utils.MapToStruct[[]MessagesContent](v)
+		if blocks == nil {
+			return ""
+		}
+
+		var texts []string
+		for _, block := range *blocks {
+			if block.Type == "text" && len(block.Text) > 0 {
+				texts = append(texts, block.Text)
+			}
+		}
+		return strings.Join(texts, "\n")
+	}
+}
+
+func getMessagesImage(source *MessagesImageSource) (globals.MessageContent, error) {
+	if source == nil {
+		return globals.MessageContent{}, fmt.Errorf("image source is required")
+	}
+
+	switch source.Type {
+	case "base64":
+		return globals.NewImageContent(source.MediaType, source.Data), nil
+	case "url":
+		return globals.NewImageUrlContent(source.Url), nil
+	default:
+		return globals.MessageContent{}, fmt.Errorf("image source type %s is not supported", source.Type)
+	}
+}
+
+// transformMessagesContent converts the anthropic message to the chat messages,
+// the tool results are split to the tool messages which answer the tool calls of the previous assistant message
+func transformMessagesContent(message MessagesMessage) ([]globals.Message, error) {
+	if message.Role != globals.User && message.Role != globals.Assistant {
+		return nil, fmt.Errorf("message role must be user or assistant, got %s", message.Role)
+	}
+
+	if content, ok := message.Content.(string); ok {
+		return []globals.Message{{Role: message.Role, Content: content}}, nil
+	}
+
+	blocks := utils.MapToStruct[[]MessagesContent](message.Content)
+	if blocks == nil {
+		return nil, fmt.Errorf("content of the %s message must be a string or content blocks", message.Role)
+	}
+
+	var (
+		messages []globals.Message
+		contents globals.MessageContents
+		calls    globals.ToolCalls
+	)
+	for _, block := range *blocks {
+		switch block.Type {
+		case "text":
+			contents = append(contents, globals.NewTextContent(block.Text))
+		case "image":
+			image, err := getMessagesImage(block.Source)
+			if err != nil {
+				return nil, err
+			}
+			contents = append(contents, image)
+		case "tool_use":
+			calls = append(calls, globals.ToolCall{
+				Type: "function",
+				Id:   globals.ToolCallId(block.Id),
+				Function: globals.ToolCallFunction{
+					Name:      block.Name,
+					Arguments: utils.Marshal(utils.Multi[interface{}](block.Input == nil, map[string]interface{}{}, block.Input)),
+				},
+			})
+		case "tool_result":
+			id := block.ToolUseId
+			messages = append(messages, globals.Message{
+				Role:       globals.Tool,
+				Content:    getMessagesText(block.Content),
+				ToolCallId: &id,
+			})
+		default:
+			return nil, fmt.Errorf("content block type %s is not supported", block.Type)
+		}
+	}
+
+	if len(contents) == 0 && len(calls) == 0 {
+		return messages, nil
+	}
+
+	result := globals.Message{Role: message.Role}
+	if len(contents) > 0 {
+		contents = utils.NormalizeImageContents(contents)
+		result.Content = contents.GetText()
+		if contents.IsMultimodal() {
+			result.Contents = contents
+		}
+	}
+	if len(calls) > 0 {
+		result.ToolCalls = &calls
+	}
+
+	return append(messages, result), nil
+}
+
+func transformMessagesForm(form RelayMessagesForm) ([]globals.Message, error) {
+	var messages []globals.Message
+	if system := getMessagesText(form.System); len(system) > 0 {
+		messages = append(messages, globals.Message{Role: globals.System, Content: system})
+	}
+
+	for _, message := range form.Messages {
+		segment, err := transformMessagesContent(message)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, segment...)
+	}
+
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("messages must not be empty")
+	}
+	return messages, nil
+}
+
+func getMessagesTools(form RelayMessagesForm) *globals.FunctionTools {
+	if len(form.Tools) == 0 {
+		return nil
+	}
+
+	tools := globals.FunctionTools(utils.Each[MessagesTool, globals.ToolObject](form.Tools, func(tool MessagesTool) globals.ToolObject {
+		parameters := utils.MapToStruct[globals.ToolParameters](tool.InputSchema)
+		return globals.ToolObject{
+			Type: "function",
+			Function: globals.ToolFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  utils.GetPtrVal(parameters, globals.ToolParameters{Type: "object"}),
+			},
+		}
+	}))
+	return &tools
+}
+
+// getMessagesToolChoice converts the anthropic tool choice to the openai format
+func getMessagesToolChoice(form RelayMessagesForm) *interface{} {
+	if form.ToolChoice == nil {
+		return nil
+	}
+
+	var choice interface{}
+	switch form.ToolChoice.Type {
+	case "any":
+		choice = "required"
+	case "none":
+		choice = "none"
+	case "tool":
+		choice = map[string]interface{}{
+			"type":     "function",
+			"function": map[string]string{"name": form.ToolChoice.Name},
+		}
+	default:
+		choice = "auto"
+	}
+	return &choice
+}
+
+func getMessagesProps(form RelayMessagesForm, messages []globals.Message, buffer *utils.Buffer) *adapter.ChatProps {
+	return &adapter.ChatProps{
+		Model:       form.Model,
+		Message:     messages,
+		Token:       form.MaxTokens,
+		Temperature: form.Temperature,
+		TopP:        form.TopP,
+		TopK:        form.TopK,
+		Tools:       getMessagesTools(form),
+		ToolChoice:  getMessagesToolChoice(form),
+		Stop:        form.StopSequences,
+		Buffer:      buffer,
+	}
+}
+
+// getMessagesToolUses returns the tool calls of the buffer as the tool_use blocks
+func getMessagesToolUses(buffer *utils.Buffer) []MessagesToolUseBlock {
+	calls := buffer.GetToolCalls()
+	if calls == nil {
+		return nil
+	}
+
+	return utils.Each[globals.ToolCall, MessagesToolUseBlock](*calls, func(call globals.ToolCall) MessagesToolUseBlock {
+		input, err := utils.Unmarshal[interface{}]([]byte(call.Function.Arguments))
+		if err != nil || input == nil {
+			input = map[string]interface{}{}
+		}
+
+		return MessagesToolUseBlock{
+			Type:  "tool_use",
+			Id:    string(call.Id),
+			Name:  call.Function.Name,
+			Input: input,
+		}
+	})
+}
+
+func getMessagesStopReason(buffer *utils.Buffer) string {
+	if buffer.IsFunctionCalling() {
+		return "tool_use"
+	}
+	return "end_turn"
+}
+
+func getMessagesUsage(buffer *utils.Buffer) MessagesUsage {
+	return MessagesUsage{
+		InputTokens:  buffer.CountInputToken(),
+		OutputTokens: buffer.CountOutputToken(),
+	}
+}
+
+func MessagesRelayAPI(c *gin.Context) {
+	username := utils.GetUserFromContext(c)
+	if username == "" {
+		sendMessagesErrorResponse(c, http.StatusUnauthorized, fmt.Errorf("access denied for invalid api key"), "authentication_error")
+		return
+	}
+
+	if utils.GetAgentFromContext(c) != "api" {
+		sendMessagesErrorResponse(c, http.StatusUnauthorized, fmt.Errorf("access denied for invalid agent"), "authentication_error")
+		return
+	}
+
+	var form RelayMessagesForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		sendMessagesErrorResponse(c, http.StatusBadRequest, fmt.Errorf("invalid request body: %s", err.Error()), "invalid_request_error")
+		return
+	}
+
+	messages, err := transformMessagesForm(form)
+	if err != nil {
+		sendMessagesErrorResponse(c, http.StatusBadRequest, err, "invalid_request_error")
+		return
+	}
+
+	input := messages // the user input before the web search segment is moderated
+	if strings.HasPrefix(form.Model, "web-") {
+		form.Model = strings.TrimPrefix(form.Model, "web-")
+		messages = web.UsingWebNativeSegment(true, messages)
+	}
+
+	if strings.HasSuffix(form.Model, "-official") {
+		form.Model = strings.TrimSuffix(form.Model, "-official")
+		form.Official = true
+	}
+
+	user := &auth.User{
+		Username: username,
+	}
+	if !auth.CanEnableModel(utils.GetDBFromContext(c), user, form.Model) {
+		sendMessagesErrorResponse(c, http.StatusForbidden, fmt.Errorf("quota exceeded"), "permission_error")
+		return
+	}
+
+	if err := moderateInput(c, user, form.Model, input); err != nil {
+		sendMessagesErrorResponse(c, http.StatusBadRequest, err, "invalid_request_error")
+		return
+	}
+
+	id := fmt.Sprintf("msg_%s", utils.Md5Encrypt(username+form.Model+time.Now().String()))
+	if form.Stream {
+		sendStreamMessagesResponse(c, form, messages, id, user)
+	} else {
+		sendMessagesResponse(c, form, messages, id, user)
+	}
+}
+
+func sendMessagesResponse(c *gin.Context, form RelayMessagesForm, messages []globals.Message, id string, user *auth.User) {
+	db := utils.GetDBFromContext(c)
+	cache := utils.GetCacheFromContext(c)
+
+	buffer := utils.NewBuffer(form.Model, messages, getChatCharge(form.Model, messages))
+	err := channel.NewChatRequest(auth.GetGroup(db, user), getMessagesProps(form, messages, buffer), func(data string) error {
+		buffer.Write(data)
+		return nil
+	})
+
+	admin.AnalysisRequest(form.Model, buffer, err)
+	if err != nil {
+		auth.RevertSubscriptionUsage(db, cache, user, form.Model)
+		globals.Warn(fmt.Sprintf("error from messages request api: %s (instance: %s, client: %s)", err, form.Model, c.ClientIP()))
+
+		status := getRelayErrorStatus(err)
+		sendMessagesErrorResponse(c, status, err, getMessagesErrorType(status))
+		return
+	}
+
+	CollectQuota(c, user, buffer, false, err)
+
+	var content []interface{}
+	if text := buffer.Read(); len(text) > 0 || !buffer.IsFunctionCalling() {
+		content = append(content, MessagesTextBlock{Type: "text", Text: buffer.ReadWithDefault(defaultMessage)})
+	}
+	for _, block := range getMessagesToolUses(buffer) {
+		content = append(content, block)
+	}
+
+	c.JSON(http.StatusOK, RelayMessagesResponse{
+		Id:         id,
+		Type:       "message",
+		Role:       globals.Assistant,
+		Model:      form.Model,
+		Content:    content,
+		StopReason: utils.ToPtr(getMessagesStopReason(buffer)),
+		Usage:      getMessagesUsage(buffer),
+		Quota:      utils.Multi[*float32](form.Official, nil, utils.ToPtr(buffer.GetQuota())),
+	})
+}
+
+// getMessagesStartEvents returns the events before the first text delta
+func getMessagesStartEvents(form RelayMessagesForm, id string, buffer *utils.Buffer) []RelayMessagesStreamEvent {
+	return []RelayMessagesStreamEvent{
+		{
+			Type: "message_start",
+			Message: &RelayMessagesResponse{
+				Id:      id,
+				Type:    "message",
+				Role:    globals.Assistant,
+				Model:   form.Model,
+				Content: []interface{}{},
+				Usage:   MessagesUsage{InputTokens: buffer.CountInputToken()},
+			},
+		},
+		{
+			Type:         "content_block_start",
+			Index:        utils.ToPtr(0),
+			ContentBlock: MessagesTextBlock{Type: "text"},
+		},
+		{Type: "ping"},
+	}
+}
+
+// getMessagesStopEvents returns the events after the text stream, the tool calls are sent as the tool_use blocks
+func getMessagesStopEvents(form RelayMessagesForm, buffer *utils.Buffer) []RelayMessagesStreamEvent {
+	events := []RelayMessagesStreamEvent{
+		{Type: "content_block_stop", Index: utils.ToPtr(0)},
+	}
+
+	for i, block := range getMessagesToolUses(buffer) {
+		index := i + 1
+		events = append(events,
+			RelayMessagesStreamEvent{
+				Type:         "content_block_start",
+				Index:        utils.ToPtr(index),
+				ContentBlock: MessagesToolUseBlock{Type: block.Type, Id: block.Id, Name: block.Name, Input: map[string]interface{}{}},
+			},
+			RelayMessagesStreamEvent{
+				Type:  "content_block_delta",
+				Index: utils.ToPtr(index),
+				Delta: MessagesContentDelta{Type: "input_json_delta", PartialJson: utils.Marshal(block.Input)},
+			},
+			RelayMessagesStreamEvent{Type: "content_block_stop", Index: utils.ToPtr(index)},
+		)
+	}
+
+	usage := getMessagesUsage(buffer)
+	return append(events,
+		RelayMessagesStreamEvent{
+			Type:  "message_delta",
+			Delta: MessagesMessageDelta{StopReason: getMessagesStopReason(buffer)},
+			Usage: &usage,
+		},
+		RelayMessagesStreamEvent{Type: "message_stop"},
+	)
+}
+
+func sendStreamMessagesResponse(c *gin.Context, form RelayMessagesForm, messages []globals.Message, id string, user *auth.User) {
+	partial := make(chan RelayMessagesStreamEvent)
+	db := utils.GetDBFromContext(c)
+	cache := utils.GetCacheFromContext(c)
+
+	go func() {
+		buffer := utils.NewBuffer(form.Model, messages, getChatCharge(form.Model, messages))
+
+		// the message is started with the first chunk, so that the error before it is responded with the status code
+		started := false
+		start := func() {
+			if started {
+				return
+			}
+
+			started = true
+			for _, event := range getMessagesStartEvents(form, id, buffer) {
+				partial <- event
+			}
+		}
+
+		err := channel.NewChatRequest(auth.GetGroup(db, user), getMessagesProps(form, messages, buffer), func(data string) error {
+			if data == "" {
+				// heartbeat of the long-running task (e.g. midjourney)
+				return nil
+			}
+
+			start()
+			partial <- RelayMessagesStreamEvent{
+				Type:  "content_block_delta",
+				Index: utils.ToPtr(0),
+				Delta: MessagesContentDelta{Type: "text_delta", Text: buffer.Write(data)},
+			}
+			return nil
+		})
+
+		admin.AnalysisRequest(form.Model, buffer, err)
+		if err != nil {
+			auth.RevertSubscriptionUsage(db, cache, user, form.Model)
+			globals.Warn(fmt.Sprintf("error from messages request api: %s (instance: %s, client: %s)", err.Error(), form.Model, c.ClientIP()))
+			partial <- RelayMessagesStreamEvent{
+				Type:  "error",
+				Error: &TranshipmentError{Message: err.Error(), Type: getMessagesErrorType(getRelayErrorStatus(err))},
+			}
+			close(partial)
+			return
+		}
+
+		start()
+		for _, event := range getMessagesStopEvents(form, buffer) {
+			partial <- event
+		}
+
+		CollectQuota(c, user, buffer, false, err)
+		close(partial)
+	}()
+
+	rendered := false
+	c.Stream(func(w io.Writer) bool {
+		if resp, ok := <-partial; ok {
+			if resp.Error != nil && !rendered {
+				sendMessagesErrorResponse(c, getMessagesErrorStatus(resp.Error.Type), errors.New(resp.Error.Message), resp.Error.Type)
+				return false
+			}
+
+			rendered = true
+			c.Render(-1, utils.NewNamedEvent(resp.Type, resp))
+			return true
+		}
+
+		return false
+	})
+}
@@ -0,0 +1,151 @@
+package manager
+
+import (
+	"chat/adapter"
+	"chat/adapter/midjourney"
+	"chat/admin"
+	"chat/auth"
+	"chat/channel"
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// getChatCharge returns the charge of the chat request, the midjourney actions are billed by
+// the rule of the action model (e.g. `midjourney-fast-upscale`) if it exists, otherwise by the model
+func getChatCharge(model string, messages []globals.Message) *channel.Charge {
+	if !globals.IsMidjourneyModel(model) {
+		return channel.ChargeInstance.GetCharge(model)
+	}
+
+	return channel.ChargeInstance.GetChargeOrDefault(midjourney.GetBillingModel(model, messages), model)
+}
+
+// getMidjourneyMessage converts the relay form to the midjourney chat command
+func getMidjourneyMessage(action string, form RelayMidjourneyForm) (*globals.Message, error) {
+	switch action {
+	case midjourney.ImagineAction:
+		prompt := strings.TrimSpace(form.Prompt)
+		if prompt == "" {
+			return nil, fmt.Errorf("prompt is required")
+		}
+		return &globals.Message{Role: globals.User, Content: fmt.Sprintf("/imagine %s", prompt)}, nil
+	case midjourney.DescribeAction:
+		image := strings.TrimSpace(form.Image)
+		if image == "" {
+			return nil, fmt.Errorf("image is required")
+		}
+
+		contents := utils.NormalizeImageContents(globals.MessageContents{
+			globals.NewTextContent("/describe"),
+			globals.NewImageUrlContent(image),
+		})
+		return &globals.Message{Role: globals.User, Content: "/describe", Contents: contents}, nil
+	case midjourney.UpscaleAction, midjourney.VariationAction, midjourney.RerollAction, midjourney.ZoomAction, midjourney.PanAction:
+		if strings.TrimSpace(form.TaskId) == "" {
+			return nil, fmt.Errorf("task_id is required")
+		}
+
+		args := []string{"/" + action, strings.TrimSpace(form.TaskId)}
+		switch action {
+		case midjourney.UpscaleAction, midjourney.VariationAction:
+			args = append(args, fmt.Sprintf("%d", form.Index))
+		case midjourney.ZoomAction:
+			args = append(args, form.Scale)
+		case midjourney.PanAction:
+			args = append(args, form.Direction)
+		}
+		return &globals.Message{Role: globals.User, Content: strings.TrimSpace(strings.Join(args, " "))}, nil
+	default:
+		return nil, fmt.Errorf("unknown midjourney action %s", action)
+	}
+}
+
+func MidjourneyRelayAPI(c *gin.Context) {
+	username := utils.GetUserFromContext(c)
+	if username == "" {
+		abortWithErrorResponse(c, fmt.Errorf("access denied for invalid api key"), "authentication_error")
+		return
+	}
+
+	if utils.GetAgentFromContext(c) != "api" {
+		abortWithErrorResponse(c, fmt.Errorf("access denied for invalid agent"), "authentication_error")
+		return
+	}
+
+	var form RelayMidjourneyForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		abortWithErrorResponse(c, fmt.Errorf("invalid request body: %s", err.Error()), "invalid_request_error")
+		return
+	}
+
+	if !globals.IsMidjourneyModel(form.Model) {
+		sendErrorResponse(c, fmt.Errorf("model %s is not a midjourney model", form.Model), "invalid_request_error")
+		return
+	}
+
+	action := strings.ToLower(c.Param("action"))
+	message, err := getMidjourneyMessage(action, form)
+	if err != nil {
+		sendErrorResponse(c, err, "invalid_request_error")
+		return
+	}
+
+	db := utils.GetDBFromContext(c)
+	user := &auth.User{
+		Username: username,
+	}
+
+	if !auth.CanEnableModel(db, user, form.Model) {
+		sendErrorResponse(c, fmt.Errorf("quota exceeded"), "quota_exceeded_error")
+		return
+	}
+
+	createRelayMidjourneyObject(c, form, action, []globals.Message{*message}, user)
+}
+
+func createRelayMidjourneyObject(c *gin.Context, form RelayMidjourneyForm, action string, messages []globals.Message, user *auth.User) {
+	db := utils.GetDBFromContext(c)
+	cache := utils.GetCacheFromContext(c)
+	created := time.Now().Unix()
+
+	buffer := utils.NewBuffer(form.Model, messages, getChatCharge(form.Model, messages))
+	err := channel.NewChatRequest(auth.GetGroup(db, user), &adapter.ChatProps{
+		Model:   form.Model,
+		Message: messages,
+		Buffer:  buffer,
+	}, func(data string) error {
+		buffer.Write(data)
+		return nil
+	})
+
+	admin.AnalysisRequest(form.Model, buffer, err)
+	if err != nil {
+		auth.RevertSubscriptionUsage(db, cache, user, form.Model)
+		globals.Warn(fmt.Sprintf("error from midjourney relay api: %s (instance: %s, action: %s, client: %s)", err, form.Model, action, c.ClientIP()))
+
+		sendErrorResponse(c, err)
+		return
+	}
+
+	CollectQuota(c, user, buffer, false, err)
+
+	result := midjourney.ParseResult(buffer.Read())
+	if result == nil {
+		sendErrorResponse(c, fmt.Errorf("no task result from midjourney"), "image_generation_error")
+		return
+	}
+
+	c.JSON(http.StatusOK, RelayMidjourneyResponse{
+		Created: created,
+		Action:  action,
+		TaskId:  result.TaskId,
+		Url:     result.Url,
+		Prompt:  result.Prompt,
+		Quota:   utils.ToPtr(buffer.GetQuota()),
+	})
+}
@@ -0,0 +1,151 @@
+package manager
+
+import (
+	"chat/adapter"
+	"chat/admin"
+	"chat/auth"
+	"chat/channel"
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+// ModerationError is returned when the input is flagged by the moderation and blocked by the policy of the group
+type ModerationError struct {
+	Categories []string
+}
+
+func (e *ModerationError) Error() string {
+	if len(e.Categories) == 0 {
+		return "your input is flagged by the content moderation"
+	}
+	return fmt.Sprintf("your input is flagged by the content moderation (categories: %s)", strings.Join(e.Categories, ", "))
+}
+
+// getModerationInput returns the text of the latest user message, the history has been moderated before
+func getModerationInput(messages []globals.Message) []string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != globals.User {
+			continue
+		}
+
+		if content := strings.TrimSpace(messages[i].Content); len(content) > 0 {
+			return []string{content}
+		}
+		return nil
+	}
+	return nil
+}
+
+// moderateInput runs the user input through the moderation channel before it is sent upstream,
+// it returns the error only if the input is flagged and the group blocks the flagged input
+func moderateInput(c *gin.Context, user *auth.User, model string, messages []globals.Message) error {
+	conf := channel.SystemInstance
+	if !conf.IsModerationEnabled() {
+		return nil
+	}
+
+	group := auth.GetGroup(utils.GetDBFromContext(c), user)
+	action := conf.GetModerationAction(group)
+	if action == globals.ModerationAllow {
+		return nil
+	}
+
+	input := getModerationInput(messages)
+	if len(input) == 0 {
+		return nil
+	}
+
+	resp, err := channel.NewModerationRequest(group, &adapter.ModerationProps{
+		Model: conf.GetModerationModel(),
+		Input: input,
+	})
+	if err != nil {
+		// the chat should not be broken by the unavailable moderation
+		globals.Warn(fmt.Sprintf("[moderation] skip moderation for error: %s (instance: %s, client: %s)", err.Error(), model, c.ClientIP()))
+		return nil
+	} else if !resp.IsFlagged() {
+		return nil
+	}
+
+	categories := resp.GetCategories()
+	admin.IncrModerationRequest(utils.GetCacheFromContext(c))
+	globals.Warn(fmt.Sprintf("[moderation] input is flagged (categories: %s, action: %s, group: %s, instance: %s, client: %s)",
+		strings.Join(categories, ", "), action, group, model, c.ClientIP(),
+	))
+
+	if action == globals.ModerationLog {
+		return nil
+	}
+	return &ModerationError{Categories: categories}
+}
+
+func ModerationRelayAPI(c *gin.Context) {
+	user := getRelayUser(c)
+	if user == nil {
+		return
+	}
+
+	var form RelayModerationForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		abortWithErrorResponse(c, fmt.Errorf("invalid request body: %s", err.Error()), "invalid_request_error")
+		return
+	}
+
+	input, ok := getStringArray(form.Input)
+	if !ok || len(input) == 0 {
+		abortWithErrorResponse(c, fmt.Errorf("input must be a string or a string array"), "invalid_request_error")
+		return
+	}
+
+	if form.Model == "" {
+		form.Model = globals.TextModerationLatest
+	} else if strings.HasSuffix(form.Model, "-official") {
+		form.Model = strings.TrimSuffix(form.Model, "-official")
+		form.Official = true
+	}
+
+	db := utils.GetDBFromContext(c)
+	if !auth.CanEnableModel(db, user, form.Model) {
+		sendErrorResponse(c, fmt.Errorf("quota exceeded"), "quota_exceeded_error")
+		return
+	}
+
+	createRelayModerationObject(c, form, input, user)
+}
+
+func createRelayModerationObject(c *gin.Context, form RelayModerationForm, input []string, user *auth.User) {
+	db := utils.GetDBFromContext(c)
+	cache := utils.GetCacheFromContext(c)
+
+	resp, err := channel.NewModerationRequest(auth.GetGroup(db, user), &adapter.ModerationProps{
+		Model: form.Model,
+		Input: input,
+	})
+
+	// moderations are billed on input tokens only
+	buffer := utils.NewEmbeddingBuffer(form.Model, utils.NumTokensFromTexts(input, form.Model), channel.ChargeInstance.GetCharge(form.Model))
+	admin.AnalysisRequest(form.Model, buffer, err)
+	if err != nil {
+		auth.RevertSubscriptionUsage(db, cache, user, form.Model)
+		globals.Warn(fmt.Sprintf("error from moderation request api: %s (instance: %s, client: %s)", err, form.Model, c.ClientIP()))
+
+		sendErrorResponse(c, err)
+		return
+	}
+
+	quota := buffer.GetQuota()
+	if quota > 0 {
+		user.UseQuota(db, quota)
+	}
+
+	c.JSON(http.StatusOK, RelayModerationResponse{
+		Id:      resp.Id,
+		Model:   form.Model,
+		Results: resp.Results,
+		Quota:   utils.Multi[*float32](form.Official, nil, utils.ToPtr(quota)),
+	})
+}
@@ -2,7 +2,11 @@ package manager
 
 import (
 	"chat/admin"
+	"chat/auth"
 	"chat/channel"
+	"chat/utils"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"net/http"
 )
@@ -23,15 +27,30 @@ func PlanAPI(c *gin.Context) {
 	c.JSON(http.StatusOK, channel.PlanInstance.GetPlans())
 }
 
+// getRelayErrorStatus returns the status code of the relay error, the parameters which no channel can honor
+// are the mistake of the request rather than the unavailable upstream, and the saturated channels ask the client to retry later
+func getRelayErrorStatus(err error) int {
+	if errors.Is(err, channel.ErrUnsupportedParams) {
+		return http.StatusBadRequest
+	} else if errors.Is(err, channel.ErrChannelsSaturated) {
+		return http.StatusTooManyRequests
+	}
+	return http.StatusServiceUnavailable
+}
+
 func sendErrorResponse(c *gin.Context, err error, types ...string) {
 	var errType string
 	if len(types) > 0 {
 		errType = types[0]
+	} else if status := getRelayErrorStatus(err); status == http.StatusBadRequest {
+		errType = "invalid_request_error"
+	} else if status == http.StatusTooManyRequests {
+		errType = "rate_limit_error"
 	} else {
 		errType = "chatnio_api_error"
 	}
 
-	c.JSON(http.StatusServiceUnavailable, RelayErrorResponse{
+	c.JSON(getRelayErrorStatus(err), RelayErrorResponse{
 		Error: TranshipmentError{
 			Message: err.Error(),
 			Type:    errType,
@@ -43,3 +62,21 @@ func abortWithErrorResponse(c *gin.Context, err error, types ...string) {
 	sendErrorResponse(c, err, types...)
 	c.Abort()
 }
+
+// getRelayUser returns the user of the api relay request, or nil if the request is aborted
+func getRelayUser(c *gin.Context) *auth.User {
+	username := utils.GetUserFromContext(c)
+	if username == "" {
+		abortWithErrorResponse(c, fmt.Errorf("access denied for invalid api key"), "authentication_error")
+		return nil
+	}
+
+	if utils.GetAgentFromContext(c) != "api" {
+		abortWithErrorResponse(c, fmt.Errorf("access denied for invalid agent"), "authentication_error")
+		return nil
+	}
+
+	return &auth.User{
+		Username: username,
+	}
+}
@@ -14,7 +14,18 @@ func Register(app *gin.RouterGroup) {
 	app.GET("/dashboard/billing/usage", GetBillingUsage)
 	app.GET("/dashboard/billing/subscription", GetSubscription)
 	app.POST("/v1/chat/completions", ChatRelayAPI)
+	app.POST("/v1/completions", CompletionsRelayAPI)
+	app.POST("/v1/messages", MessagesRelayAPI)
+	app.POST("/v1beta/models/:model", GeminiRelayAPI)
 	app.POST("/v1/images/generations", ImagesRelayAPI)
+	app.POST("/v1/images/edits", ImageEditsRelayAPI)
+	app.POST("/v1/images/variations", ImageVariationsRelayAPI)
+	app.POST("/v1/embeddings", EmbeddingRelayAPI)
+	app.POST("/v1/moderations", ModerationRelayAPI)
+	app.POST("/v1/audio/transcriptions", AudioTranscriptionsRelayAPI)
+	app.POST("/v1/audio/translations", AudioTranslationsRelayAPI)
+	app.POST("/v1/audio/speech", SpeechRelayAPI)
+	app.POST("/v1/midjourney/:action", MidjourneyRelayAPI)
 
 	broadcast.Register(app)
 }
@@ -0,0 +1,250 @@
+package manager
+
+import (
+	"chat/adapter"
+	adaptercommon "chat/adapter/common"
+	"chat/admin"
+	"chat/auth"
+	"chat/channel"
+	"chat/globals"
+	"chat/utils"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// getStringArray converts the string or string array field of the request to the string array
+func getStringArray(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return nil, true
+	case string:
+		return []string{v}, true
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			text, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			result = append(result, text)
+		}
+		return result, true
+	default:
+		return nil, false
+	}
+}
+
+func getCompletionPrompt(form RelayCompletionForm) (string, error) {
+	prompts, ok := getStringArray(form.Prompt)
+	if !ok {
+		return "", fmt.Errorf("prompt must be a string or a string array")
+	} else if len(prompts) != 1 {
+		return "", fmt.Errorf("only single prompt is supported")
+	}
+
+	return prompts[0], nil
+}
+
+// getStopSequences returns the stop sequences of the request (string or string array)
+func getStopSequences(value interface{}) ([]string, error) {
+	stop, ok := getStringArray(value)
+	if !ok {
+		return nil, fmt.Errorf("stop must be a string or a string array")
+	} else if len(stop) > 4 {
+		return nil, fmt.Errorf("up to 4 stop sequences are supported")
+	}
+
+	return stop, nil
+}
+
+func CompletionsRelayAPI(c *gin.Context) {
+	user := getRelayUser(c)
+	if user == nil {
+		return
+	}
+
+	var form RelayCompletionForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		abortWithErrorResponse(c, fmt.Errorf("invalid request body: %s", err.Error()), "invalid_request_error")
+		return
+	}
+
+	prompt, err := getCompletionPrompt(form)
+	if err != nil {
+		abortWithErrorResponse(c, err, "invalid_request_error")
+		return
+	}
+
+	stop, err := getStopSequences(form.Stop)
+	if err != nil {
+		abortWithErrorResponse(c, err, "invalid_request_error")
+		return
+	}
+
+	if strings.HasSuffix(form.Model, "-official") {
+		form.Model = strings.TrimSuffix(form.Model, "-official")
+		form.Official = true
+	}
+
+	if !auth.CanEnableModel(utils.GetDBFromContext(c), user, form.Model) {
+		sendErrorResponse(c, fmt.Errorf("quota exceeded"), "quota_exceeded_error")
+		return
+	}
+
+	id := fmt.Sprintf("cmpl-%s", utils.Md5Encrypt(user.Username+form.Model+time.Now().String()))
+	created := time.Now().Unix()
+	messages := []globals.Message{{Role: globals.User, Content: prompt}}
+
+	props := &adapter.CompletionProps{
+		Model:            form.Model,
+		Prompt:           prompt,
+		Suffix:           form.Suffix,
+		Echo:             form.Echo,
+		Stop:             stop,
+		Logprobs:         form.Logprobs,
+		MaxTokens:        form.MaxTokens,
+		Temperature:      form.Temperature,
+		TopP:             form.TopP,
+		PresencePenalty:  form.PresencePenalty,
+		FrequencyPenalty: form.FrequencyPenalty,
+		User:             form.User,
+	}
+
+	if form.Stream {
+		sendStreamCompletionResponse(c, form, props, messages, id, created, user)
+	} else {
+		sendCompletionResponse(c, form, props, messages, id, created, user)
+	}
+}
+
+func getCompletionUsage(buffer *utils.Buffer) *Usage {
+	return &Usage{
+		PromptTokens:     buffer.CountInputToken(),
+		CompletionTokens: buffer.CountOutputToken(),
+		TotalTokens:      buffer.CountToken(),
+	}
+}
+
+func sendCompletionResponse(c *gin.Context, form RelayCompletionForm, props *adapter.CompletionProps, messages []globals.Message, id string, created int64, user *auth.User) {
+	db := utils.GetDBFromContext(c)
+	cache := utils.GetCacheFromContext(c)
+
+	// the chunks are merged to the single choice
+	choice := adaptercommon.CompletionChoice{FinishReason: "stop"}
+
+	buffer := utils.NewBuffer(form.Model, messages, channel.ChargeInstance.GetCharge(form.Model))
+	props.Buffer = buffer
+	err := channel.NewCompletionRequest(auth.GetGroup(db, user), props, func(resp *adapter.CompletionResponse) error {
+		for _, item := range resp.Choices {
+			if item.Text != "" {
+				buffer.Write(item.Text)
+			}
+
+			choice.Logprobs = choice.Logprobs.Merge(item.Logprobs)
+			if item.FinishReason != nil {
+				choice.FinishReason = item.FinishReason
+			}
+		}
+		return nil
+	})
+
+	admin.AnalysisRequest(form.Model, buffer, err)
+	if err != nil {
+		auth.RevertSubscriptionUsage(db, cache, user, form.Model)
+		globals.Warn(fmt.Sprintf("error from completion request api: %s (instance: %s, client: %s)", err, form.Model, c.ClientIP()))
+
+		sendErrorResponse(c, err)
+		return
+	}
+
+	CollectQuota(c, user, buffer, false, err)
+
+	choice.Text = buffer.Read()
+	c.JSON(http.StatusOK, RelayCompletionResponse{
+		Id:      id,
+		Object:  "text_completion",
+		Created: created,
+		Model:   form.Model,
+		Choices: []adaptercommon.CompletionChoice{choice},
+		Usage:   getCompletionUsage(buffer),
+		Quota:   utils.Multi[*float32](form.Official, nil, utils.ToPtr(buffer.GetQuota())),
+	})
+}
+
+func getStreamCompletionForm(id string, created int64, form RelayCompletionForm, choices []adaptercommon.CompletionChoice, buffer *utils.Buffer, end bool, err error) RelayCompletionResponse {
+	return RelayCompletionResponse{
+		Id:      id,
+		Object:  "text_completion",
+		Created: created,
+		Model:   form.Model,
+		Choices: choices,
+		Usage:   utils.MultiF(end, func() *Usage { return getCompletionUsage(buffer) }, nil),
+		Quota:   utils.Multi[*float32](form.Official, nil, utils.ToPtr(buffer.GetQuota())),
+		Error:   err,
+	}
+}
+
+func sendStreamCompletionResponse(c *gin.Context, form RelayCompletionForm, props *adapter.CompletionProps, messages []globals.Message, id string, created int64, user *auth.User) {
+	partial := make(chan RelayCompletionResponse)
+	db := utils.GetDBFromContext(c)
+	cache := utils.GetCacheFromContext(c)
+
+	go func() {
+		finished := false
+
+		buffer := utils.NewBuffer(form.Model, messages, channel.ChargeInstance.GetCharge(form.Model))
+		props.Buffer = buffer
+		err := channel.NewCompletionRequest(auth.GetGroup(db, user), props, func(resp *adapter.CompletionResponse) error {
+			for _, item := range resp.Choices {
+				if item.Text != "" {
+					buffer.Write(item.Text)
+				}
+				if item.FinishReason != nil {
+					finished = true
+				}
+			}
+
+			if len(resp.Choices) > 0 {
+				partial <- getStreamCompletionForm(id, created, form, resp.Choices, buffer, false, nil)
+			}
+			return nil
+		})
+
+		admin.AnalysisRequest(form.Model, buffer, err)
+		if err != nil {
+			auth.RevertSubscriptionUsage(db, cache, user, form.Model)
+			globals.Warn(fmt.Sprintf("error from completion request api: %s (instance: %s, client: %s)", err.Error(), form.Model, c.ClientIP()))
+			partial <- getStreamCompletionForm(id, created, form, nil, buffer, true, err)
+			close(partial)
+			return
+		}
+
+		choices := make([]adaptercommon.CompletionChoice, 0)
+		if !finished {
+			choices = append(choices, adaptercommon.CompletionChoice{FinishReason: "stop"})
+		}
+
+		partial <- getStreamCompletionForm(id, created, form, choices, buffer, true, nil)
+		CollectQuota(c, user, buffer, false, err)
+		close(partial)
+	}()
+
+	c.Stream(func(w io.Writer) bool {
+		if resp, ok := <-partial; ok {
+			if resp.Error != nil {
+				sendErrorResponse(c, resp.Error)
+				return false
+			}
+
+			c.Render(-1, utils.NewEvent(resp))
+			return true
+		}
+
+		c.Render(-1, utils.NewEndEvent())
+		return false
+	})
+}
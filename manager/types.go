@@ -1,9 +1,9 @@
 package manager
 
 import (
+	adaptercommon "chat/adapter/common"
 	"chat/globals"
 	"chat/utils"
-	"fmt"
 )
 
 type Message struct {
@@ -13,33 +13,31 @@ type Message struct {
 	ToolCalls  *globals.ToolCalls `json:"tool_calls,omitempty"`   // only `assistant` role
 }
 
-type ImageUrl struct {
-	Url    string  `json:"url"`
-	Detail *string `json:"detail,omitempty"`
-}
-
-type MessageContent struct {
-	Type     string    `json:"type"`
-	Text     *string   `json:"text,omitempty"`
-	ImageUrl *ImageUrl `json:"image_url,omitempty"`
+type RelayForm struct {
+	Model             string                 `json:"model" binding:"required"`
+	Messages          []Message              `json:"messages" binding:"required"`
+	Stream            bool                   `json:"stream"`
+	MaxTokens         int                    `json:"max_tokens"`
+	PresencePenalty   *float32               `json:"presence_penalty"`
+	FrequencyPenalty  *float32               `json:"frequency_penalty"`
+	RepetitionPenalty *float32               `json:"repetition_penalty"`
+	Temperature       *float32               `json:"temperature"`
+	TopP              *float32               `json:"top_p"`
+	TopK              *int                   `json:"top_k"`
+	Tools             *globals.FunctionTools `json:"tools"`
+	ToolChoice        *interface{}           `json:"tool_choice"`
+	N                 *int                   `json:"n"`
+	Stop              interface{}            `json:"stop"` // string or array of strings
+	Seed              *int                   `json:"seed"`
+	User              *string                `json:"user"`
+	LogitBias         *map[string]float32    `json:"logit_bias"`
+	ResponseFormat    *interface{}           `json:"response_format"`
+	StreamOptions     *RelayStreamOptions    `json:"stream_options"`
+	Official          bool                   `json:"official"`
 }
 
-type MessageContents []MessageContent
-
-type RelayForm struct {
-	Model             string    `json:"model" binding:"required"`
-	Messages          []Message `json:"messages" binding:"required"`
-	Stream            bool      `json:"stream"`
-	MaxTokens         int       `json:"max_tokens"`
-	PresencePenalty   *float32  `json:"presence_penalty"`
-	FrequencyPenalty  *float32  `json:"frequency_penalty"`
-	RepetitionPenalty *float32  `json:"repetition_penalty"`
-	Temperature       *float32  `json:"temperature"`
-	TopP              *float32  `json:"top_p"`
-	TopK              *int      `json:"top_k"`
-	Tools             *globals.FunctionTools
-	ToolChoice        *interface{}
-	Official          bool `json:"official"`
+type RelayStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type Choice struct {
@@ -90,51 +88,357 @@ type TranshipmentError struct {
 	Type    string `json:"type"`
 }
 
-type RelayImageForm struct {
-	Model  string `json:"model"`
-	Prompt string `json:"prompt"`
-	N      *int   `json:"n,omitempty"`
+type RelayCompletionForm struct {
+	Model            string      `json:"model" binding:"required"`
+	Prompt           interface{} `json:"prompt" binding:"required"` // string or string array with single prompt
+	Suffix           *string     `json:"suffix,omitempty"`
+	Echo             bool        `json:"echo"`
+	Stop             interface{} `json:"stop,omitempty"` // string or string array
+	Logprobs         *int        `json:"logprobs,omitempty"`
+	MaxTokens        *int        `json:"max_tokens,omitempty"`
+	Temperature      *float32    `json:"temperature,omitempty"`
+	TopP             *float32    `json:"top_p,omitempty"`
+	PresencePenalty  *float32    `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float32    `json:"frequency_penalty,omitempty"`
+	User             *string     `json:"user,omitempty"`
+	Stream           bool        `json:"stream"`
+	Official         bool        `json:"official"`
 }
 
-type RelayImageData struct {
-	Url string `json:"url"`
+type RelayCompletionResponse struct {
+	Id      string                           `json:"id"`
+	Object  string                           `json:"object"`
+	Created int64                            `json:"created"`
+	Model   string                           `json:"model"`
+	Choices []adaptercommon.CompletionChoice `json:"choices"`
+	Usage   *Usage                           `json:"usage,omitempty"` // only the non-stream response and the last chunk
+	Quota   *float32                         `json:"quota,omitempty"`
+	Error   error                            `json:"-"`
+}
+
+type RelayModerationForm struct {
+	Model    string      `json:"model"`
+	Input    interface{} `json:"input" binding:"required"` // string or string array
+	Official bool        `json:"official"`
+}
+
+type RelayModerationResponse struct {
+	Id      string                           `json:"id"`
+	Model   string                           `json:"model"`
+	Results []adaptercommon.ModerationResult `json:"results"`
+	Quota   *float32                         `json:"quota,omitempty"`
+}
+
+type RelayImageForm struct {
+	Model          string  `json:"model" form:"model"`
+	Prompt         string  `json:"prompt" form:"prompt"`
+	N              *int    `json:"n,omitempty" form:"n"`
+	Size           *string `json:"size,omitempty" form:"size"`
+	Quality        *string `json:"quality,omitempty" form:"quality"`
+	Style          *string `json:"style,omitempty" form:"style"`
+	ResponseFormat *string `json:"response_format,omitempty" form:"response_format"` // url or b64_json
+	User           *string `json:"user,omitempty" form:"user"`
 }
 
 type RelayImageResponse struct {
-	Created int64            `json:"created"`
-	Data    []RelayImageData `json:"data"`
+	Created int64                     `json:"created"`
+	Data    []adaptercommon.ImageData `json:"data"`
+}
+
+type RelayTranscriptionForm struct {
+	Model          string   `form:"model" binding:"required"`
+	Language       *string  `form:"language"` // only transcriptions
+	Prompt         *string  `form:"prompt"`
+	ResponseFormat string   `form:"response_format"` // json, text, srt, verbose_json or vtt
+	Temperature    *float32 `form:"temperature"`
+}
+
+type RelaySpeechForm struct {
+	Model          string   `json:"model" binding:"required"`
+	Input          string   `json:"input" binding:"required"`
+	Voice          string   `json:"voice" binding:"required"`
+	ResponseFormat *string  `json:"response_format,omitempty"` // mp3, opus, aac or flac
+	Speed          *float32 `json:"speed,omitempty"`
+}
+
+type RelayMidjourneyForm struct {
+	Model     string `json:"model" binding:"required"`
+	Prompt    string `json:"prompt"`    // imagine
+	TaskId    string `json:"task_id"`   // upscale, variation, reroll, zoom and pan
+	Index     int    `json:"index"`     // upscale and variation (1-4)
+	Scale     string `json:"scale"`     // zoom (2x, 1.5x)
+	Direction string `json:"direction"` // pan (left, right, up, down)
+	Image     string `json:"image"`     // describe, image url or base64 data url
+}
+
+type RelayMidjourneyResponse struct {
+	Created int64    `json:"created"`
+	Action  string   `json:"action"`
+	TaskId  string   `json:"task_id"`
+	Url     string   `json:"url,omitempty"`
+	Prompt  string   `json:"prompt,omitempty"` // result of describe
+	Quota   *float32 `json:"quota,omitempty"`
+}
+
+type RelayEmbeddingForm struct {
+	Model          string      `json:"model" binding:"required"`
+	Input          interface{} `json:"input" binding:"required"` // string, string array, token array or token arrays
+	EncodingFormat *string     `json:"encoding_format,omitempty"`
+	Dimensions     *int        `json:"dimensions,omitempty"`
+	User           *string     `json:"user,omitempty"`
+	Official       bool        `json:"official"`
+}
+
+type RelayEmbeddingResponse struct {
+	Object string                        `json:"object"`
+	Data   []adaptercommon.EmbeddingData `json:"data"`
+	Model  string                        `json:"model"`
+	Usage  adaptercommon.EmbeddingUsage  `json:"usage"`
+	Quota  *float32                      `json:"quota,omitempty"`
+}
+
+// MessagesContent is the content block of the anthropic messages api request
+type MessagesContent struct {
+	Type      string               `json:"type"` // text, image, tool_use or tool_result
+	Text      string               `json:"text"`
+	Source    *MessagesImageSource `json:"source"`      // only image
+	Id        string               `json:"id"`          // only tool_use
+	Name      string               `json:"name"`        // only tool_use
+	Input     interface{}          `json:"input"`       // only tool_use
+	ToolUseId string               `json:"tool_use_id"` // only tool_result
+	Content   interface{}          `json:"content"`     // only tool_result, string or content blocks
+}
+
+type MessagesImageSource struct {
+	Type      string `json:"type"` // base64 or url
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+	Url       string `json:"url"`
+}
+
+type MessagesMessage struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"` // string or content blocks
+}
+
+type MessagesTool struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	InputSchema interface{} `json:"input_schema"`
+}
+
+type MessagesToolChoice struct {
+	Type string `json:"type"` // auto, any, tool or none
+	Name string `json:"name"` // only tool
+}
+
+type RelayMessagesForm struct {
+	Model         string              `json:"model" binding:"required"`
+	Messages      []MessagesMessage   `json:"messages" binding:"required"`
+	System        interface{}         `json:"system"` // string or text blocks
+	MaxTokens     int                 `json:"max_tokens" binding:"required"`
+	StopSequences []string            `json:"stop_sequences"`
+	Stream        bool                `json:"stream"`
+	Temperature   *float32            `json:"temperature"`
+	TopP          *float32            `json:"top_p"`
+	TopK          *int                `json:"top_k"`
+	Tools         []MessagesTool      `json:"tools"`
+	ToolChoice    *MessagesToolChoice `json:"tool_choice"`
+	Official      bool                `json:"official"`
+}
+
+type MessagesTextBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type MessagesToolUseBlock struct {
+	Type  string      `json:"type"`
+	Id    string      `json:"id"`
+	Name  string      `json:"name"`
+	Input interface{} `json:"input"`
+}
+
+type MessagesUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type RelayMessagesResponse struct {
+	Id           string        `json:"id"`
+	Type         string        `json:"type"`
+	Role         string        `json:"role"`
+	Model        string        `json:"model"`
+	Content      []interface{} `json:"content"` // text and tool_use blocks
+	StopReason   *string       `json:"stop_reason"`
+	StopSequence *string       `json:"stop_sequence"`
+	Usage        MessagesUsage `json:"usage"`
+	Quota        *float32      `json:"quota,omitempty"`
+}
+
+type MessagesContentDelta struct {
+	Type        string `json:"type"` // text_delta or input_json_delta
+	Text        string `json:"text,omitempty"`
+	PartialJson string `json:"partial_json,omitempty"`
+}
+
+type MessagesMessageDelta struct {
+	StopReason   string  `json:"stop_reason"`
+	StopSequence *string `json:"stop_sequence"`
+}
+
+// RelayMessagesStreamEvent is the event of the anthropic messages stream, the event type is also the name of the sse event
+type RelayMessagesStreamEvent struct {
+	Type         string                 `json:"type"`
+	Message      *RelayMessagesResponse `json:"message,omitempty"`       // message_start
+	Index        *int                   `json:"index,omitempty"`         // content_block_start, content_block_delta and content_block_stop
+	ContentBlock interface{}            `json:"content_block,omitempty"` // content_block_start
+	Delta        interface{}            `json:"delta,omitempty"`         // content_block_delta and message_delta
+	Usage        *MessagesUsage         `json:"usage,omitempty"`         // message_delta
+	Error        *TranshipmentError     `json:"error,omitempty"`
+}
+
+type RelayMessagesErrorResponse struct {
+	Type  string            `json:"type"`
+	Error TranshipmentError `json:"error"`
+}
+
+// GeminiPart is the part of the gemini generateContent request and response
+type GeminiPart struct {
+	Text             *string                 `json:"text,omitempty"`
+	InlineData       *GeminiInlineData       `json:"inlineData,omitempty"`
+	InlineDataAlias  *GeminiInlineData       `json:"inline_data,omitempty"` // snake case is also accepted by google
+	FileData         *GeminiFileData         `json:"fileData,omitempty"`
+	FunctionCall     *GeminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *GeminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type GeminiInlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type GeminiFileData struct {
+	MimeType string `json:"mimeType"`
+	FileUri  string `json:"fileUri"`
+}
+
+type GeminiFunctionCall struct {
+	Name string      `json:"name"`
+	Args interface{} `json:"args"`
+}
+
+type GeminiFunctionResponse struct {
+	Name     string      `json:"name"`
+	Response interface{} `json:"response"`
+}
+
+type GeminiContent struct {
+	Role  string       `json:"role,omitempty"` // user or model
+	Parts []GeminiPart `json:"parts"`
+}
+
+type GeminiGenerationConfig struct {
+	Temperature     *float32 `json:"temperature"`
+	TopP            *float32 `json:"topP"`
+	TopK            *int     `json:"topK"`
+	MaxOutputTokens int      `json:"maxOutputTokens"`
+	CandidateCount  int      `json:"candidateCount"`
+	StopSequences   []string `json:"stopSequences"`
+}
+
+// GeminiSafetySetting is accepted for the compatibility, the safety of the channels is not configurable per request
+type GeminiSafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+type GeminiFunctionDeclaration struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Parameters  interface{} `json:"parameters"`
+}
+
+type GeminiTool struct {
+	FunctionDeclarations []GeminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type GeminiToolConfig struct {
+	FunctionCallingConfig struct {
+		Mode                 string   `json:"mode"` // AUTO, ANY or NONE
+		AllowedFunctionNames []string `json:"allowedFunctionNames"`
+	} `json:"functionCallingConfig"`
 }
 
-func transformContent(content interface{}) string {
+type RelayGeminiForm struct {
+	Contents          []GeminiContent        `json:"contents" binding:"required"`
+	SystemInstruction *GeminiContent         `json:"systemInstruction"`
+	GenerationConfig  GeminiGenerationConfig `json:"generationConfig"`
+	SafetySettings    []GeminiSafetySetting  `json:"safetySettings"`
+	Tools             []GeminiTool           `json:"tools"`
+	ToolConfig        *GeminiToolConfig      `json:"toolConfig"`
+	Official          bool                   `json:"official"`
+}
+
+type GeminiCandidate struct {
+	Index        int           `json:"index"`
+	Content      GeminiContent `json:"content"`
+	FinishReason string        `json:"finishReason,omitempty"`
+}
+
+type GeminiUsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+// RelayGeminiResponse is the response of generateContent, also used as the chunk of streamGenerateContent
+type RelayGeminiResponse struct {
+	Candidates    []GeminiCandidate    `json:"candidates"`
+	UsageMetadata *GeminiUsageMetadata `json:"usageMetadata,omitempty"`
+	ModelVersion  string               `json:"modelVersion"`
+	Quota         *float32             `json:"quota,omitempty"`
+	Error         *GeminiError         `json:"error,omitempty"`
+}
+
+type GeminiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+type RelayGeminiErrorResponse struct {
+	Error GeminiError `json:"error"`
+}
+
+// transformContent returns the text view and the typed parts of the message content (string or openai content parts)
+func transformContent(content interface{}) (string, globals.MessageContents) {
 	switch v := content.(type) {
 	case string:
-		return v
+		return v, nil
 	default:
-		var result string
-		data := utils.MapToStruct[MessageContents](v)
+		data := utils.MapToStruct[globals.MessageContents](v)
 		if data == nil || len(*data) == 0 {
-			return ""
+			return "", nil
 		}
 
-		for _, v := range *data {
-			if v.Text != nil {
-				result += *v.Text
-			}
-
-			if v.ImageUrl != nil {
-				result += fmt.Sprintf(" %s ", v.ImageUrl.Url)
-			}
+		contents := utils.NormalizeImageContents(*data)
+		if !contents.IsMultimodal() {
+			// plain text parts are kept as the text view only
+			return contents.GetText(), nil
 		}
-		return result
+		return contents.GetText(), contents
 	}
 }
 
 func transform(m []Message) []globals.Message {
 	var messages []globals.Message
 	for _, v := range m {
+		content, contents := transformContent(v.Content)
 		messages = append(messages, globals.Message{
 			Role:       v.Role,
-			Content:    transformContent(v.Content),
+			Content:    content,
+			Contents:   contents,
 			ToolCallId: v.ToolCallId,
 			ToolCalls:  v.ToolCalls,
 		})
@@ -50,8 +50,35 @@ func ProcessKey(c *gin.Context, key string) *auth.User {
 	return nil
 }
 
+// isGeminiRoute returns whether the request is sent to the gemini generateContent api, the path is prefixed
+// with `/api` if the static files are served
+func isGeminiRoute(path string) bool {
+	return strings.HasPrefix(strings.TrimPrefix(path, "/api"), "/v1beta/models/")
+}
+
+// getGeminiKey returns the api key sent by the google sdks in the x-goog-api-key header or the key query,
+// only the api keys are accepted, so the token of the user is never leaked to the access logs by the query
+func getGeminiKey(c *gin.Context) string {
+	key := strings.TrimSpace(c.GetHeader("X-Goog-Api-Key"))
+	if key == "" {
+		key = strings.TrimSpace(c.Query("key"))
+	}
+
+	if !strings.HasPrefix(key, "sk-") {
+		return ""
+	}
+	return key
+}
+
 func ProcessAuthorization(c *gin.Context) *auth.User {
 	k := strings.TrimSpace(c.GetHeader("Authorization"))
+	if k == "" {
+		// anthropic sdk sends the api key in the x-api-key header
+		k = strings.TrimSpace(c.GetHeader("X-Api-Key"))
+	}
+	if k == "" && isGeminiRoute(c.Request.URL.Path) {
+		k = getGeminiKey(c)
+	}
 	if k != "" {
 		if strings.HasPrefix(k, "Bearer ") {
 			k = strings.TrimPrefix(k, "Bearer ")
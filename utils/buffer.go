@@ -27,6 +27,13 @@ type Buffer struct {
 	Images    Images             `json:"images"`
 	ToolCalls *globals.ToolCalls `json:"tool_calls"`
 	Charge    Charge             `json:"charge"`
+	Count     int                `json:"count"` // number of the generated objects (e.g. images), times billing is charged per object
+
+	InputTokens  int `json:"input_tokens"`  // usage reported by the upstream, 0 if not reported
+	OutputTokens int `json:"output_tokens"` // usage reported by the upstream, 0 if not reported
+	TextTokens   int `json:"text_tokens"`   // tokens of the buffered text counted by tiktoken once finished, fallback of the output usage
+
+	counted int // cursor of the text when the text tokens are counted
 }
 
 func NewBuffer(model string, history []globals.Message, charge Charge) *Buffer {
@@ -38,12 +45,45 @@ func NewBuffer(model string, history []globals.Message, charge Charge) *Buffer {
 	}
 }
 
+// NewEmbeddingBuffer creates the buffer of embedding requests, which are billed on input tokens only
+func NewEmbeddingBuffer(model string, tokens int, charge Charge) *Buffer {
+	return &Buffer{
+		Model:       model,
+		Quota:       CountInputQuota(charge, tokens),
+		InputTokens: tokens,
+		Charge:      charge,
+	}
+}
+
+// NewAudioBuffer creates the buffer of audio requests, the quota is counted by the audio duration or the speech input
+func NewAudioBuffer(model string, quota float32, charge Charge) *Buffer {
+	return &Buffer{
+		Model:  model,
+		Quota:  quota,
+		Charge: charge,
+	}
+}
+
 func (b *Buffer) GetCursor() int {
 	return b.Cursor
 }
 
+// GetQuota returns the quota of the request, the usage reported by the upstream is preferred over the estimated one
 func (b *Buffer) GetQuota() float32 {
-	return b.Quota + CountOutputToken(b.Charge, b.Model, b.ReadTimes())
+	input := b.Quota
+	if b.InputTokens > 0 && b.Charge.IsBillingType(globals.TokenBilling) {
+		input = CountInputQuota(b.Charge, b.InputTokens)
+	}
+
+	quota := CountOutputQuota(b.Charge, b.CountOutputToken())
+	if b.Count > 1 && b.Charge.IsBillingType(globals.TimesBilling) {
+		quota *= float32(b.Count)
+	}
+	return input + quota
+}
+
+func (b *Buffer) SetCount(count int) {
+	b.Count = count
 }
 
 func (b *Buffer) Write(data string) string {
@@ -54,6 +94,17 @@ func (b *Buffer) Write(data string) string {
 	return data
 }
 
+// Finish counts the tokens of the buffered text once the request is finished,
+// the text is not encoded if the upstream reports the output usage
+func (b *Buffer) Finish() {
+	if b.OutputTokens > 0 || b.counted == b.Cursor {
+		return
+	}
+
+	b.TextTokens = NumTokensFromTexts([]string{b.Data}, b.Model)
+	b.counted = b.Cursor
+}
+
 func (b *Buffer) GetChunk() string {
 	return b.Latest
 }
@@ -84,6 +135,22 @@ func (b *Buffer) IsFunctionCalling() bool {
 	return b.GetToolCalls() != nil
 }
 
+func (b *Buffer) SetInputTokens(tokens int) {
+	if tokens <= 0 {
+		return
+	}
+
+	b.InputTokens = tokens
+}
+
+func (b *Buffer) SetOutputTokens(tokens int) {
+	if tokens <= 0 {
+		return
+	}
+
+	b.OutputTokens = tokens
+}
+
 func (b *Buffer) WriteBytes(data []byte) []byte {
 	b.Write(string(data))
 	return data
@@ -125,11 +192,25 @@ func (b *Buffer) ReadHistory() []globals.Message {
 }
 
 func (b *Buffer) CountInputToken() int {
-	return GetWeightByModel(b.Model) * NumTokensFromMessages(b.History, b.Model)
+	if b.InputTokens > 0 {
+		return b.InputTokens
+	}
+
+	return NumTokensFromMessages(b.History, b.Model)
 }
 
 func (b *Buffer) CountOutputToken() int {
-	return b.ReadTimes() * GetWeightByModel(b.Model)
+	if b.OutputTokens > 0 {
+		return b.OutputTokens
+	}
+
+	tokens := b.TextTokens
+	if b.ToolCalls != nil {
+		for _, call := range *b.ToolCalls {
+			tokens += NumTokensFromTexts([]string{call.Function.Name, call.Function.Arguments}, b.Model)
+		}
+	}
+	return tokens
 }
 
 func (b *Buffer) CountToken() int {
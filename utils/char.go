@@ -171,6 +171,14 @@ func ExtractImageUrls(data string) []string {
 	return re.FindAllString(strings.ToLower(data), -1)
 }
 
+// ExtractMarkdownImages returns the urls of the markdown images (e.g. `![image](url)`), the case of the urls is kept
+func ExtractMarkdownImages(data string) []string {
+	re := regexp.MustCompile(`!\[[^]]*]\((\S+?)\)`)
+	return Each[[]string, string](re.FindAllStringSubmatch(data, -1), func(match []string) string {
+		return match[1]
+	})
+}
+
 func ContainUnicode(data string) bool {
 	// like `hi\\u2019s` => true
 	re := regexp.MustCompile(`\\u([0-9a-fA-F]{4})`)
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"bytes"
 	"chat/globals"
 	"github.com/chai2010/webp"
 	"image"
@@ -68,6 +69,104 @@ func ConvertToBase64(url string) (string, error) {
 	return Base64EncodeBytes(data), nil
 }
 
+func NewImageFromBase64(data string) (*Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(Base64DecodeBytes(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Image{Object: img}, nil
+}
+
+// NewImageFromContent returns the image of the url or base64 image part
+func NewImageFromContent(content globals.MessageContent) (*Image, error) {
+	if content.Type == globals.ImageContentType && content.Image != nil {
+		return NewImageFromBase64(content.Image.Data)
+	}
+
+	if mimeType, data, ok := ParseDataUrl(content.GetImageUrl()); ok && strings.HasPrefix(mimeType, "image/") {
+		return NewImageFromBase64(data)
+	}
+	return NewImage(content.GetImageUrl())
+}
+
+// GetImageMimeType returns the mime type of the image by the extension of the url, defaults to png
+func GetImageMimeType(url string) string {
+	switch strings.TrimPrefix(strings.ToLower(path.Ext(url)), ".") {
+	case "jpg", "jpeg":
+		return "image/jpeg"
+	case "gif":
+		return "image/gif"
+	case "webp":
+		return "image/webp"
+	case "heif":
+		return "image/heif"
+	case "heic":
+		return "image/heic"
+	default:
+		return "image/png"
+	}
+}
+
+// ParseDataUrl splits the base64 data url (e.g. `data:image/png;base64,...`) into the mime type and the data
+func ParseDataUrl(url string) (string, string, bool) {
+	if !strings.HasPrefix(url, "data:") {
+		return "", "", false
+	}
+
+	meta, data, ok := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+	if !ok || !strings.HasSuffix(meta, ";base64") {
+		return "", "", false
+	}
+
+	return strings.TrimSuffix(meta, ";base64"), data, true
+}
+
+// GetImageData returns the mime type and the base64 data of the image part, the remote image will be downloaded
+func GetImageData(content globals.MessageContent) (string, string, error) {
+	if content.Type == globals.ImageContentType && content.Image != nil {
+		return content.Image.MimeType, content.Image.Data, nil
+	}
+
+	url := content.GetImageUrl()
+	if mimeType, data, ok := ParseDataUrl(url); ok {
+		return mimeType, data, nil
+	}
+
+	data, err := ConvertToBase64(url)
+	if err != nil {
+		return "", "", err
+	}
+	return GetImageMimeType(url), data, nil
+}
+
+// NormalizeImageContents converts the data url images to the base64 image parts
+func NormalizeImageContents(contents globals.MessageContents) globals.MessageContents {
+	return Each[globals.MessageContent, globals.MessageContent](contents, func(content globals.MessageContent) globals.MessageContent {
+		if content.Type != globals.ImageUrlContentType || content.ImageUrl == nil {
+			return content
+		}
+
+		if mimeType, data, ok := ParseDataUrl(content.ImageUrl.Url); ok {
+			return globals.NewImageContent(mimeType, data)
+		}
+		return content
+	})
+}
+
+// ExtractImageContents converts the plain message which contains image urls to the text and image url parts,
+// it returns nil if there is no image url in the message
+func ExtractImageContents(content string) globals.MessageContents {
+	urls := ExtractImageUrls(content)
+	if len(urls) == 0 {
+		return nil
+	}
+
+	return append(globals.MessageContents{globals.NewTextContent(content)}, Each[string, globals.MessageContent](urls, func(url string) globals.MessageContent {
+		return globals.NewImageUrlContent(url)
+	})...)
+}
+
 func (i *Image) GetWidth() int {
 	return i.Object.Bounds().Max.X
 }
@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"github.com/goccy/go-json"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"net/url"
 	"strings"
 	"time"
@@ -85,6 +87,79 @@ func Post(uri string, headers map[string]string, body interface{}) (data interfa
 	return data, err
 }
 
+// PostRaw posts the json body and returns the raw response body with its content type (e.g. the binary audio)
+func PostRaw(uri string, headers map[string]string, body interface{}) (data []byte, contentType string, err error) {
+	req, err := http.NewRequest(http.MethodPost, uri, ConvertBody(body))
+	if err != nil {
+		return nil, "", err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	client := newClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	defer resp.Body.Close()
+
+	if data, err = io.ReadAll(resp.Body); err != nil {
+		return nil, "", err
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+// MultipartFile is the file field of the multipart form
+type MultipartFile struct {
+	Name string
+	Data []byte
+}
+
+// PostMultipart posts the multipart form of the fields and the files, the content type of the headers is replaced by the form
+func PostMultipart(uri string, headers map[string]string, fields map[string]string, files map[string]*MultipartFile) (data interface{}, err error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	for key, value := range fields {
+		if err := writer.WriteField(key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	for key, file := range files {
+		if file == nil {
+			continue
+		}
+
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, key, file.Name))
+		header.Set("Content-Type", http.DetectContentType(file.Data))
+
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write(file.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	form := make(map[string]string, len(headers))
+	for key, value := range headers {
+		form[key] = value
+	}
+	form["Content-Type"] = writer.FormDataContentType()
+
+	err = Http(uri, http.MethodPost, &data, form, body)
+	return data, err
+}
+
 func ConvertBody(body interface{}) (form io.Reader) {
 	if buffer, err := json.Marshal(body); err == nil {
 		form = bytes.NewBuffer(buffer)
@@ -156,12 +231,7 @@ func EventSource(method string, uri string, headers map[string]string, body inte
 		buf := make([]byte, 20480)
 		n, err := res.Body.Read(buf)
 
-		if err == io.EOF {
-			return nil
-		} else if err != nil {
-			return err
-		}
-
+		// the last chunk may be returned together with io.EOF
 		data := string(buf[:n])
 		for _, item := range strings.Split(data, "\n") {
 			segment := strings.TrimSpace(item)
@@ -171,5 +241,11 @@ func EventSource(method string, uri string, headers map[string]string, body inte
 				}
 			}
 		}
+
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
 	}
 }
@@ -45,6 +45,11 @@ func checkWriter(writer io.Writer) stringWriter {
 
 func encode(writer io.Writer, event StreamEvent) error {
 	w := checkWriter(writer)
+	if len(event.Event) > 0 {
+		if _, err := w.writeString(fmt.Sprintf("event: %s\n", event.Event)); err != nil {
+			return err
+		}
+	}
 	return writeData(w, event.Data)
 }
 
@@ -77,6 +82,15 @@ func NewEvent(data interface{}) StreamEvent {
 	}
 }
 
+// NewNamedEvent creates the event with the event type, e.g. the events of the anthropic messages stream
+func NewNamedEvent(event string, data interface{}) StreamEvent {
+	chunk := Marshal(data)
+	return StreamEvent{
+		Event: event,
+		Data:  fmt.Sprintf("data: %s", chunk),
+	}
+}
+
 func NewEndEvent() StreamEvent {
 	return StreamEvent{
 		Data: "data: [DONE]",
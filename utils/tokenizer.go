@@ -3,7 +3,9 @@ package utils
 import (
 	"chat/globals"
 	"github.com/pkoukk/tiktoken-go"
+	"math"
 	"strings"
+	"sync"
 )
 
 //   Using https://github.com/pkoukk/tiktoken-go
@@ -42,21 +44,70 @@ func GetWeightByModel(model string) int {
 		}
 	}
 }
+
+// GetContextLengthByModel returns the context window of the model in tokens, 4k by default
+func GetContextLengthByModel(model string) int {
+	switch model {
+	case globals.GPT3Turbo1106, globals.GPT3Turbo16k, globals.GPT3Turbo16k0613, globals.GPT3Turbo16k0301:
+		return 16385
+	case globals.GPT4, globals.GPT40314, globals.GPT40613, globals.GPT4Vision, globals.GPT4All, globals.GPT4Dalle:
+		return 8192
+	case globals.GPT432k, globals.GPT432k0314, globals.GPT432k0613:
+		return 32768
+	case globals.GPT41106Preview, globals.GPT41106VisionPreview:
+		return 128000
+	case globals.Claude1, globals.Claude1100k, globals.Claude2, globals.Claude2100k:
+		return 100000
+	case globals.Claude2200k:
+		return 200000
+	case globals.GeminiPro, globals.SparkDeskV3, globals.QwenTurbo, globals.QwenTurboNet:
+		return 8192
+	case globals.GLM4, globals.GLM3Turbo, globals.QwenPlus, globals.QwenPlusNet:
+		return 32768
+	default:
+		if strings.Contains(model, "-128k") {
+			return 128000
+		} else if strings.Contains(model, "-32k") {
+			return 32768
+		} else if strings.Contains(model, "-16k") {
+			return 16385
+		} else if strings.Contains(model, "-8k") {
+			return 8192
+		}
+		return 4096
+	}
+}
+
+// encodings caches the tiktoken encoders by model, since building the encoder is expensive,
+// the models without the encoder are cached too, otherwise every count of them looks the encoder up again
+var encodings sync.Map
+
+type encoding struct {
+	tkm *tiktoken.Tiktoken
+	err error
+}
+
+func encodingForModel(model string) (*tiktoken.Tiktoken, error) {
+	if cached, ok := encodings.Load(model); ok {
+		return cached.(encoding).tkm, cached.(encoding).err
+	}
+
+	tkm, err := tiktoken.EncodingForModel(model)
+	encodings.Store(model, encoding{tkm: tkm, err: err})
+	return tkm, err
+}
+
 func NumTokensFromMessages(messages []globals.Message, model string) (tokens int) {
 	tokensPerMessage := GetWeightByModel(model)
-	tkm, err := tiktoken.EncodingForModel(model)
-	if err != nil {
-		// the method above was deprecated, use the recall method instead
-		// can not encode messages, use length of messages as a proxy for number of tokens
-		// using rune instead of byte to account for unicode characters (e.g. emojis, non-english characters)
-		// data := Marshal(messages)
-		// return len([]rune(data)) * weight
-
-		// use the recall method instead (default encoder model is gpt-3.5-turbo-0613)
-		return NumTokensFromMessages(messages, globals.GPT3Turbo0613)
-	}
 
+	// default encoder model is gpt-3.5-turbo-0613, use the length of the runes if the encoder is unavailable
+	tkm := getEncoding(model)
 	for _, message := range messages {
+		if tkm == nil {
+			tokens += len([]rune(message.Content)) + len([]rune(message.Role)) + tokensPerMessage
+			continue
+		}
+
 		tokens +=
 			len(tkm.Encode(message.Content, nil, nil)) +
 				len(tkm.Encode(message.Role, nil, nil)) +
@@ -66,22 +117,87 @@ func NumTokensFromMessages(messages []globals.Message, model string) (tokens int
 	return tokens
 }
 
+// NumTokensFromMessage returns the tokens of the single message without the reply priming
+func NumTokensFromMessage(message globals.Message, model string) int {
+	return NumTokensFromMessages([]globals.Message{message}, model) - 3
+}
+
 func CountTokenPrice(messages []globals.Message, model string) int {
 	return NumTokensFromMessages(messages, model)
 }
 
-func CountInputToken(charge Charge, model string, message []globals.Message) float32 {
+func getEncoding(model string) *tiktoken.Tiktoken {
+	if tkm, err := encodingForModel(model); err == nil {
+		return tkm
+	}
+
+	// default encoder model is gpt-3.5-turbo-0613
+	tkm, _ := encodingForModel(globals.GPT3Turbo0613)
+	return tkm
+}
+
+// NumTokensFromTexts returns the number of tokens of the plain texts (e.g. embedding inputs)
+func NumTokensFromTexts(texts []string, model string) (tokens int) {
+	tkm := getEncoding(model)
+	for _, text := range texts {
+		if tkm == nil {
+			tokens += len([]rune(text))
+			continue
+		}
+		tokens += len(tkm.Encode(text, nil, nil))
+	}
+	return tokens
+}
+
+// CountSpeechQuota returns the quota of the text-to-speech input, audio billing is charged per character
+func CountSpeechQuota(charge Charge, model string, input string) float32 {
+	switch charge.GetType() {
+	case globals.AudioBilling:
+		return float32(len([]rune(input))) / 1000 * charge.GetInput()
+	case globals.TokenBilling:
+		return CountInputQuota(charge, NumTokensFromTexts([]string{input}, model))
+	default:
+		return 0
+	}
+}
+
+// CountTranscriptionQuota returns the quota of the transcribed audio, audio billing is charged per second of audio
+func CountTranscriptionQuota(charge Charge, model string, seconds float64, text string) float32 {
+	switch charge.GetType() {
+	case globals.AudioBilling:
+		return float32(math.Ceil(seconds)) / 60 * charge.GetOutput()
+	case globals.TokenBilling:
+		return float32(NumTokensFromTexts([]string{text}, model)) / 1000 * charge.GetOutput()
+	default:
+		return 0
+	}
+}
+
+// DecodeTokens decodes the token array to text, for the upstreams which do not accept token inputs
+func DecodeTokens(tokens []int, model string) string {
+	tkm := getEncoding(model)
+	if tkm == nil {
+		return ""
+	}
+	return tkm.Decode(tokens)
+}
+
+func CountInputQuota(charge Charge, tokens int) float32 {
 	if charge.IsBillingType(globals.TokenBilling) {
-		return float32(CountTokenPrice(message, model)) / 1000 * charge.GetInput()
+		return float32(tokens) / 1000 * charge.GetInput()
 	}
 
 	return 0
 }
 
-func CountOutputToken(charge Charge, model string, token int) float32 {
+func CountInputToken(charge Charge, model string, message []globals.Message) float32 {
+	return CountInputQuota(charge, CountTokenPrice(message, model))
+}
+
+func CountOutputQuota(charge Charge, tokens int) float32 {
 	switch charge.GetType() {
 	case globals.TokenBilling:
-		return float32(token*GetWeightByModel(model)) / 1000 * charge.GetOutput()
+		return float32(tokens) / 1000 * charge.GetOutput()
 	case globals.TimesBilling:
 		return charge.GetOutput()
 	default: